@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ==================== 按后台进程切换（while_running） ====================
+// "while_running=obs64.exe => standard_ms_on,1000"：该进程在运行（不必在前台）时固定用这组配置，
+// 优先于按前台的白名单规则，例如 OBS 录制期间。多条时按书写顺序取第一条在运行的。
+
+// AmbientRule 一条 while_running 规则
+type AmbientRule struct {
+	Proc    string // 已归一化
+	Profile Profile
+}
+
+// parseAmbientRule 解析 "obs64.exe => standard_ms_on,1000"（配置写作 mode:poll 也可以）
+func parseAmbientRule(s string) (AmbientRule, error) {
+	proc, spec, ok := strings.Cut(s, "=>")
+	proc = normalizeProcName(strings.TrimSpace(proc))
+	if !ok || proc == "" {
+		return AmbientRule{}, fmt.Errorf("want process => mode,poll: %s", s)
+	}
+	p, err := parseProfile(strings.Replace(strings.TrimSpace(spec), ",", ":", 1))
+	if err != nil {
+		return AmbientRule{}, err
+	}
+	return AmbientRule{Proc: proc, Profile: p}, nil
+}
+
+func (r AmbientRule) String() string {
+	return fmt.Sprintf("%s => %s,%d", r.Proc, perfName(r.Profile.Perf), r.Profile.Poll)
+}
+
+// ambientRule 第一条对应进程正在运行的 while_running 规则
+func (c *Config) ambientRule(running map[string]struct{}) (AmbientRule, bool) {
+	for _, r := range c.AmbientRules {
+		if _, ok := running[r.Proc]; ok {
+			return r, true
+		}
+	}
+	return AmbientRule{}, false
+}
+
+// ambientRunning 配置了 while_running 时取一次进程列表（给 Context.Running）；未配置或枚举失败时为 nil
+func ambientRunning(cfg *Config, procs *ProcSnapshot) map[string]struct{} {
+	if len(cfg.AmbientRules) == 0 {
+		return nil
+	}
+	running, err := procs.get()
+	if err != nil {
+		return nil
+	}
+	return running
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+)
+
+const defaultApplyTimeout = 3 * time.Second
+
+// ApplyError 下发失败，并说明失败前哪些设置已经生效（设备可能处于半更新状态）
+type ApplyError struct {
+	PerfApplied bool
+	PollApplied bool
+	Err         error
+}
+
+func (e *ApplyError) Error() string {
+	switch {
+	case e.PerfApplied && !e.PollApplied:
+		return fmt.Sprintf("部分生效（性能模式已生效，回报率未生效）：%v", e.Err)
+	case e.PollApplied && !e.PerfApplied:
+		return fmt.Sprintf("部分生效（回报率已生效，性能模式未生效）：%v", e.Err)
+	}
+	return e.Err.Error()
+}
+
+func (e *ApplyError) Unwrap() error {
+	return e.Err
+}
+
+// matches 两项都已生效且与目标一致（目标带消抖时消抖也要一致）
+func (a Applied) matches(p Profile) bool {
+	return a.perfOK && a.pollOK && a.perf == p.Perf && a.poll == p.Poll &&
+		a.perfByte == p.PerfByte && a.pollByte == p.PollByte && a.slot == p.Slot && a.led == p.LED && a.extra == p.Extra &&
+		(p.Debounce == 0 || a.debounce == p.Debounce)
+}
+
+// pending 返回还需要下发的部分：已确认生效且一致的项置 0（设备层跳过 0 值）。
+// 未确认的项（初次、失败、唤醒后）照常下发，保证设备状态与记录一致。
+func (a Applied) pending(want Profile) Profile {
+	p := want
+	if a.perfOK && a.perf == want.Perf && a.perfByte == want.PerfByte {
+		p.Perf, p.PerfByte = 0, 0
+	}
+	if a.pollOK && a.poll == want.Poll && a.pollByte == want.PollByte {
+		p.Poll, p.PollByte = 0, 0
+	}
+	if a.debounce != 0 && a.debounce == want.Debounce {
+		p.Debounce = 0
+	}
+	if a.led == want.LED {
+		p.LED = 0
+	}
+	if a.extra == want.Extra {
+		p.Extra = ""
+	}
+	return p
+}
+
+// syncFromDevice 用设备读回的实际值覆盖记录；读不出消抖（0）时保留原记录
+func (a *Applied) syncFromDevice(got Profile) {
+	db := a.debounce
+	if got.Debounce != 0 {
+		db = got.Debounce
+	}
+	*a = Applied{perf: got.Perf, poll: got.Poll, perfOK: true, pollOK: true, debounce: db, led: a.led, extra: a.extra}
+}
+
+// DeviceReadback trust_device_readback 的读回时机：启动后第一次找到设备、设备重新连接
+// （路径变化，或中间有一轮找不到）、系统唤醒后各读一次，平时信任缓存，不每轮读设备
+type DeviceReadback struct {
+	path string // 上次读回时的设备路径；空 = 下次找到设备时要读
+}
+
+var deviceReadback DeviceReadback
+
+// due 本轮是否需要读回；找不到设备时记下，重新找到后再读
+func (r *DeviceReadback) due(dev VaxeeDeviceInfo, found bool) bool {
+	if !found {
+		r.path = ""
+		return false
+	}
+	if dev.Path == r.path {
+		return false
+	}
+	r.path = dev.Path
+	return true
+}
+
+// reset 设备状态未知（如系统唤醒）：下次找到设备时重新读回
+func (r *DeviceReadback) reset() { r.path = "" }
+
+// syncAppliedFromDevice 读回设备当前设置并同步到记录；
+// 缓存认为已生效、设备却不一致时说明被其它软件改过，打一条日志
+func syncAppliedFromDevice(ctx context.Context, cfg *Config, dev VaxeeDeviceInfo, last *Applied, want Profile) {
+	// 板载槽位：只比对当前槽位
+	if want.Slot != 0 {
+		slot, err := ReadActiveProfileSlot(ctx, dev.Path, cfg.Device, int(dev.FeatureLen))
+		if err != nil {
+			log.Printf("[WARN] 读回当前板载槽位失败（沿用缓存）：%v", err)
+			return
+		}
+		if last.matches(want) && slot != want.Slot {
+			log.Printf("[APPLY] 板载槽位已被外部切换（期望 %d，实际 %d），重新切换。", want.Slot, slot)
+			last.slot = 0
+		}
+		return
+	}
+	// 原始字节读回后无法与映射值对应，沿用缓存
+	if want.PerfByte.Set() || want.PollByte.Set() {
+		return
+	}
+	got, err := ReadCurrentSettings(ctx, dev.Path, cfg.Device, int(dev.FeatureLen))
+	if err != nil {
+		log.Printf("[WARN] 读回设备设置失败（沿用缓存）：%v", err)
+		return
+	}
+	if last.matches(want) && !readBackMatches(got, want) {
+		log.Printf("[APPLY] 设备设置已被外部修改（期望 %s，实际 %s），重新下发。", profileName(want), profileName(got))
+	}
+	last.syncFromDevice(got)
+}
+
+// update 按下发结果更新记录：成功则各项都生效；
+// ApplyError 只记已生效的那项；其它错误（超时、找不到设备）视为状态未知。
+// 消抖排在最后发送，出错时一律视为未知。
+func (a *Applied) update(want Profile, err error) {
+	if err == nil {
+		db := a.debounce
+		if want.Debounce != 0 {
+			db = want.Debounce
+		}
+		*a = Applied{perf: want.Perf, poll: want.Poll, perfOK: true, pollOK: true, debounce: db,
+			perfByte: want.PerfByte, pollByte: want.PollByte, slot: want.Slot, led: want.LED, extra: want.Extra}
+		return
+	}
+	// 切槽位失败或逐项下发只成功一部分：当前槽位、指示灯、额外报告都不再可信
+	a.slot, a.led, a.extra = 0, 0, ""
+	if want.Debounce != 0 {
+		a.debounce = 0
+	}
+	var ae *ApplyError
+	if !errors.As(err, &ae) {
+		a.perfOK, a.pollOK = false, false
+		return
+	}
+	if ae.PerfApplied {
+		a.perf, a.perfByte, a.perfOK = want.Perf, want.PerfByte, true
+	}
+	if ae.PollApplied {
+		a.poll, a.pollByte, a.pollOK = want.Poll, want.PollByte, true
+	}
+}
+
+// capForApply 回报率上限兜底：不经 Decide 的下发（手动覆盖、-reset、-soak、退出还原）同样受 max_poll 约束
+func capForApply(cfg *Config, p Profile) Profile {
+	capped, ok := cfg.capPoll(p, onBattery(cfg), cfg.hasWirelessVariants() && linkMode.wireless)
+	if ok {
+		log.Printf("[APPLY] 回报率 %dHz 超过上限，按 %dHz 下发（max_poll）。", p.Poll, capped.Poll)
+	}
+	return capped
+}
+
+// applyProfile 带整体超时（apply_timeout_ms）地下发一组设置，设备卡住时不阻塞主循环
+func applyProfile(ctx context.Context, cfg *Config, path string, p Profile) error {
+	p = capForApply(cfg, p)
+	actx, cancel := context.WithTimeout(ctx, cfg.ApplyTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- ApplyVaxeeSetting(actx, cfg.Device, path, p)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-actx.Done():
+		return fmt.Errorf("apply abandoned after %s: %w", cfg.ApplyTimeout, actx.Err())
+	}
+}
+
+// sleepCtx 可被 ctx 打断的 Sleep
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// applyKeysChanged 重载前后影响“发给鼠标的字节”或“发给哪个集合”的设备选项中变了的那些。
+// 模式、回报率、白名单、按程序配置等的变化会直接体现在下一轮的决策上（与缓存不同就下发），
+// 日志、间隔等与下发无关的项变了也不需要碰设备，这两类都不清缓存。
+func applyKeysChanged(old, cur *Config) []string {
+	a, b := old.Device, cur.Device
+	var keys []string
+	check := func(key string, changed bool) {
+		if changed {
+			keys = append(keys, key)
+		}
+	}
+	check("report_id", a.ReportID != b.ReportID)
+	check("report_id_in_buffer", a.ReportIDInBuffer != b.ReportIDInBuffer)
+	check("feature_length", !slices.Equal(a.FeatureLens, b.FeatureLens))
+	check("cmd_*", a.Cmd != b.Cmd)
+	check("motion_sync_report", a.MotionSyncReport != b.MotionSyncReport)
+	check("combined_report", a.CombinedReport != b.CombinedReport)
+	check("persist", !a.Persist && b.Persist) // 关掉 persist 不需要重发
+	check("safe_mode", a.SafeMode != b.SafeMode)
+	check("device_model", a.Model != b.Model)
+	check("serial", a.Serial != b.Serial)
+	check("container_id", a.ContainerID != b.ContainerID)
+	check("ignore_path", !slices.Equal(a.IgnorePaths, b.IgnorePaths))
+	check("control_path", a.ControlPath != b.ControlPath)
+	check("control_usage_page/control_usage", a.ControlUsagePage != b.ControlUsagePage || a.ControlUsage != b.ControlUsage)
+	check("control_select", a.ControlSelect != b.ControlSelect)
+	return keys
+}
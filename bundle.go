@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ==================== 诊断包（-diag） ====================
+// 把提交问题时需要的信息汇总到一个文本文件：生效配置、配置检查、VAXEE 设备枚举（含 caps）、
+// 全部 HID 设备、系统版本，以及最近的日志（按天日志文件）的最后几行。
+// 设备部分复用启动时的枚举输出；控制通道固定按 first 选，诊断过程不往设备写任何报告。
+
+const (
+	diagFileName = "vaxee-diag.txt"
+	diagLogLines = 200
+)
+
+// runDiag 生成诊断包，返回退出码
+func runDiag(ctx context.Context, cfgPath string) int {
+	var b bytes.Buffer
+	section := func(title string) {
+		fmt.Fprintf(&b, "\n==================== %s ====================\n", title)
+	}
+	// 复用现有的日志式输出：采集期间把 log 重定向到缓冲区
+	capture := func(fn func()) {
+		prevOut, prevFlags := log.Writer(), log.Flags()
+		log.SetOutput(&b)
+		log.SetFlags(0)
+		defer func() {
+			log.SetOutput(prevOut)
+			log.SetFlags(prevFlags)
+		}()
+		fn()
+	}
+
+	fmt.Fprintf(&b, "# VAXEE AutoSwitch 诊断信息\n")
+	fmt.Fprintf(&b, "generated: %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&b, "os: %s\n", strings.TrimSpace(runtime.GOOS+"/"+runtime.GOARCH+" "+osVersion()))
+	fmt.Fprintf(&b, "go: %s\n", runtime.Version())
+	if exe, err := os.Executable(); err == nil {
+		fmt.Fprintf(&b, "exe: %s\n", exe)
+	}
+	fmt.Fprintf(&b, "config: %s\n", cfgPath)
+
+	cfg, _, err := loadConfig(cfgPath)
+	section("生效配置")
+	if err != nil {
+		fmt.Fprintf(&b, "读取配置失败：%v\n", err)
+		// 配置坏了也照样枚举设备
+		cfg = &Config{ConfigPath: cfgPath, Device: DeviceOptions{
+			ReportID: defaultReportID, ReportGap: defaultReportGap, Cmd: defaultCommandBytes, ReportIDInBuffer: true,
+		}, Log: LogOptions{Dir: filepath.Join(filepath.Dir(cfgPath), "logs")}}
+	} else {
+		b.WriteString(formatConfig(cfg))
+		section("配置检查")
+		ws := configWarnings(cfg)
+		if len(ws) == 0 {
+			b.WriteString("（无警告）\n")
+		}
+		for _, w := range ws {
+			fmt.Fprintf(&b, "WARN: %s\n", w)
+		}
+	}
+
+	// rewrite / verify 选通道时会写报告探测，诊断只读
+	cfg.Device.ControlSelect = controlSelectFirst
+
+	section("VAXEE 设备")
+	capture(func() {
+		enumerateDevices(ctx, cfg)
+		runCaps(ctx)
+	})
+
+	section("全部 HID 设备")
+	all := *cfg
+	all.DumpAllHID, all.DumpAllHIDMax = true, 0
+	capture(func() { enumerateAllHidDevices(ctx, &all) })
+
+	section(fmt.Sprintf("最近日志（最后 %d 行）", diagLogLines))
+	writeLogTail(&b, cfg.Log.Dir)
+
+	out := filepath.Join(".", diagFileName)
+	if err := os.WriteFile(out, b.Bytes(), 0o644); err != nil {
+		log.Printf("[ERR] 写入诊断包失败：%v", err)
+		return 1
+	}
+	abs, _ := filepath.Abs(out)
+	log.Printf("诊断信息已写入：%s（提交问题时附上此文件；里面含设备路径与配置，发出前可自行检查）", abs)
+	return 0
+}
+
+// writeLogTail 写出最新一个按天日志文件的最后 diagLogLines 行
+func writeLogTail(w io.Writer, dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		fmt.Fprintf(w, "（没有日志文件：%v；可开启 log_file_daily=true 后复现问题再生成诊断包）\n", err)
+		return
+	}
+	latest := ""
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, logFilePrefix) || !strings.HasSuffix(name, logFileSuffix) {
+			continue
+		}
+		day := strings.TrimSuffix(strings.TrimPrefix(name, logFilePrefix), logFileSuffix)
+		if _, err := time.Parse(logFileDate, day); err != nil {
+			continue
+		}
+		if name > latest {
+			latest = name
+		}
+	}
+	if latest == "" {
+		fmt.Fprintf(w, "（%s 下没有日志文件；可开启 log_file_daily=true 后复现问题再生成诊断包）\n", dir)
+		return
+	}
+	path := filepath.Join(dir, latest)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(w, "读取 %s 失败：%v\n", path, err)
+		return
+	}
+	fmt.Fprintf(w, "file: %s\n", path)
+	lines := strings.Split(strings.TrimRight(string(data), "\r\n"), "\n")
+	if len(lines) > diagLogLines {
+		lines = lines[len(lines)-diagLogLines:]
+	}
+	for _, l := range lines {
+		fmt.Fprintln(w, strings.TrimRight(l, "\r"))
+	}
+}
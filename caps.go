@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Capabilities 设备自报的可选回报率/性能模式（读能力报告成功时才有）。
+// 数据段（推测）：[0] 回报率位图 bit0=1000 bit1=2000 bit2=4000 bit3=8000；
+// [1] 性能模式位图 bit(n-1) = PerfMode n。
+type Capabilities struct {
+	Polls []PollingRate
+	Perfs []PerfMode
+}
+
+const cmdCapabilities = 0x02 // 能力报告（推测）
+
+var capsPollBits = []PollingRate{1000, 2000, 4000, 8000}
+
+func parseCapabilities(data []byte) (Capabilities, error) {
+	if len(data) < 2 {
+		return Capabilities{}, fmt.Errorf("capabilities too short: %d bytes", len(data))
+	}
+	var c Capabilities
+	for i, p := range capsPollBits {
+		if data[0]&(1<<i) != 0 {
+			c.Polls = append(c.Polls, p)
+		}
+	}
+	for i := 0; i < 8; i++ {
+		if data[1]&(1<<i) != 0 {
+			c.Perfs = append(c.Perfs, PerfMode(i+1))
+		}
+	}
+	if len(c.Polls) == 0 && len(c.Perfs) == 0 {
+		return Capabilities{}, fmt.Errorf("empty capabilities")
+	}
+	// 所有 VAXEE 都支持 1000Hz；不含 1000 的回复多半不是能力报告
+	if len(c.Polls) > 0 && !c.supportsPoll(Poll1000) {
+		return Capabilities{}, fmt.Errorf("implausible capabilities: % x", data[:2])
+	}
+	return c, nil
+}
+
+func (c Capabilities) supportsPoll(p PollingRate) bool {
+	if len(c.Polls) == 0 {
+		return true
+	}
+	for _, x := range c.Polls {
+		if x == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Capabilities) supportsPerf(p PerfMode) bool {
+	if len(c.Perfs) == 0 {
+		return true
+	}
+	for _, x := range c.Perfs {
+		if x == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Capabilities) String() string {
+	polls := make([]string, len(c.Polls))
+	for i, p := range c.Polls {
+		polls[i] = fmt.Sprintf("%d", p)
+	}
+	perfs := make([]string, len(c.Perfs))
+	for i, p := range c.Perfs {
+		perfs[i] = perfName(p)
+	}
+	return fmt.Sprintf("poll=[%s] mode=[%s]", strings.Join(polls, ", "), strings.Join(perfs, ", "))
+}
+
+// validateConfig 检查配置里所有会下发的值是否在设备支持范围内
+func (c Capabilities) validateConfig(cfg *Config) error {
+	var bad []string
+	checkPerf := func(key string, p PerfMode) {
+		if p != 0 && !c.supportsPerf(p) {
+			bad = append(bad, fmt.Sprintf("%s=%s", key, perfName(p)))
+		}
+	}
+	checkPoll := func(key string, p PollingRate) {
+		if p != 0 && !c.supportsPoll(p) {
+			bad = append(bad, fmt.Sprintf("%s=%d", key, p))
+		}
+	}
+
+	checkPerf("hit_mode", cfg.HitMode)
+	checkPoll("hit_poll", cfg.HitPoll)
+	checkPerf("default_mode", cfg.DefaultMode)
+	checkPoll("default_poll", cfg.DefaultPoll)
+	checkPerf("hit_mode_battery", cfg.HitModeBattery)
+	checkPoll("hit_poll_battery", cfg.HitPollBattery)
+	checkPerf("default_mode_battery", cfg.DefaultModeBattery)
+	checkPoll("default_poll_battery", cfg.DefaultPollBattery)
+	checkPerf("baseline_mode", cfg.BaselineMode)
+	checkPoll("baseline_poll", cfg.BaselinePoll)
+	checkPoll("max_poll", cfg.MaxPoll)
+	checkPoll("max_poll_battery", cfg.MaxPollBattery)
+	checkPoll("hit_poll_wireless", cfg.HitPollWireless)
+	checkPoll("default_poll_wireless", cfg.DefaultPollWireless)
+	checkPoll("max_poll_wireless", cfg.MaxPollWireless)
+	for _, rule := range cfg.Whitelist {
+		if p, ok := cfg.RuleProfiles[rule]; ok {
+			checkPerf(rule+": mode", p.Perf)
+			checkPoll(rule+": poll", p.Poll)
+		}
+	}
+	for i, p := range cfg.ManualProfiles {
+		checkPerf(fmt.Sprintf("manual_profiles[%d]", i+1), p.Perf)
+		checkPoll(fmt.Sprintf("manual_profiles[%d]", i+1), p.Poll)
+	}
+
+	if len(bad) > 0 {
+		return fmt.Errorf("设备不支持：%s（设备支持 %s）", strings.Join(bad, ", "), c)
+	}
+	return nil
+}
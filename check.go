@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// configWarnings 不影响运行、但多半不是本意的配置问题（-check-config 打印）
+func configWarnings(cfg *Config) []string {
+	var ws []string
+	add := func(format string, args ...any) {
+		ws = append(ws, fmt.Sprintf(format, args...))
+	}
+
+	for _, k := range cfg.UnknownKeys {
+		add("未知配置项 %q（已忽略，是否拼写错误？）", k)
+	}
+
+	if len(cfg.Whitelist) == 0 && len(cfg.CmdlineRules) == 0 && len(cfg.TitleRules) == 0 {
+		add("白名单为空：hit_mode/hit_poll 永远不会生效")
+	}
+	seen := map[string]bool{}
+	for _, w := range cfg.Whitelist {
+		if seen[w] {
+			add("白名单重复：%s", w)
+			continue
+		}
+		seen[w] = true
+		if cfg.MatchMode == matchExact && !strings.HasSuffix(w, ".exe") {
+			add("白名单 %q 没有 .exe 后缀，前台进程名通常带后缀，可能永远匹配不到", w)
+		}
+	}
+
+	if cfg.switchIsNoop() {
+		add("命中与未命中配置相同（%s），切换不会有任何效果", profileName(cfg.effectiveProfile(true, false)))
+	}
+	if cfg.Device.ControlUsage != 0 && cfg.Device.ControlUsagePage == 0 {
+		add("配置了 control_usage 但没有 control_usage_page，不会生效")
+	}
+	if cfg.HitModeRaw.Set() || cfg.HitPollRaw.Set() {
+		add("使用了 hit_mode_raw/hit_poll_raw：原始字节不做校验，写入未公开的值可能让鼠标进入异常状态")
+	}
+	if cfg.HotkeyRelease != nil && cfg.HotkeyCycle == nil {
+		add("配置了 hotkey_release 但没有 hotkey_cycle，热键不会注册")
+	}
+	if len(cfg.ManualProfiles) > 0 && cfg.HotkeyCycle == nil {
+		add("配置了 manual_profiles 但没有 hotkey_cycle，手动覆盖无法触发")
+	}
+	if cfg.ConfirmDelay >= cfg.Interval {
+		add("confirm_delay_ms（%s）不小于检查间隔（%s）", cfg.ConfirmDelay, cfg.Interval)
+	}
+	if cfg.Conflict == conflictExit && len(cfg.OfficialProcs) == 0 {
+		add("conflict=exit 但 official_process 为空，冲突检测不会生效")
+	}
+	return ws
+}
+
+// switchIsNoop 不论命中哪条规则、是否电池供电，下发的都与未命中时相同：程序永远不会有可见的动作。
+// 板载槽位的切换看不出槽位内容，按“有效果”算
+func (c *Config) switchIsNoop() bool {
+	if len(c.RuleSlots) > 0 || c.DefaultSlot != 0 || c.HitPollWireless != c.DefaultPollWireless {
+		return false
+	}
+	for _, battery := range []bool{false, true} {
+		def := c.effectiveProfile(false, battery)
+		if c.effectiveProfile(true, battery) != def {
+			return false
+		}
+		for rule := range c.RuleProfiles {
+			if c.profileFor(rule, true, battery) != def {
+				return false
+			}
+		}
+	}
+	return true
+}
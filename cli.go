@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+)
+
+// ==================== 一次性命令（执行完即退出） ====================
+
+// runDumpConfig 打印解析后的完整配置，返回进程退出码；只读，不创建文件
+func runDumpConfig(cfgPath string) int {
+	cfg, _, err := loadConfig(cfgPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取配置失败：%v\n", err)
+		return 1
+	}
+	fmt.Print(formatConfig(cfg))
+	return 0
+}
+
+// runCheckConfig 解析并校验配置，打印警告/错误：0 = 可用，1 = 有错误。
+// 不启动主循环、不访问设备，任何平台都能跑（适合放进 CI）。
+func runCheckConfig(cfgPath string) int {
+	cfg, _, err := loadConfig(cfgPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s: %v\n", cfgPath, err)
+		return 1
+	}
+	ws := configWarnings(cfg)
+	for _, w := range ws {
+		fmt.Fprintf(os.Stderr, "WARN: %s\n", w)
+	}
+	fmt.Printf("OK: %s（%d 条警告）\n", cfgPath, len(ws))
+	return 0
+}
+
+// runAddWhitelist 往配置文件追加一个白名单进程，其余内容（注释、顺序、未知 key）原样保留
+func runAddWhitelist(cfgPath, proc string) int {
+	doc, err := readConfDoc(cfgPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取配置失败：%v\n", err)
+		return 1
+	}
+	if !doc.AddWhitelist(proc) {
+		fmt.Printf("白名单已包含 %s，未修改。\n", proc)
+		return 0
+	}
+	if err := doc.WriteFile(cfgPath); err != nil {
+		fmt.Fprintf(os.Stderr, "写入配置失败：%v\n", err)
+		return 1
+	}
+	fmt.Printf("已加入白名单：%s（%s）\n", proc, cfgPath)
+	return 0
+}
+
+// runOnce 用真实前台窗口跑一次完整的 tickOnce 逻辑并应用，返回退出码
+func runOnce(ctx context.Context, cfgPath string) int {
+	cfg, _, err := loadConfig(cfgPath)
+	if err != nil {
+		log.Printf("[ERR] 读取配置失败：%v", err)
+		return 1
+	}
+	applySessionFlags(cfg)
+	applyLogOptions(cfg.Log)
+	deviceThread.setEnabled(cfg.ApplyThread)
+
+	var last Applied // 空缓存：一定会下发
+	switchMsg, errStr := tickOnce(ctx, cfg, &last, nil, nil)
+	switch {
+	case errStr != "":
+		log.Printf("[ERR] %s", errStr)
+		return 1
+	case switchMsg == "":
+		log.Printf("[ERR] 无法确定前台进程，未做任何修改。")
+		return 1
+	}
+	log.Print(switchMsg)
+	return 0
+}
+
+// runReset 把鼠标恢复到已知基线（baseline_mode/baseline_poll），返回退出码。
+// 已抓到的协议里没有“恢复出厂”命令，这里发送的仍是与自动切换相同的
+// 性能模式 + 回报率报告；只有 persist=true 时才会像自动切换一样额外发送保存命令。
+func runReset(ctx context.Context, cfgPath string) int {
+	cfg, _, err := loadConfig(cfgPath)
+	if err != nil {
+		log.Printf("[ERR] 读取配置失败：%v", err)
+		return 1
+	}
+	applySessionFlags(cfg)
+	applyLogOptions(cfg.Log)
+	deviceThread.setEnabled(cfg.ApplyThread)
+
+	p := cfg.baselineProfile()
+	dev, err := FindOneVaxeeDevice(ctx, cfg.Device)
+	if err != nil {
+		log.Printf("[ERR] 未找到可用 VAXEE 设备：%v", err)
+		return 1
+	}
+	if err := applyProfile(ctx, cfg, dev.Path, p); err != nil {
+		log.Printf("[ERR] 恢复基线失败：%v", err)
+		return 1
+	}
+	log.Printf("[RESET] 已恢复基线 -> %s（设备：%s）", profileName(p), dev.Path)
+	return 0
+}
+
+// runCaps 列出每个 VAXEE 集合的 UsagePage/Usage 与报告长度后退出（排查 SetFeature 长度错误）。
+// 只读 caps，不发送任何报告。
+func runCaps(ctx context.Context) int {
+	infos, err := EnumerateVaxeeDevices(ctx)
+	if err != nil {
+		log.Printf("[ERR] 枚举 HID 设备失败：%v", err)
+		return 1
+	}
+	if len(infos) == 0 {
+		log.Printf("[DEV] 未发现 VAXEE 设备（Manufacturer/Product 不包含 vaxee）。")
+		return 1
+	}
+	groups, nDev := physicalGroups(infos)
+	log.Printf("[DEV] 发现 %d 个 VAXEE HID 集合（%d 个物理设备）：", len(infos), nDev)
+	for i, d := range infos {
+		if d.CapsErr != "" {
+			log.Printf("  #%d 设备%d caps failed（%s） Path=%s", i+1, groups[i], d.CapsErr, d.Path)
+			continue
+		}
+		log.Printf("  #%d 设备%d UsagePage=0x%04x Usage=0x%04x FeatureReportByteLength=%d OutputReportByteLength=%d Path=%s",
+			i+1, groups[i], d.UsagePage, d.Usage, d.FeatureLen, d.OutputLen, d.Path)
+	}
+	return 0
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ==================== 保留注释的配置改写 ====================
+// 改写配置文件时按行原样保留：注释、空行、未知 key、顺序、BOM 和换行风格都不动，
+// 只替换/追加需要修改的那几行。不要用 defaultConfigText()/formatConfig() 重新生成整份文件。
+
+// ConfDoc 按行保存的配置文件
+type ConfDoc struct {
+	lines []string
+	crlf  bool
+	bom   bool
+}
+
+// readConfDoc 读入配置文件；文件不存在时从默认模板开始
+func readConfDoc(path string) (*ConfDoc, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		data, err = []byte(defaultConfigText()), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	d := &ConfDoc{}
+	if rest, ok := bytes.CutPrefix(data, utf8BOM); ok {
+		d.bom, data = true, rest
+	}
+	text := string(data)
+	d.crlf = strings.Contains(text, "\r\n")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.TrimSuffix(text, "\n")
+	if text != "" {
+		d.lines = strings.Split(text, "\n")
+	}
+	return d, nil
+}
+
+// lineKey 与 loadConfig 相同的判定：返回 key=value 行的 key（小写）
+func lineKey(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", false
+	}
+	if isRulePrefixLine(line) {
+		return "", false
+	}
+	if _, _, ok := cutRuleProfileLine(line); ok {
+		return "", false // 按程序配置（cs2.exe: poll=4000），'=' 属于字段
+	}
+	if strings.HasPrefix(line, `"`) {
+		return "", false // 带引号的进程名，'=' 在引号内
+	}
+	if i := strings.IndexByte(line, '='); i > 0 {
+		return strings.ToLower(strings.TrimSpace(line[:i])), true
+	}
+	return "", false
+}
+
+// isRulePrefixLine cmdline: / title: 规则行
+func isRulePrefixLine(line string) bool {
+	_, cmdline := cutPrefixFold(line, cmdlinePrefix)
+	_, title := cutPrefixFold(line, titlePrefix)
+	return cmdline || title
+}
+
+// isWhitelistLine 与 loadConfig 相同的判定：白名单进程行
+func isWhitelistLine(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return false
+	}
+	if isRulePrefixLine(line) {
+		return false
+	}
+	_, isKey := lineKey(line)
+	return !isKey
+}
+
+// whitelistLineNames 白名单行里的各项（未去引号与优先级）：按程序配置行只有冒号前的进程名
+func whitelistLineNames(line string) []string {
+	if proc, _, ok := cutRuleProfileLine(strings.TrimSpace(line)); ok {
+		return []string{proc}
+	}
+	return splitWhitelistLine(line)
+}
+
+// AddWhitelist 追加白名单进程（已存在时返回 false）；接在最后一个白名单行之后，没有则追加到末尾
+func (d *ConfDoc) AddWhitelist(proc string) bool {
+	name := normalizeProcName(proc)
+	if name == "" || name == "." {
+		return false
+	}
+	last := -1
+	for i, l := range d.lines {
+		if !isWhitelistLine(l) {
+			continue
+		}
+		for _, w := range whitelistLineNames(l) {
+			if w, _, _ = cutRuleName(w); normalizeProcName(w) == name {
+				return false
+			}
+		}
+		last = i
+	}
+	if last < 0 {
+		last = len(d.lines) - 1
+	}
+	d.insert(last+1, quoteValue(name))
+	return true
+}
+
+func (d *ConfDoc) insert(i int, line string) {
+	d.lines = append(d.lines, "")
+	copy(d.lines[i+1:], d.lines[i:])
+	d.lines[i] = line
+}
+
+// Bytes 还原为文件内容（保留原 BOM 与换行风格）
+func (d *ConfDoc) Bytes() []byte {
+	nl := "\n"
+	if d.crlf {
+		nl = "\r\n"
+	}
+	var b bytes.Buffer
+	if d.bom {
+		b.Write(utf8BOM)
+	}
+	for _, l := range d.lines {
+		b.WriteString(l)
+		b.WriteString(nl)
+	}
+	return b.Bytes()
+}
+
+// WriteFile 先写临时文件再改名，避免写到一半时热加载读到残缺配置
+func (d *ConfDoc) WriteFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, d.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -5,12 +5,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
 
 const configFileName = "vaxee_autoswitch.conf"
 
+// configDirName 用户配置目录下的子目录名
+const configDirName = "vaxee-autoswitch"
+
 type PerfMode byte
 
 const (
@@ -25,6 +29,15 @@ const (
 	PerfStandardMSOn     PerfMode = 0x04
 )
 
+// 部分固件里 Motion Sync 不编码在性能模式字节里，而是单独的命令（抓包为 0x0a）：
+// 此时性能模式字节只取 competitive/standard（即 *_ms_off 的值），
+// 再另发一条 cmd=0x0a, val=0x01(开)/0x00(关) 的报告。见 motion_sync_report。
+const (
+	cmdMotionSync    = 0x0a
+	motionSyncOffVal = 0x00
+	motionSyncOnVal  = 0x01
+)
+
 type PollingRate int
 
 const (
@@ -33,31 +46,444 @@ const (
 	Poll4000 PollingRate = 4000
 )
 
+// Profile 一组要下发到鼠标的设置
+type Profile struct {
+	Perf     PerfMode
+	Poll     PollingRate
+	Debounce int // 按键消抖（毫秒），0 = 不设置、沿用设备当前值
+
+	// 高级：直接写入报告的原始字节（hit_mode_raw / hit_poll_raw），设置时代替 Perf/Poll 的映射值
+	PerfByte RawByte
+	PollByte RawByte
+
+	// 板载配置槽位（cs2.exe=slot:2 / default_slot），非 0 时只发切换槽位命令，其它项不下发
+	Slot int
+
+	// 指示灯颜色（hit_led / default_led），未设置时不发送
+	LED LEDColor
+
+	// 高级：标准报告之后原样发送的额外报告（extra_report_hit / extra_report_default）
+	Extra ExtraReports
+}
+
+// RawByte 原始字节；0 = 未设置（0x00 本身也是合法字节，所以用高位标记“已设置”）
+type RawByte uint16
+
+func rawByte(b byte) RawByte { return RawByte(0x100 | uint16(b)) }
+
+func (r RawByte) Set() bool { return r != 0 }
+
+func (r RawByte) Byte() byte { return byte(r) }
+
+func (r RawByte) String() string { return fmt.Sprintf("0x%02x", byte(r)) }
+
+// Hotkey 全局热键（RegisterHotKey 的 fsModifiers + vk）
+type Hotkey struct {
+	Mods uint32
+	VK   uint32
+	Spec string // 原始写法，用于日志
+}
+
+// DeviceOptions 设备选择/下发相关选项
+type DeviceOptions struct {
+	SafeMode         bool          // 绝不探测/写入键盘、多媒体控制集合
+	ReportID         byte          // Feature Report ID（抓包为 0x0e）
+	MotionSyncReport bool          // 固件用单独的 0x0a 报告设置 Motion Sync
+	Model            string        // 只控制型号包含该子串的设备（小写，空 = 不限）
+	IgnorePaths      []string      // 路径包含任一子串（小写）的集合不探测、不下发
+	ControlPath      string        // 固定控制通道：路径包含该子串（小写）的集合直接使用，不探测
+	CombinedReport   bool          // 先尝试用一条组合报告同时设置性能模式和回报率
+	Serial           string        // 只控制该序列号的设备（空 = 不限），区分两只同型号鼠标
+	ReportGap        time.Duration // 相邻两条写报告之间的间隔
+	Index            int           // -device N：固定使用枚举列表第 N 个（从 1 开始，0 = 自动选择）
+	Persist          bool          // 下发后再发保存命令，把设置写入鼠标板载存储（断电/重启后保留）
+	ControlSelect    string        // 多个集合都接受 GetFeature 时如何选：rewrite（默认）/ first / verify
+	ControlUsagePage uint16        // 固定控制通道：UsagePage 等于它的集合（0 = 不指定）
+	ControlUsage     uint16        // 与 ControlUsagePage 一起使用（0 = 该页下任意 Usage）
+	Cmd              CommandBytes  // 各设置项的命令字节（cmd_perf 等，默认为抓包值）
+	EnumRetries      int           // 一个 VAXEE 设备都没枚举到时额外重试的次数（USB 瞬断）
+	ContainerID      string        // 只控制该容器 ID 的物理设备（normalizeContainerID 后，空 = 不限）
+	WirelessPIDs     []uint16      // 这些 PID 的接口视为 2.4G 接收器（另按产品名推断，见 isWirelessLink）
+	ReportIDInBuffer bool          // 缓冲区首字节写 ReportID（默认）；false 时写 0（按无编号报告收发）
+
+	// feature_length：固定 Feature 报告长度，先于 caps/探测使用（见 withFeatureLenOverrides）
+	FeatureLens []FeatureLenOverride
+}
+
+// sessionDeviceIndex -device 参数：本次运行期间固定使用的设备序号（不写入配置文件，重载后仍生效）
+var sessionDeviceIndex int
+
+// applySessionFlags 把本次运行的命令行参数（-device）套到刚加载的配置上；loadConfig 只反映配置文件本身
+func applySessionFlags(cfg *Config) {
+	cfg.Device.Index = sessionDeviceIndex
+}
+
+// defaultReportGap 相邻两条写报告之间的默认间隔（固件处理上一条需要一点时间）
+const defaultReportGap = 25 * time.Millisecond
+
+// defaultEnumRetries 枚举不到设备时的默认重试次数；enumRetryDelay 每次重试前的等待
+const (
+	defaultEnumRetries = 2
+	enumRetryDelay     = 100 * time.Millisecond
+)
+
+// defaultReportID VAXEE 控制通道的 Feature ReportID
+const defaultReportID = 0x0e
+
 type Config struct {
-	Interval     time.Duration
-	HitMode      PerfMode
-	HitPoll      PollingRate
-	DefaultMode  PerfMode
-	DefaultPoll  PollingRate
-	Whitelist    []string
-	WhitelistSet map[string]struct{}
-	ConfigPath   string
+	Interval        time.Duration
+	HitInterval     time.Duration // 命中白名单时的检查间隔（0 = 沿用 Interval）
+	DefaultInterval time.Duration // 未命中时的检查间隔（0 = 沿用 Interval）
+	Jitter          time.Duration // 每次等待额外加 [0, Jitter] 的随机量
+	IdleTicks       int           // 前台连续这么多轮不变后逐步拉长间隔（0 = 关闭）
+	IdleMaxInterval time.Duration // 拉长后的间隔上限
+	HitMode         PerfMode
+	HitPoll         PollingRate
+	DefaultMode     PerfMode
+	DefaultPoll     PollingRate
+	HitDebounce     int // 按键消抖（毫秒），0 = 不设置
+	DefaultDebounce int
+	HitModeRaw      RawByte // 命中时直接发送的性能模式字节
+	HitPollRaw      RawByte // 命中时直接发送的回报率字节
+	Whitelist       []string
+	WhitelistSet    map[string]struct{}
+	MatchMode       string                  // 白名单条目的比较方式：exact / substring / prefix
+	CmdlineRules    []string                // cmdline:xxx 规则（已转小写，按子串匹配）
+	TitleRules      []string                // title:xxx 规则（已转小写，按子串匹配前台窗口标题）
+	RulePriority    map[string]int          // 规则优先级（键为进程名、"cmdline:xxx" 或 "title:xxx"，未写的为 0）
+	RuleSlots       map[string]int          // 规则 -> 板载配置槽位（cs2.exe=slot:2），命中时切槽位而不是逐项下发
+	RuleOverrides   map[string]RuleOverride // 按程序配置（cs2.exe: poll=4000）写出的字段
+	RuleProfiles    map[string]Profile      // 以 hit_* 为模板展开后的按程序配置（loadConfig 末尾解析）
+	DefaultSlot     int                     // 未命中时切换到的板载槽位（0 = 按 default_* 逐项下发）
+	ConfigPath      string
+
+	// 手动覆盖：热键循环切换 ManualProfiles，释放热键恢复自动
+	ManualProfiles []Profile
+	HotkeyCycle    *Hotkey
+	HotkeyRelease  *Hotkey
+
+	// 切换历史 CSV（空 = 不记录）；相对路径相对配置文件目录
+	HistoryFile     string
+	HistoryMaxLines int
+
+	// 回报率上限：任何规则算出的回报率都不超过它（0 = 不限）
+	MaxPoll        PollingRate
+	MaxPollBattery PollingRate // 电池供电时的上限（0 = 沿用 MaxPoll）
+
+	// 电池供电时的替代值（0 = 未配置，沿用交流电的值）
+	HitModeBattery     PerfMode
+	HitPollBattery     PollingRate
+	DefaultModeBattery PerfMode
+	DefaultPollBattery PollingRate
+
+	// 命中 / 未命中时的指示灯颜色（0 = 不设置）
+	HitLED     LEDColor
+	DefaultLED LEDColor
+
+	// 高级：命中 / 未命中时在标准报告之后额外发送的整条报告
+	HitExtra     ExtraReports
+	DefaultExtra ExtraReports
+
+	// 经 2.4G 接收器连接时的回报率与上限（0 = 未配置，沿用有线的值 / 不另设上限）
+	HitPollWireless     PollingRate
+	DefaultPollWireless PollingRate
+	MaxPollWireless     PollingRate
+
+	Device       DeviceOptions
+	Log          LogOptions
+	ApplyTimeout time.Duration // 整个下发过程的超时
+
+	// -reset 使用的基线配置（未配置时取 default_mode/default_poll）
+	BaselineMode PerfMode
+	BaselinePoll PollingRate
+
+	// 切换前间隔 ConfirmDelay 再读一次前台，两次一致才动作（0 = 不确认）
+	ConfirmDelay time.Duration
+
+	// 反作弊敏感的进程：在前台时首次下发后不再访问鼠标，直到离开前台（已归一化）
+	NoTouchProcs []string
+
+	// 只有其中任一进程在运行时才按前台切换（已归一化，空 = 不限）
+	RequireRunning []string
+
+	// while_running：这些进程在运行时固定用对应配置，优先于按前台的规则（按书写顺序）
+	AmbientRules []AmbientRule
+
+	// device_profile：命中时按当前设备的序列号/容器 ID 改用的模式与回报率（按书写顺序）
+	DeviceProfiles []DeviceProfile
+
+	// 官方软件冲突检测：进程名（小写，空 = 不检测）与处理方式 warn/exit
+	OfficialProcs []string
+	Conflict      string
+
+	// 找不到 VAXEE 时是否列出全部 HID 设备，以及最多列出多少个（0 = 不限）
+	DumpAllHID    bool
+	DumpAllHIDMax int
+
+	// 启动时读取型号/固件、设备能力（命令都是推测的，默认不发）
+	ProbeDeviceInfo bool
+	ProbeCaps       bool
+
+	// 启动后立即评估并下发一次（false = 等满第一个间隔）
+	ApplyOnStart bool
+
+	// 从睡眠唤醒后清空已应用缓存并立即重新下发（鼠标唤醒后可能丢设置）
+	ResumeReapply bool
+
+	// 所有设备 I/O 都在专用的锁定 OS 线程上串行执行（false = 在调用方直接执行）
+	ApplyThread bool
+
+	// 退出（Ctrl+C / 关机）时恢复到基线并读回确认；RestoreRetry 未确认时再试一次
+	RestoreOnExit bool
+	RestoreRetry  bool
+
+	// 重载配置失败时弹窗提示，可一键用默认编辑器打开配置文件（默认关闭：游戏中弹窗会打断操作）
+	NotifyConfigError bool
+
+	// 本程序自身窗口在前台时不参与匹配（保持当前配置）
+	SelfExclude bool
+
+	// 启动、重新连接、唤醒后读回设备实际状态再决定是否下发（官方软件等改动设置时也能纠正）
+	TrustDeviceReadback bool
+
+	// 状态文件（JSON，含 in_game），空 = 不写
+	StatusFile string
+
+	// 只在该会话的前台上切换：any / console / 会话 ID（远程桌面时避免误切换）
+	Session string
+
+	// 显示状态窗口（仅 Windows，启动时生效）
+	GUI bool
+
+	// 命名管道命令服务（如 \\.\pipe\vaxee），空 = 不启用；启动时生效
+	PipeName string
+
+	// 启动时让控制台窗口最小化且不抢焦点（开机自启时不打断当前窗口）
+	NoActivate bool
+
+	// 安静时段：期间不自动切换、日志只保留错误
+	QuietHours QuietHours
+
+	// 白名单进程一启动就提前切换（不等它拿到前台）；启动时生效
+	WatchProcessStart bool
+
+	// 学习模式：只记录新出现的前台进程，不切换、不碰设备
+	LearnMode bool
+	LearnFile string // 可选：同时追加到该文件（相对路径相对配置文件目录）
+
+	// 心跳日志间隔（0 = 关闭）
+	Heartbeat time.Duration
+
+	// 无法识别的 key（忽略，但 -check-config 会提示，多半是拼写错误）
+	UnknownKeys []string
 }
 
 func defaultConfigText() string {
 	// 预设：命中白名单 -> competitive_ms_off + 1000Hz，否则 -> standard_ms_off + 1000Hz
 	return `# VAXEE AutoSwitch 配置文件
 # --------------------------------------------
+# 配置文件查找顺序：
+#   -config 参数 > %APPDATA%\vaxee-autoswitch\（Linux: ~/.config/vaxee-autoswitch/）> 程序所在目录
+#   都没有时在 %APPDATA%\vaxee-autoswitch\ 下生成本文件
+#
 # 说明：
 # 1) 以 key=value 配置策略
-# 2) 其余非空、非 # 开头的行，会被当作“白名单程序名”（每行一个，例如 cs2.exe）
+# 2) 其余非空、非 # 开头的行，会被当作“白名单程序名”（每行一个，例如 cs2.exe；
+#    也可以一行写多个，用逗号分隔：cs2.exe, valorant.exe）
+#    白名单的比较方式由 match_mode 决定：
+#      exact（默认）= 进程名完全相同；substring = 进程名包含该条目（cs2 可命中 cs2.exe）；
+#      prefix = 进程名以该条目开头
+# 3) cmdline:子串 形式的行按前台进程命令行匹配（不区分大小写），
+#    适合通过启动器拉起、exe 名很通用的游戏，例如 cmdline:-game=csgo
+#    读取命令行失败（如提权进程）时静默跳过
+#    title:子串 形式的行按前台窗口标题匹配（不区分大小写），例如 title:"My Game: Edition"
+# 4) 规则末尾可加 " @整数" 指定优先级（默认 0，负数排在默认规则之后），例如 cs2.exe @10、cmdline:-game=csgo @5、launcher.exe @-1
+#    " @" 后不是整数时报错；名字本身含 " @" 时请加引号
+#    多条规则同时命中时的裁决顺序：
+#      优先级高者 > 更具体的规则（精确 > prefix > substring > cmdline > title）> 配置文件中先写的
+# 5) 进程名=slot:N 把该程序映射到鼠标的板载配置槽位（推测协议，需固件支持），例如 cs2.exe=slot:2：
+#    命中时只发一条切换槽位命令，参数由槽位自身决定（不逐项下发）；未命中时可用 default_slot=N 切回
+# 6) 进程名: 字段=值 为单个程序指定配置，以 hit_* 为模板只改写列出的字段（mode / poll / debounce），
+#    例如 cs2.exe: poll=4000 或 valorant.exe: mode=competitive_ms_on, poll=2000（同时加入白名单）；
+#    interval=秒 指定该程序在前台时的检查间隔，例如 cs2.exe: interval=5
+# 7) 值或进程名含 , : = @ 或首尾空格时可加双引号原样保留，例如 "My Game: Edition.exe" @5、
+#    cmdline:"-mode=ranked, -novid"、learn_file="D:\My Logs\learned.txt"；引号内 \" 表示引号本身
 #
 # 可配置项：
+# match_mode=exact                   # 白名单比较方式：exact / substring / prefix
 # interval_seconds=60                # 检查前台程序间隔（秒），默认 60
+# hit_interval_seconds=              # 命中白名单（游戏在前台）时的检查间隔，可设短一些如 5；留空沿用 interval_seconds
+# default_interval_seconds=          # 未命中（桌面等）时的检查间隔；留空沿用 interval_seconds
+# interval_jitter_ms=0               # 每次间隔额外加 0~N 毫秒随机量，避免与其他定时任务扎堆，默认 0
+# idle_backoff_ticks=0               # >0 时，前台进程连续 N 轮没变就把检查间隔翻倍（每再过 N 轮再翻倍），
+#                                    # 一变就恢复正常间隔；离开电脑时少做无用的检查。0 = 关闭
+# idle_max_interval_seconds=300      # 翻倍后的间隔上限（也是离开后回来时最长的切换延迟）
 # hit_mode=competitive_ms_off        # 命中白名单时性能模式：standard_ms_off / competitive_ms_off / competitive_ms_on / standard_ms_on
+#                                    # 也可以只写 competitive / standard，再用 hit_motion_sync 单独指定 Motion Sync
 # hit_poll=1000                      # 命中白名单时回报率：1000 / 2000 / 4000
 # default_mode=standard_ms_off       # 未命中时性能模式
 # default_poll=1000                  # 未命中时回报率
+# hit_debounce=                      # 命中时按键消抖（毫秒）：1 / 2 / 4 / 8 / 12 / 16；留空 = 不修改设备当前值
+# default_debounce=                  # 未命中时按键消抖（毫秒）
+# hit_led=                           # 命中时指示灯颜色：#rrggbb 或 red/green/blue/yellow/cyan/purple/white/off；
+#                                    # 留空 = 不改指示灯（指示灯命令未经验证；设备拒绝一次后不再发送，见 cmd_led）
+# default_led=                       # 未命中时指示灯颜色
+# default_slot=                      # 未命中时切换到的板载配置槽位（1..5，配合 进程名=slot:N），留空 = 按 default_* 逐项下发
+#
+# 高级 / 不安全（固件实验用）：直接指定报告里的原始字节，覆盖 hit_mode / hit_poll 的映射值，
+# 不做任何校验（也不受 max_poll 限制）。写入未公开的值可能让鼠标进入异常状态，后果自负：
+# hit_mode_raw=0x07                  # 命中时性能模式字节（支持 0x 十六进制）
+# hit_poll_raw=0x05                  # 命中时回报率字节
+# 新固件的参数程序还不支持时，可直接写整条 Feature 报告（冒号分隔的十六进制字节，首字节为 report_id），
+# 切换到对应配置时在标准报告之后原样发送（不足 Feature 报告长度的部分补 0）；可重复写多条：
+# extra_report_hit=0e:a5:0e:02:01:03
+# extra_report_default=0e:a5:0e:02:01:00
+#
+# 电池供电时的替代值（可选，笔记本用；不配置则与交流电相同）：
+# hit_mode_battery=competitive_ms_off
+# hit_poll_battery=1000
+# default_mode_battery=standard_ms_on
+# default_poll_battery=1000
+#
+# 回报率上限（可选，不论命中与否都生效，超过时压到上限并在切换日志里注明）：
+# max_poll=                          # 例如 2000；留空 = 不限
+# max_poll_battery=2000              # 电池供电时的上限；留空沿用 max_poll
+#
+# 有线 / 无线分开配置（可选，有线 + 2.4G 双模鼠标用；接收器跑不满高回报率时）：
+# hit_poll_wireless=4000             # 经接收器连接时命中的回报率；留空与有线相同
+# default_poll_wireless=             # 经接收器连接时未命中的回报率
+# max_poll_wireless=4000             # 经接收器连接时的上限（与 max_poll 取较低者）
+# wireless_pid=                      # 逗号分隔的接收器 PID（如 0x1234，见启动日志）；不配置时只有产品名含 receiver/dongle 的接口视为无线
+#                                    # 配置了以上任一项后每轮都确认连接方式，插拔切换后按新的连接方式重新下发
+#
+# safe_mode=false                    # true 时绝不向键盘(UsagePage 0x01/Usage 0x06)、多媒体(UsagePage 0x0C)
+#                                    # 集合发送探测或设置报告（而不只是把 \kbd 排到最后）
+# apply_timeout_ms=3000              # 单次下发（性能模式+回报率）的超时，设备卡住时放弃并继续
+# apply_thread=true                  # 所有设备访问（枚举、探测、读回、下发）都在一个专用线程上串行执行
+#                                    # （该线程单独设低优先级/EcoQoS，卡住超时后换新线程）；false = 在调用方直接执行
+# device_model=                      # 只控制型号包含该字符串的 VAXEE（如 xe-s），需固件支持设备信息读取
+# probe_device_info=false            # true = 启动时读取型号/固件并显示（推测的设备信息命令 0x01，未经验证）；
+#                                    # 配置了 device_model 时查找设备总会读取
+# probe_caps=false                   # true = 启动时读取设备自报的回报率/模式并对照配置（推测的能力命令 0x02）；
+#                                    # 读取失败或不符时只警告，不拒绝配置
+# serial=                            # 只控制该序列号的设备（启动日志里的 Serial），用于区分两只同型号鼠标；
+#                                    # 设备不提供序列号时无法用此项筛选
+# container_id=                      # 只控制该物理设备（启动日志里的 Container={...}）：Windows 按物理设备分配，
+#                                    # 换 USB 口、重启都不变，没有序列号时用它区分多只鼠标
+# device_profile=serial:ABC123 => competitive_ms_on,4000   # 可重复；命中白名单时，序列号（或 container:{...}）
+#                                    # 匹配的设备改用这组模式/回报率，两只同型号鼠标可各用各的
+# ignore_path=\\?\hid#vid_xxxx&pid_yyyy&mi_02   # 可重复；路径包含该子串（不区分大小写）的集合直接跳过，
+#                                    # 用于绕开某个一调用 GetFeature 就卡住的集合
+# control_path=\\?\hid#vid_xxxx&pid_yyyy&mi_01   # 固定控制通道（子串匹配）：存在时直接使用、跳过探测；
+#                                    # 找不到时回退到自动探测
+# control_usage_page=0xff00          # 按 UsagePage（可再加 control_usage=0x01）固定控制通道：重启后路径变了也能找到；
+# control_usage=                     # 在 control_path 之后判断，没有匹配的集合时回退到自动探测（-caps 可查看各集合的值）
+# control_select=rewrite             # 多个集合都接受 ReportID 时如何选控制通道（每次运行只确认一次）：
+#                                    # rewrite：读出当前性能模式并原样写回，写入成功的即控制通道（不改变设置）；
+#                                    # first：第一个非键盘集合（旧行为）；verify：临时改回报率并读回，选真正生效的那个；
+#                                    # rewrite/verify 读不出当前值时按 first
+# combined_report=false             # 固件支持时用一条组合报告同时设置性能模式+回报率（更快、不会只改一半），
+#                                    # 设备拒绝时自动回退到分两条发送
+# persist=false                      # true = 每次下发后再发保存命令，设置写入鼠标闪存（重启/换电脑后仍保留）；
+#                                    # 闪存擦写次数有限，频繁切换会加速损耗，默认只在本次上电期间生效
+# dump_all_hid=true                  # 找不到 VAXEE 时列出系统全部 HID 设备（排查用，外设多时很长）
+# dump_all_hid_max=0                 # 最多列出多少个，0 = 不限
+# report_gap_ms=25                   # 相邻两条设置报告之间的间隔（毫秒），设备偶尔丢设置时可调大
+# enum_retries=2                     # 一个 VAXEE 设备都没枚举到时，隔 100ms 重新枚举的次数（过滤 USB 瞬断），0 = 不重试
+# report_id=0x0e                     # Feature ReportID（高级，支持 0x 十六进制）
+# report_id_in_buffer=true           # 报告缓冲区首字节写 report_id（默认，与抓包一致）；false = 首字节写 0，
+#                                    # 给报告描述符不带 ReportID 的 HID 栈用。怎么判断：官方软件抓包里报告首字节
+#                                    # 是 0x0e 就保持 true；是 0x00，或每次下发都报 Incorrect function / 参数错误、
+#                                    # 而 -caps 能正常列出集合时，改成 false 后用 -once 试一次，能下发成功的就是对的
+# feature_length=65                  # 可重复；固定 Feature 报告长度，caps 取不到/给错长度时用（先于 caps 和自动探测）。
+#                                    # 写成 0x3057=65 或 0x3057:0x0001=65 只对该 VID（/PID）生效，越具体越优先；
+#                                    # 超过集合的 OutputReportByteLength（-caps 可见）时忽略
+# cmd_perf=0x08                      # 高级：各设置项的命令字节，固件更新改了命令号时覆盖（支持 0x 十六进制）
+# cmd_poll=0x07
+# cmd_motion_sync=0x0a               # motion_sync_report=true 时使用
+# cmd_debounce=0x0c
+# cmd_combined=0x09                  # combined_report=true 时使用
+# cmd_save=0x0d                      # persist=true 时使用
+# cmd_slot=0x0b                      # 板载配置槽位（见下方 slot:N）
+# cmd_led=0x10                       # 指示灯颜色（hit_led/default_led 时使用；数据为 R G B 三字节，格式未经验证）
+# apply_on_start=true                # 启动后立即按当前前台切换一次；false 则等满第一个检查间隔
+# resume_reapply=true                # 电脑从睡眠唤醒后立即重新下发当前配置（鼠标唤醒后可能恢复成板载设置）
+# trust_device_readback=false        # true = 启动、鼠标重新连接、系统唤醒后读回鼠标实际设置，与目标不一致就重新下发
+#                                    # （其它软件在背后改了设置也能纠正；平时不额外读设备）
+# notify_config_error=false          # true = 修改后的配置加载失败时弹窗提示，点“是”用默认编辑器打开配置文件；
+#                                    # 弹窗不抢前台（只在任务栏闪烁），但仍建议只在调配置时打开
+# watch_process_start=false          # true = 白名单进程一启动（加载画面、尚未切到前台）就提前切换；
+#                                    # 切回仍按前台判断。以管理员运行时订阅 WMI 进程启动事件，否则每秒对比一次进程列表；
+#                                    # 修改后需重启生效
+# self_exclude=true                  # 本程序的控制台/状态窗口在前台时不当作前台进程匹配，保持当前配置
+# confirm_delay_ms=0                 # >0 时，切换前隔这么久再读一次前台进程，两次一致才切换
+#                                    # （过滤启动时一闪而过的提示框/闪屏窗口），例如 300
+#
+# 反作弊安全（可选）：有的反作弊会把游戏运行时的 HID 写入视为可疑
+# no_touch_while_focused=valorant.exe, faceitclient.exe   # 逗号分隔，可重复；这些进程在前台时只在切过来时下发一次，
+#                                    # 之后不再读写鼠标（包括唤醒重发、trust_device_readback 读回），离开前台后恢复
+#
+# 游戏会话（可选）：
+# require_running=steam.exe          # 逗号分隔，可重复；这些进程一个都没在运行时不按前台切换、保持 default 配置，
+#                                    # 任一启动后恢复（例如只在游戏启动器运行期间自动切换）
+# while_running=obs64.exe => standard_ms_on,1000   # 可重复；该进程在运行（不必在前台）时固定用这组配置，
+#                                    # 优先于白名单（例如录制期间）；多条时按书写顺序取第一条在运行的
+#
+# 官方软件冲突检测（两者同时运行会互相改回设置）：
+# official_process=vaxee.exe, vaxee mouse setting.exe   # 逗号分隔的官方软件进程名；留空 = 不检测
+# conflict=warn                      # warn：启动及运行中检测到时打警告；exit：启动时检测到则不开始自动切换
+#
+# 远程桌面（可选）：
+# session=any                        # any：不限制；console：前台不在本地控制台会话（如远程桌面连入时）就不切换；
+#                                    # 也可填会话 ID。注意：作为服务/SYSTEM 任务运行在 session 0 时取不到前台窗口
+#
+# 手动覆盖（可选）：
+# manual_profiles=competitive_ms_off:4000, standard_ms_on:1000   # 热键循环的配置列表（mode:poll）
+# hotkey_cycle=ctrl+alt+f9           # 进入手动覆盖 / 切到下一个配置
+# hotkey_release=ctrl+alt+f10        # 退出手动覆盖，恢复自动切换
+#
+# 日志（可选）：
+# log_microseconds=false             # 时间戳精确到微秒，便于观察切换延迟
+# log_uptime=false                   # 每行前加进程运行时长，如 [+12.345s]
+# log_utc=false                      # true 时时间戳用 UTC（多台电脑的日志汇总到一起时便于对齐），默认本地时间
+# log_verbose=false                  # true 时输出 [DBG] 调试日志，如选择控制通道时每个集合 GetFeature 的原始返回
+# log_format=text                    # json = 每条日志一行 JSON（ts/level/event/proc/mode/poll/device/err/msg），
+#                                    # 便于 Loki 等采集；此时 log_microseconds/log_uptime 不生效
+# log_file_daily=false               # true = 日志同时写入配置目录下 logs\vaxee-2024-06-01.log，每天一个文件，过零点自动换新
+# log_file_keep=14                   # 按天日志保留最近多少个文件，更旧的自动删除；0 = 全部保留
+# log_max_total_mb=0                 # 按天日志总大小上限（MB），超过时从最旧的开始删；0 = 不限。
+#                                    # 两项都在启动时和每天换文件时检查，删掉的文件会记一条 [LOG] 日志
+# heartbeat_seconds=0                # >0 时每隔这么久打一行“仍在运行”概要（当前配置、设备、切换次数），如 600
+#
+# -reset 基线（可选）：
+# baseline_mode=standard_ms_off      # 未配置时用 default_mode
+# baseline_poll=1000                 # 未配置时用 default_poll
+# restore_on_exit=false              # true = 退出（Ctrl+C / 关机）时也恢复到基线，并读回确认是否生效
+# restore_retry=true                 # 恢复后读回不一致时重试一次（整个过程不超过 3 秒，不拖慢退出）
+#
+# 状态文件（可选，给 OBS 等外部程序读）：
+# status_file=status.json            # 每轮检查后更新，如 {"in_game":true,"process":"cs2.exe",...}；
+#                                    # in_game = 当前前台命中白名单。内容不变时不写盘
+#
+# 状态窗口（可选，仅 Windows）：
+# gui=false                          # true 时额外显示一个状态小窗口（不置顶、不抢焦点）：当前进程、模式、回报率、设备和最近日志；
+#                                    # 点关闭按钮只是最小化，退出程序仍用控制台 Ctrl+C。修改后需重启生效
+# pipe_name=\\.\pipe\vaxee           # 本机命名管道命令（给 Stream Deck 插件等用），留空 = 关闭。每行一条命令：
+#                                    # apply <mode> <poll> / status / reload / pause / resume，每条回复一行 OK/ERR。修改后需重启生效
+# no_activate=false                  # true = 启动后立即把控制台最小化、把焦点还给原来的窗口
+#                                    # （开机自启时不抢焦点，也避免因此误触发一次切换）。修改后需重启生效
+#
+# 安静时段（可选）：
+# quiet_hours=23:00-07:00            # 每天这段时间完全不自动切换、不访问鼠标，日志只保留错误与警告；可跨午夜，
+#                                    # 留空/off = 关闭。结束时立即按当前前台下发一次。热键/管道命令不受影响
+#
+# 学习模式（可选，整理白名单用）：
+# learn_mode=false                   # true 时不切换、不访问鼠标，只把每个新出现的前台进程名打到日志
+# learn_file=learned.txt             # 同时追加到该文件（每个进程一行，前面一行注释是首次出现时间），可直接复制进白名单
+#
+# 切换历史（可选，CSV，可直接用 Excel 打开）：
+# history_file=switches.csv          # 每次切换追加一行：时间、进程、旧/新模式与回报率、结果
+# history_max_lines=10000            # 最多保留的记录条数，超出后丢弃最旧的
 #
 # --------------------------------------------
 interval_seconds=60
@@ -72,6 +498,44 @@ default_poll=1000
 `
 }
 
+// userConfigPath 用户配置目录里的配置文件路径：
+// Windows 为 %APPDATA%\vaxee-autoswitch\，Linux 为 $XDG_CONFIG_HOME（或 ~/.config）/vaxee-autoswitch/
+func userConfigPath() (string, bool) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", false
+	}
+	return filepath.Join(dir, configDirName, configFileName), true
+}
+
+// resolveConfigPath 按以下顺序确定配置文件：
+//  1. -config 参数（explicit 非空时直接使用）
+//  2. 用户配置目录（%APPDATA% / XDG）里已存在的配置
+//  3. 可执行文件同目录里已存在的配置（兼容旧版本）
+//  4. 都不存在：在用户配置目录新建；取不到用户配置目录时退回可执行文件目录
+func resolveConfigPath(explicit string) string {
+	if explicit != "" {
+		return explicit
+	}
+	userPath, hasUser := userConfigPath()
+	if hasUser && fileExists(userPath) {
+		return userPath
+	}
+	exePath := filepath.Join(exeDir(), configFileName)
+	if fileExists(exePath) {
+		return exePath
+	}
+	if hasUser {
+		return userPath
+	}
+	return exePath
+}
+
+func fileExists(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && !fi.IsDir()
+}
+
 func ensureConfigExists(path string) error {
 	_, err := os.Stat(path)
 	if err == nil {
@@ -80,6 +544,9 @@ func ensureConfigExists(path string) error {
 	if !os.IsNotExist(err) {
 		return err
 	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
 	return os.WriteFile(path, []byte(defaultConfigText()), 0644)
 }
 
@@ -97,7 +564,31 @@ func loadConfig(path string) (*Config, time.Time, error) {
 		DefaultPoll:  Poll1000,
 		Whitelist:    []string{},
 		WhitelistSet: map[string]struct{}{},
+		MatchMode:    matchExact,
 		ConfigPath:   path,
+
+		Device: DeviceOptions{
+			ReportID:    defaultReportID,
+			ReportGap:   defaultReportGap,
+			Cmd:         defaultCommandBytes,
+			EnumRetries: defaultEnumRetries,
+
+			ReportIDInBuffer: true,
+			ControlSelect:    controlSelectRewrite,
+		},
+		ApplyTimeout:    defaultApplyTimeout,
+		IdleMaxInterval: defaultIdleMaxInterval,
+		Log:             LogOptions{Format: logFormatText, Keep: defaultLogKeep, Dir: filepath.Join(filepath.Dir(path), "logs")},
+		HistoryMaxLines: defaultHistoryMaxLines,
+		OfficialProcs:   append([]string(nil), defaultOfficialProcs...),
+		Conflict:        conflictWarn,
+		ResumeReapply:   true,
+		RestoreRetry:    true,
+		ApplyThread:     true,
+		SelfExclude:     true,
+		ApplyOnStart:    true,
+		DumpAllHID:      true,
+		Session:         sessionAny,
 	}
 
 	f, err := os.Open(path)
@@ -106,6 +597,9 @@ func loadConfig(path string) (*Config, time.Time, error) {
 	}
 	defer f.Close()
 
+	// Motion Sync 覆盖项在读完全部配置后再折算进模式（与书写顺序无关）
+	var hitMS, defaultMS *bool
+
 	sc := bufio.NewScanner(f)
 	for sc.Scan() {
 		line := strings.TrimSpace(sc.Text())
@@ -113,9 +607,61 @@ func loadConfig(path string) (*Config, time.Time, error) {
 			continue
 		}
 
-		if i := strings.IndexByte(line, '='); i > 0 {
+		// cmdline / title 规则：子串里可能带 '=' 或 ':'，必须在 key=value 与按程序配置之前处理
+		if rule, ok := cutPrefixFold(line, cmdlinePrefix); ok {
+			name, prio, e := cutRuleName(rule)
+			if e != nil {
+				return nil, time.Time{}, fmt.Errorf("invalid %s%s: %w", cmdlinePrefix, rule, e)
+			}
+			rule = strings.ToLower(name)
+			if rule != "" {
+				cfg.CmdlineRules = append(cfg.CmdlineRules, rule)
+				cfg.setRulePriority(cmdlinePrefix+rule, prio)
+			}
+			continue
+		}
+		if rule, ok := cutPrefixFold(line, titlePrefix); ok {
+			name, prio, e := cutRuleName(rule)
+			if e != nil {
+				return nil, time.Time{}, fmt.Errorf("invalid %s%s: %w", titlePrefix, rule, e)
+			}
+			rule = strings.ToLower(name)
+			if rule != "" {
+				cfg.TitleRules = append(cfg.TitleRules, rule)
+				cfg.setRulePriority(titlePrefix+rule, prio)
+			}
+			continue
+		}
+
+		// 按程序配置：cs2.exe: poll=4000（同时加入白名单）
+		if name, fields, ok := cutRuleProfileLine(line); ok {
+			o, e := parseRuleOverride(fields)
+			if e != nil {
+				return nil, time.Time{}, fmt.Errorf("invalid profile for %s: %w", name, e)
+			}
+			proc, prio, e := cutRuleName(name)
+			if e != nil {
+				return nil, time.Time{}, fmt.Errorf("invalid profile for %s: %w", name, e)
+			}
+			proc = normalizeProcName(proc)
+			cfg.Whitelist = append(cfg.Whitelist, proc)
+			cfg.WhitelistSet[proc] = struct{}{}
+			cfg.setRulePriority(proc, prio)
+			if cfg.RuleOverrides == nil {
+				cfg.RuleOverrides = map[string]RuleOverride{}
+			}
+			cfg.RuleOverrides[proc] = o
+			continue
+		}
+
+		// 以引号开头的是带引号的进程名（白名单行），其中的 '=' 不是 key=value 分隔符
+		if i := strings.IndexByte(line, '='); i > 0 && !strings.HasPrefix(line, `"`) {
 			key := strings.ToLower(strings.TrimSpace(line[:i]))
-			val := strings.TrimSpace(line[i+1:])
+			raw := strings.TrimSpace(line[i+1:]) // 进程名列表按项去引号，用未处理的原值
+			val, e := unquoteValue(raw)
+			if e != nil {
+				return nil, time.Time{}, fmt.Errorf("invalid %s: %w", key, e)
+			}
 
 			switch key {
 			case "interval_seconds":
@@ -125,6 +671,45 @@ func loadConfig(path string) (*Config, time.Time, error) {
 				}
 				cfg.Interval = time.Duration(sec) * time.Second
 
+			case "match_mode":
+				m, e := parseMatchMode(val)
+				if e != nil {
+					return nil, time.Time{}, e
+				}
+				cfg.MatchMode = m
+
+			case "hit_interval_seconds", "default_interval_seconds":
+				sec, e := parseInt(val)
+				if e != nil || sec <= 0 {
+					return nil, time.Time{}, fmt.Errorf("invalid %s: %s (must be > 0)", key, val)
+				}
+				if key == "hit_interval_seconds" {
+					cfg.HitInterval = time.Duration(sec) * time.Second
+				} else {
+					cfg.DefaultInterval = time.Duration(sec) * time.Second
+				}
+
+			case "interval_jitter_ms":
+				ms, e := parseInt(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid interval_jitter_ms: %s", val)
+				}
+				cfg.Jitter = time.Duration(ms) * time.Millisecond
+
+			case "idle_backoff_ticks":
+				n, e := parseInt(val)
+				if e != nil || n < 0 {
+					return nil, time.Time{}, fmt.Errorf("invalid idle_backoff_ticks: %s", val)
+				}
+				cfg.IdleTicks = n
+
+			case "idle_max_interval_seconds":
+				sec, e := parseInt(val)
+				if e != nil || sec <= 0 {
+					return nil, time.Time{}, fmt.Errorf("invalid idle_max_interval_seconds: %s (must be > 0)", val)
+				}
+				cfg.IdleMaxInterval = time.Duration(sec) * time.Second
+
 			case "hit_mode":
 				m, e := parsePerf(val)
 				if e != nil {
@@ -142,6 +727,20 @@ func loadConfig(path string) (*Config, time.Time, error) {
 					return nil, time.Time{}, e
 				}
 
+			case "hit_debounce", "default_debounce":
+				n, e := parseInt(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid %s: %s", key, val)
+				}
+				if _, e := debounceToByte(n); e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid %s: %w", key, e)
+				}
+				if key == "hit_debounce" {
+					cfg.HitDebounce = n
+				} else {
+					cfg.DefaultDebounce = n
+				}
+
 			case "default_mode":
 				m, e := parsePerf(val)
 				if e != nil {
@@ -158,24 +757,661 @@ func loadConfig(path string) (*Config, time.Time, error) {
 				if _, e := pollingToYY(cfg.DefaultPoll); e != nil {
 					return nil, time.Time{}, e
 				}
+
+			case "manual_profiles":
+				ps, e := parseProfileList(val)
+				if e != nil {
+					return nil, time.Time{}, e
+				}
+				cfg.ManualProfiles = ps
+
+			case "hotkey_cycle", "hotkey_release":
+				hk, e := parseHotkey(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid %s: %w", key, e)
+				}
+				if key == "hotkey_cycle" {
+					cfg.HotkeyCycle = &hk
+				} else {
+					cfg.HotkeyRelease = &hk
+				}
+
+			case "hit_mode_battery", "default_mode_battery":
+				m, e := parsePerf(val)
+				if e != nil {
+					return nil, time.Time{}, e
+				}
+				if key == "hit_mode_battery" {
+					cfg.HitModeBattery = m
+				} else {
+					cfg.DefaultModeBattery = m
+				}
+
+			case "hit_poll_battery", "default_poll_battery":
+				n, e := parseInt(val)
+				if e != nil {
+					return nil, time.Time{}, e
+				}
+				if _, e := pollingToYY(PollingRate(n)); e != nil {
+					return nil, time.Time{}, e
+				}
+				if key == "hit_poll_battery" {
+					cfg.HitPollBattery = PollingRate(n)
+				} else {
+					cfg.DefaultPollBattery = PollingRate(n)
+				}
+
+			case "max_poll", "max_poll_battery":
+				n, e := parseInt(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid %s: %s", key, val)
+				}
+				if _, e := pollingToYY(PollingRate(n)); e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid %s: %w", key, e)
+				}
+				if key == "max_poll" {
+					cfg.MaxPoll = PollingRate(n)
+				} else {
+					cfg.MaxPollBattery = PollingRate(n)
+				}
+
+			case "hit_poll_wireless", "default_poll_wireless", "max_poll_wireless":
+				n, e := parseInt(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid %s: %s", key, val)
+				}
+				if _, e := pollingToYY(PollingRate(n)); e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid %s: %w", key, e)
+				}
+				switch key {
+				case "hit_poll_wireless":
+					cfg.HitPollWireless = PollingRate(n)
+				case "default_poll_wireless":
+					cfg.DefaultPollWireless = PollingRate(n)
+				default:
+					cfg.MaxPollWireless = PollingRate(n)
+				}
+
+			case "feature_length":
+				o, e := parseFeatureLenOverride(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid feature_length: %s (%v)", val, e)
+				}
+				cfg.Device.FeatureLens = append(cfg.Device.FeatureLens, o)
+
+			case "wireless_pid":
+				cfg.Device.WirelessPIDs = nil
+				for _, item := range splitWhitelistLine(val) {
+					n, e := parseHexInt(item)
+					if e != nil || n <= 0 || n > 0xffff {
+						return nil, time.Time{}, fmt.Errorf("invalid wireless_pid: %s", item)
+					}
+					cfg.Device.WirelessPIDs = append(cfg.Device.WirelessPIDs, uint16(n))
+				}
+
+			case "hit_motion_sync", "default_motion_sync":
+				b, e := parseBool(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid %s: %s", key, val)
+				}
+				if key == "hit_motion_sync" {
+					hitMS = &b
+				} else {
+					defaultMS = &b
+				}
+
+			case "combined_report":
+				b, e := parseBool(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid combined_report: %s", val)
+				}
+				cfg.Device.CombinedReport = b
+
+			case "persist":
+				b, e := parseBool(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid persist: %s", val)
+				}
+				cfg.Device.Persist = b
+
+			case "motion_sync_report":
+				b, e := parseBool(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid motion_sync_report: %s", val)
+				}
+				cfg.Device.MotionSyncReport = b
+
+			case "safe_mode":
+				b, e := parseBool(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid safe_mode: %s", val)
+				}
+				cfg.Device.SafeMode = b
+
+			case "apply_timeout_ms":
+				ms, e := parseInt(val)
+				if e != nil || ms <= 0 {
+					return nil, time.Time{}, fmt.Errorf("invalid apply_timeout_ms: %s", val)
+				}
+				cfg.ApplyTimeout = time.Duration(ms) * time.Millisecond
+
+			case "device_model":
+				cfg.Device.Model = strings.ToLower(val)
+
+			case "serial":
+				cfg.Device.Serial = val
+
+			case "container_id":
+				cfg.Device.ContainerID = normalizeContainerID(val)
+
+			case "device_profile":
+				r, e := parseDeviceProfile(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid device_profile: %w", e)
+				}
+				cfg.DeviceProfiles = append(cfg.DeviceProfiles, r)
+
+			case "ignore_path":
+				if val != "" {
+					cfg.Device.IgnorePaths = append(cfg.Device.IgnorePaths, strings.ToLower(val))
+				}
+
+			case "control_path":
+				cfg.Device.ControlPath = strings.ToLower(val)
+
+			case "control_usage_page", "control_usage":
+				n, e := parseHexInt(val)
+				if e != nil || n < 0 || n > 0xffff {
+					return nil, time.Time{}, fmt.Errorf("invalid %s: %s", key, val)
+				}
+				if key == "control_usage_page" {
+					cfg.Device.ControlUsagePage = uint16(n)
+				} else {
+					cfg.Device.ControlUsage = uint16(n)
+				}
+
+			case "control_select":
+				v := strings.ToLower(val)
+				if v != controlSelectFirst && v != controlSelectRewrite && v != controlSelectVerify {
+					return nil, time.Time{}, fmt.Errorf("invalid control_select: %s (rewrite|first|verify)", val)
+				}
+				cfg.Device.ControlSelect = v
+
+			case "report_gap_ms":
+				ms, e := parseInt(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid report_gap_ms: %s", val)
+				}
+				cfg.Device.ReportGap = time.Duration(ms) * time.Millisecond
+
+			case "enum_retries":
+				n, e := parseInt(val)
+				if e != nil || n < 0 || n > 10 {
+					return nil, time.Time{}, fmt.Errorf("invalid enum_retries: %s (0..10)", val)
+				}
+				cfg.Device.EnumRetries = n
+
+			case "hit_mode_raw", "hit_poll_raw":
+				b, e := parseByte(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid %s: %w", key, e)
+				}
+				if key == "hit_mode_raw" {
+					cfg.HitModeRaw = rawByte(b)
+				} else {
+					cfg.HitPollRaw = rawByte(b)
+				}
+
+			case "extra_report_hit", "extra_report_default":
+				b, e := parseExtraReport(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid %s: %w", key, e)
+				}
+				if key == "extra_report_hit" {
+					cfg.HitExtra = cfg.HitExtra.add(b)
+				} else {
+					cfg.DefaultExtra = cfg.DefaultExtra.add(b)
+				}
+
+			case "report_id":
+				b, e := parseByte(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid report_id: %w", e)
+				}
+				cfg.Device.ReportID = b
+
+			case "report_id_in_buffer":
+				b, e := parseBool(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid report_id_in_buffer: %s", val)
+				}
+				cfg.Device.ReportIDInBuffer = b
+
+			case "cmd_perf", "cmd_poll", "cmd_motion_sync", "cmd_debounce", "cmd_combined", "cmd_save", "cmd_slot", "cmd_led":
+				b, e := parseByte(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid %s: %s", key, val)
+				}
+				switch key {
+				case "cmd_perf":
+					cfg.Device.Cmd.Perf = b
+				case "cmd_poll":
+					cfg.Device.Cmd.Poll = b
+				case "cmd_motion_sync":
+					cfg.Device.Cmd.MotionSync = b
+				case "cmd_debounce":
+					cfg.Device.Cmd.Debounce = b
+				case "cmd_combined":
+					cfg.Device.Cmd.Combined = b
+				case "cmd_save":
+					cfg.Device.Cmd.Save = b
+				case "cmd_slot":
+					cfg.Device.Cmd.Slot = b
+				case "cmd_led":
+					cfg.Device.Cmd.LED = b
+				}
+
+			case "hit_led", "default_led":
+				c, e := parseLEDColor(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid %s: %w", key, e)
+				}
+				if key == "hit_led" {
+					cfg.HitLED = c
+				} else {
+					cfg.DefaultLED = c
+				}
+
+			case "default_slot":
+				n, e := parseSlot(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid default_slot: %s", val)
+				}
+				cfg.DefaultSlot = n
+
+			case "baseline_mode":
+				m, e := parsePerf(val)
+				if e != nil {
+					return nil, time.Time{}, e
+				}
+				cfg.BaselineMode = m
+
+			case "baseline_poll":
+				n, e := parseInt(val)
+				if e != nil {
+					return nil, time.Time{}, e
+				}
+				if _, e := pollingToYY(PollingRate(n)); e != nil {
+					return nil, time.Time{}, e
+				}
+				cfg.BaselinePoll = PollingRate(n)
+
+			case "confirm_delay_ms":
+				ms, e := parseInt(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid confirm_delay_ms: %s", val)
+				}
+				cfg.ConfirmDelay = time.Duration(ms) * time.Millisecond
+
+			case "log_microseconds", "log_uptime", "log_utc", "log_verbose":
+				b, e := parseBool(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid %s: %s", key, val)
+				}
+				switch key {
+				case "log_microseconds":
+					cfg.Log.Microseconds = b
+				case "log_uptime":
+					cfg.Log.Uptime = b
+				case "log_verbose":
+					cfg.Log.Verbose = b
+				default:
+					cfg.Log.UTC = b
+				}
+
+			case "log_file_daily":
+				b, e := parseBool(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid log_file_daily: %s", val)
+				}
+				cfg.Log.Daily = b
+
+			case "log_file_keep":
+				n, e := parseInt(val)
+				if e != nil || n < 0 {
+					return nil, time.Time{}, fmt.Errorf("invalid log_file_keep: %s", val)
+				}
+				cfg.Log.Keep = n
+
+			case "log_max_total_mb":
+				n, e := parseInt(val)
+				if e != nil || n < 0 {
+					return nil, time.Time{}, fmt.Errorf("invalid log_max_total_mb: %s", val)
+				}
+				cfg.Log.MaxTotalMB = n
+
+			case "log_format":
+				switch v := strings.ToLower(val); v {
+				case logFormatText, logFormatJSON:
+					cfg.Log.Format = v
+				default:
+					return nil, time.Time{}, fmt.Errorf("invalid log_format: %s (text|json)", val)
+				}
+
+			case "probe_device_info", "probe_caps":
+				b, e := parseBool(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid %s: %s", key, val)
+				}
+				if key == "probe_caps" {
+					cfg.ProbeCaps = b
+				} else {
+					cfg.ProbeDeviceInfo = b
+				}
+
+			case "dump_all_hid":
+				b, e := parseBool(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid dump_all_hid: %s", val)
+				}
+				cfg.DumpAllHID = b
+
+			case "dump_all_hid_max":
+				n, e := parseInt(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid dump_all_hid_max: %s", val)
+				}
+				cfg.DumpAllHIDMax = n
+
+			case "apply_on_start":
+				b, e := parseBool(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid apply_on_start: %s", val)
+				}
+				cfg.ApplyOnStart = b
+
+			case "resume_reapply":
+				b, e := parseBool(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid resume_reapply: %s", val)
+				}
+				cfg.ResumeReapply = b
+
+			case "apply_thread":
+				b, e := parseBool(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid apply_thread: %s", val)
+				}
+				cfg.ApplyThread = b
+
+			case "restore_on_exit", "restore_retry":
+				b, e := parseBool(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid %s: %s", key, val)
+				}
+				if key == "restore_on_exit" {
+					cfg.RestoreOnExit = b
+				} else {
+					cfg.RestoreRetry = b
+				}
+
+			case "notify_config_error":
+				b, e := parseBool(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid notify_config_error: %s", val)
+				}
+				cfg.NotifyConfigError = b
+
+			case "self_exclude":
+				b, e := parseBool(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid self_exclude: %s", val)
+				}
+				cfg.SelfExclude = b
+
+			case "trust_device_readback":
+				b, e := parseBool(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid trust_device_readback: %s", val)
+				}
+				cfg.TrustDeviceReadback = b
+
+			case "heartbeat_seconds":
+				sec, e := parseInt(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid heartbeat_seconds: %s", val)
+				}
+				cfg.Heartbeat = time.Duration(sec) * time.Second
+
+			case "no_touch_while_focused":
+				for _, item := range splitWhitelistLine(raw) {
+					name, e := unquoteValue(item)
+					if e != nil {
+						return nil, time.Time{}, fmt.Errorf("invalid no_touch_while_focused: %w", e)
+					}
+					cfg.NoTouchProcs = append(cfg.NoTouchProcs, normalizeProcName(name))
+				}
+
+			case "while_running":
+				r, e := parseAmbientRule(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid while_running: %w", e)
+				}
+				cfg.AmbientRules = append(cfg.AmbientRules, r)
+
+			case "require_running":
+				for _, item := range splitWhitelistLine(raw) {
+					name, e := unquoteValue(item)
+					if e != nil {
+						return nil, time.Time{}, fmt.Errorf("invalid require_running: %w", e)
+					}
+					cfg.RequireRunning = append(cfg.RequireRunning, normalizeProcName(name))
+				}
+
+			case "official_process":
+				cfg.OfficialProcs = nil
+				for _, item := range splitWhitelistLine(raw) {
+					name, e := unquoteValue(item)
+					if e != nil {
+						return nil, time.Time{}, fmt.Errorf("invalid official_process: %w", e)
+					}
+					cfg.OfficialProcs = append(cfg.OfficialProcs, normalizeProcName(name))
+				}
+
+			case "conflict":
+				c, e := parseConflict(val)
+				if e != nil {
+					return nil, time.Time{}, e
+				}
+				cfg.Conflict = c
+
+			case "session":
+				v, e := parseSession(val)
+				if e != nil {
+					return nil, time.Time{}, e
+				}
+				cfg.Session = v
+
+			case "status_file":
+				cfg.StatusFile = val
+
+			case "pipe_name":
+				if val != "" && !strings.HasPrefix(strings.ToLower(val), `\\.\pipe\`) {
+					return nil, time.Time{}, fmt.Errorf("invalid pipe_name: %s (必须以 \\\\.\\pipe\\ 开头)", val)
+				}
+				cfg.PipeName = val
+
+			case "no_activate":
+				b, e := parseBool(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid no_activate: %s", val)
+				}
+				cfg.NoActivate = b
+
+			case "watch_process_start":
+				b, e := parseBool(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid watch_process_start: %s", val)
+				}
+				cfg.WatchProcessStart = b
+
+			case "quiet_hours":
+				q, e := parseQuietHours(val)
+				if e != nil {
+					return nil, time.Time{}, e
+				}
+				cfg.QuietHours = q
+
+			case "gui":
+				b, e := parseBool(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid gui: %s", val)
+				}
+				cfg.GUI = b
+
+			case "learn_mode":
+				b, e := parseBool(val)
+				if e != nil {
+					return nil, time.Time{}, fmt.Errorf("invalid learn_mode: %s", val)
+				}
+				cfg.LearnMode = b
+
+			case "learn_file":
+				cfg.LearnFile = val
+
+			case "history_file":
+				cfg.HistoryFile = val
+
+			case "history_max_lines":
+				n, e := parseInt(val)
+				if e != nil || n <= 0 {
+					return nil, time.Time{}, fmt.Errorf("invalid history_max_lines: %s", val)
+				}
+				cfg.HistoryMaxLines = n
+
 			default:
+				// 白名单条目映射到板载槽位：cs2.exe=slot:2（可带 " @N" 优先级）
+				if s, ok := cutPrefixFold(val, "slot:"); ok {
+					n, e := parseSlot(s)
+					if e != nil {
+						return nil, time.Time{}, fmt.Errorf("invalid slot for %s: %s", key, val)
+					}
+					proc, prio, e := cutPriority(key)
+					if e != nil {
+						return nil, time.Time{}, fmt.Errorf("invalid whitelist entry %s: %w", key, e)
+					}
+					proc = normalizeProcName(proc)
+					cfg.Whitelist = append(cfg.Whitelist, proc)
+					cfg.WhitelistSet[proc] = struct{}{}
+					cfg.setRulePriority(proc, prio)
+					if cfg.RuleSlots == nil {
+						cfg.RuleSlots = map[string]int{}
+					}
+					cfg.RuleSlots[proc] = n
+					continue
+				}
 				// 未知 key 忽略，便于扩展
+				cfg.UnknownKeys = append(cfg.UnknownKeys, key)
 			}
 			continue
 		}
 
-		// 白名单行：只取 basename，转小写
-		proc := strings.ToLower(filepath.Base(line))
-		cfg.Whitelist = append(cfg.Whitelist, proc)
-		cfg.WhitelistSet[proc] = struct{}{}
+		// 白名单行：可逗号分隔多个；每项只取 basename，转小写
+		for _, item := range splitWhitelistLine(line) {
+			proc, prio, e := cutRuleName(item)
+			if e != nil {
+				return nil, time.Time{}, fmt.Errorf("invalid whitelist entry %s: %w", item, e)
+			}
+			proc = normalizeProcName(proc)
+			cfg.Whitelist = append(cfg.Whitelist, proc)
+			cfg.WhitelistSet[proc] = struct{}{}
+			cfg.setRulePriority(proc, prio)
+		}
 	}
 
 	if err := sc.Err(); err != nil {
 		return nil, time.Time{}, err
 	}
+	if hitMS != nil {
+		cfg.HitMode = withMotionSync(cfg.HitMode, *hitMS)
+		if cfg.HitModeBattery != 0 {
+			cfg.HitModeBattery = withMotionSync(cfg.HitModeBattery, *hitMS)
+		}
+	}
+	if defaultMS != nil {
+		cfg.DefaultMode = withMotionSync(cfg.DefaultMode, *defaultMS)
+		if cfg.DefaultModeBattery != 0 {
+			cfg.DefaultModeBattery = withMotionSync(cfg.DefaultModeBattery, *defaultMS)
+		}
+	}
+	cfg.resolveRuleProfiles()
+	if cfg.HotkeyCycle != nil && len(cfg.ManualProfiles) == 0 {
+		return nil, time.Time{}, fmt.Errorf("hotkey_cycle requires manual_profiles")
+	}
 	return cfg, fi.ModTime(), nil
 }
 
+const (
+	cmdlinePrefix = "cmdline:"
+	titlePrefix   = "title:"
+)
+
+// splitWhitelistLine 拆分白名单行（cs2.exe, valorant.exe），返回去掉空白的各项（可能带 " @N" 优先级）。
+// 只用于非 key=value、非 cmdline:/title: 的行，带 '=' 的配置值不会被拆开。
+// 引号内的逗号不拆分，各项保留引号（见 cutRuleName / unquoteValue）。
+func splitWhitelistLine(line string) []string {
+	var out []string
+	add := func(item string) {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	start, inQuote := 0, false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && inQuote && i+1 < len(line):
+			i++
+		case c == '"':
+			inQuote = !inQuote
+		case c == ',' && !inQuote:
+			add(line[start:i])
+			start = i + 1
+		}
+	}
+	add(line[start:])
+	return out
+}
+
+// normalizeProcName 进程名的统一形式：去空白、只取 basename、转小写。
+// 白名单条目与前台进程名都经过它，所以 C:\Games\CS2.EXE、" cs2.exe " 都与前台 cs2.exe 相同。
+// \ 和 / 都按路径分隔符处理，不依赖运行平台的 filepath 规则。
+func normalizeProcName(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndexAny(s, `\/`); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.ToLower(s)
+}
+
+// setRulePriority 记录规则优先级（0 为默认，不存）
+func (c *Config) setRulePriority(rule string, prio int) {
+	if prio == 0 {
+		return
+	}
+	if c.RulePriority == nil {
+		c.RulePriority = map[string]int{}
+	}
+	c.RulePriority[rule] = prio
+}
+
+// cutPrefixFold 不区分大小写地去掉前缀
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
+
 func parseInt(s string) (int, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
@@ -191,11 +1427,63 @@ func parseInt(s string) (int, error) {
 	return n, nil
 }
 
+// parseHexInt 接受十进制或 0x 前缀的十六进制，可带负号。
+// 计数/间隔类仍用严格十进制的 parseInt。
+func parseHexInt(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	neg := false
+	if strings.HasPrefix(s, "-") {
+		neg = true
+		s = s[1:]
+	}
+	if s == "" {
+		return 0, fmt.Errorf("empty int")
+	}
+
+	var u uint64
+	var err error
+	if rest, ok := cutPrefixFold(s, "0x"); ok {
+		u, err = strconv.ParseUint(rest, 16, 63)
+	} else {
+		u, err = strconv.ParseUint(s, 10, 63)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("not int: %s", s)
+	}
+	if neg {
+		return -int64(u), nil
+	}
+	return int64(u), nil
+}
+
+// parseByte 解析单字节值（0..255），用于 report id、命令字节等
+func parseByte(s string) (byte, error) {
+	n, err := parseHexInt(s)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > 0xff {
+		return 0, fmt.Errorf("byte out of range: %s", strings.TrimSpace(s))
+	}
+	return byte(n), nil
+}
+
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "true", "1", "yes", "on":
+		return true, nil
+	case "false", "0", "no", "off":
+		return false, nil
+	default:
+		return false, fmt.Errorf("not bool: %s", s)
+	}
+}
+
 func parsePerf(s string) (PerfMode, error) {
 	switch strings.ToLower(strings.TrimSpace(s)) {
-	case "standard_ms_off":
+	case "standard_ms_off", "standard":
 		return PerfStandardMSOff, nil
-	case "competitive_ms_off":
+	case "competitive_ms_off", "competitive":
 		return PerfCompetitiveMSOff, nil
 	case "competitive_ms_on":
 		return PerfCompetitiveMSOn, nil
@@ -206,6 +1494,37 @@ func parsePerf(s string) (PerfMode, error) {
 	}
 }
 
+// perfMotionSync 性能模式里的 Motion Sync 开关
+func perfMotionSync(p PerfMode) bool {
+	return p == PerfCompetitiveMSOn || p == PerfStandardMSOn
+}
+
+// perfBase 去掉 Motion Sync 后的基础模式（competitive/standard 即 *_ms_off）
+func perfBase(p PerfMode) PerfMode {
+	switch p {
+	case PerfCompetitiveMSOn:
+		return PerfCompetitiveMSOff
+	case PerfStandardMSOn:
+		return PerfStandardMSOff
+	}
+	return p
+}
+
+// withMotionSync 保留基础模式，替换 Motion Sync 开关
+func withMotionSync(p PerfMode, on bool) PerfMode {
+	base := perfBase(p)
+	if !on {
+		return base
+	}
+	switch base {
+	case PerfCompetitiveMSOff:
+		return PerfCompetitiveMSOn
+	case PerfStandardMSOff:
+		return PerfStandardMSOn
+	}
+	return p
+}
+
 func perfName(p PerfMode) string {
 	switch p {
 	case PerfStandardMSOff:
@@ -221,6 +1540,229 @@ func perfName(p PerfMode) string {
 	}
 }
 
+// parseProfile 解析 "mode:poll"，例如 competitive_ms_off:4000
+func parseProfile(s string) (Profile, error) {
+	i := strings.IndexByte(s, ':')
+	if i <= 0 {
+		return Profile{}, fmt.Errorf("invalid profile (want mode:poll): %s", s)
+	}
+	m, err := parsePerf(s[:i])
+	if err != nil {
+		return Profile{}, err
+	}
+	n, err := parseInt(s[i+1:])
+	if err != nil {
+		return Profile{}, err
+	}
+	if _, err := pollingToYY(PollingRate(n)); err != nil {
+		return Profile{}, err
+	}
+	return Profile{Perf: m, Poll: PollingRate(n)}, nil
+}
+
+// parseProfileList 解析逗号分隔的 profile 列表
+func parseProfileList(s string) ([]Profile, error) {
+	var out []Profile
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		p, err := parseProfile(part)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("empty profile list")
+	}
+	return out, nil
+}
+
+// hasBatteryVariants 是否配置了任何电池专用项
+func (c *Config) hasBatteryVariants() bool {
+	return c.HitModeBattery != 0 || c.HitPollBattery != 0 ||
+		c.DefaultModeBattery != 0 || c.DefaultPollBattery != 0 ||
+		c.MaxPollBattery != 0
+}
+
+// effectiveProfile 按是否命中白名单、是否电池供电得出目标配置
+func (c *Config) effectiveProfile(hit bool, battery bool) Profile {
+	p := Profile{Perf: c.DefaultMode, Poll: c.DefaultPoll, Debounce: c.DefaultDebounce, LED: c.DefaultLED, Extra: c.DefaultExtra}
+	bm, bp := c.DefaultModeBattery, c.DefaultPollBattery
+	if hit {
+		p = Profile{Perf: c.HitMode, Poll: c.HitPoll, Debounce: c.HitDebounce, PerfByte: c.HitModeRaw, PollByte: c.HitPollRaw,
+			LED: c.HitLED, Extra: c.HitExtra}
+		bm, bp = c.HitModeBattery, c.HitPollBattery
+	}
+	if battery {
+		if bm != 0 {
+			p.Perf = bm
+		}
+		if bp != 0 {
+			p.Poll = bp
+		}
+	}
+	return p
+}
+
+// slotFor 命中规则（或未命中时 default_slot）对应的板载槽位，0 = 按参数逐项下发
+func (c *Config) slotFor(rule string, hit bool) int {
+	if hit {
+		return c.RuleSlots[rule]
+	}
+	return c.DefaultSlot
+}
+
+// parseSlot 板载槽位号 1..maxProfileSlot
+func parseSlot(s string) (int, error) {
+	n, err := parseInt(s)
+	if err != nil {
+		return 0, err
+	}
+	if n < 1 || n > maxProfileSlot {
+		return 0, fmt.Errorf("slot out of range (1..%d): %d", maxProfileSlot, n)
+	}
+	return n, nil
+}
+
+// pollCap 当前电源状态下的回报率上限（0 = 不限）
+func (c *Config) pollCap(battery bool) PollingRate {
+	if battery && c.MaxPollBattery != 0 {
+		return c.MaxPollBattery
+	}
+	return c.MaxPoll
+}
+
+// intervalFor 当前前台命中与否对应的检查间隔；命中的规则写了 interval= 时用它
+func (c *Config) intervalFor(rule string, hit bool) time.Duration {
+	if o := c.RuleOverrides[rule]; hit && o.Interval > 0 {
+		return o.Interval
+	}
+	d := c.DefaultInterval
+	if hit {
+		d = c.HitInterval
+	}
+	if d <= 0 {
+		return c.Interval
+	}
+	return d
+}
+
+// baselineProfile -reset 使用的基线配置
+func (c *Config) baselineProfile() Profile {
+	p := Profile{Perf: c.DefaultMode, Poll: c.DefaultPoll}
+	if c.BaselineMode != 0 {
+		p.Perf = c.BaselineMode
+	}
+	if c.BaselinePoll != 0 {
+		p.Poll = c.BaselinePoll
+	}
+	return p
+}
+
+func profileName(p Profile) string {
+	if p.Slot != 0 {
+		return fmt.Sprintf("板载槽位 %d", p.Slot)
+	}
+	perf, poll := perfName(p.Perf), fmt.Sprintf("%dHz", p.Poll)
+	if p.PerfByte.Set() {
+		perf = "mode=" + p.PerfByte.String() + "(原始)"
+	}
+	if p.PollByte.Set() {
+		poll = "poll=" + p.PollByte.String() + "(原始)"
+	}
+	s := fmt.Sprintf("%s + %s", perf, poll)
+	if p.Debounce != 0 {
+		s += fmt.Sprintf(" + 消抖%dms", p.Debounce)
+	}
+	if p.LED.Set() {
+		s += " + 指示灯" + p.LED.String()
+	}
+	if n := len(p.Extra.Reports()); n > 0 {
+		s += fmt.Sprintf(" + 额外报告%d条", n)
+	}
+	return s
+}
+
+// RegisterHotKey 修饰键
+const (
+	MOD_ALT     = 0x0001
+	MOD_CONTROL = 0x0002
+	MOD_SHIFT   = 0x0004
+	MOD_WIN     = 0x0008
+)
+
+// parseHotkey 解析 "ctrl+alt+f9" 这类写法（不区分大小写）
+func parseHotkey(s string) (Hotkey, error) {
+	hk := Hotkey{Spec: strings.ToLower(strings.TrimSpace(s))}
+	parts := strings.Split(hk.Spec, "+")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		last := i == len(parts)-1
+		switch {
+		case !last && (part == "ctrl" || part == "control"):
+			hk.Mods |= MOD_CONTROL
+		case !last && part == "alt":
+			hk.Mods |= MOD_ALT
+		case !last && part == "shift":
+			hk.Mods |= MOD_SHIFT
+		case !last && part == "win":
+			hk.Mods |= MOD_WIN
+		case last:
+			vk, ok := vkFromName(part)
+			if !ok {
+				return Hotkey{}, fmt.Errorf("unknown key: %s", part)
+			}
+			hk.VK = vk
+		default:
+			return Hotkey{}, fmt.Errorf("unknown modifier: %s", part)
+		}
+	}
+	if hk.VK == 0 {
+		return Hotkey{}, fmt.Errorf("missing key: %s", s)
+	}
+	return hk, nil
+}
+
+// vkFromName 键名 -> Windows 虚拟键码
+func vkFromName(name string) (uint32, bool) {
+	if len(name) == 1 {
+		ch := name[0]
+		switch {
+		case ch >= 'a' && ch <= 'z':
+			return uint32(ch - 'a' + 'A'), true
+		case ch >= '0' && ch <= '9':
+			return uint32(ch), true
+		}
+	}
+	if len(name) >= 2 && name[0] == 'f' {
+		if n, err := parseInt(name[1:]); err == nil && n >= 1 && n <= 24 {
+			return uint32(0x70 + n - 1), true // VK_F1 = 0x70
+		}
+	}
+	switch name {
+	case "pause":
+		return 0x13, true
+	case "pageup":
+		return 0x21, true
+	case "pagedown":
+		return 0x22, true
+	case "end":
+		return 0x23, true
+	case "home":
+		return 0x24, true
+	case "insert":
+		return 0x2d, true
+	case "delete":
+		return 0x2e, true
+	case "scrolllock":
+		return 0x91, true
+	}
+	return 0, false
+}
+
 // 回报率映射：按抓包分段标注（1000/2000/4000）
 // 1000->0x02, 2000->0x03, 4000->0x04
 func pollingToYY(p PollingRate) (byte, error) {
@@ -235,3 +1777,30 @@ func pollingToYY(p PollingRate) (byte, error) {
 		return 0, fmt.Errorf("unsupported polling rate: %d", p)
 	}
 }
+
+// debounceSteps 设备接受的消抖档位（毫秒）；报告里直接发送毫秒数（推测协议，cmd=0x0c）
+var debounceSteps = []int{1, 2, 4, 8, 12, 16}
+
+// debounceToByte 消抖毫秒数 -> 报告值；不在档位内的报错
+func debounceToByte(ms int) (byte, error) {
+	for _, s := range debounceSteps {
+		if s == ms {
+			return byte(ms), nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported debounce: %dms (supported: %v)", ms, debounceSteps)
+}
+
+// yyToPolling pollingToYY 的反向映射（读回设备当前回报率用）
+func yyToPolling(b byte) (PollingRate, error) {
+	switch b {
+	case 0x02:
+		return Poll1000, nil
+	case 0x03:
+		return Poll2000, nil
+	case 0x04:
+		return Poll4000, nil
+	default:
+		return 0, fmt.Errorf("unknown polling rate value: 0x%02x", b)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// writeTestConfig 把 text 写成临时配置文件并返回路径
+func writeTestConfig(t *testing.T, text string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "vaxee.conf")
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// loadTestConfig 按 text 加载配置，失败时终止测试
+func loadTestConfig(t *testing.T, text string) *Config {
+	t.Helper()
+	cfg, _, err := loadConfig(writeTestConfig(t, text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// 每行一个、逗号分隔、按程序配置与槽位映射混写时，只拆白名单部分
+func TestLoadConfigWhitelistStyles(t *testing.T) {
+	cases := []struct {
+		name, text string
+		want       []string
+	}{
+		{"每行一个", "cs2.exe\nvalorant.exe\n", []string{"cs2.exe", "valorant.exe"}},
+		{"逗号分隔", "cs2.exe, valorant.exe ,r6.exe\n", []string{"cs2.exe", "valorant.exe", "r6.exe"}},
+		{"混写", "cs2.exe\nvalorant.exe, r6.exe\ndota2.exe\n", []string{"cs2.exe", "valorant.exe", "r6.exe", "dota2.exe"}},
+		{"按程序配置不拆", "cs2.exe: mode=competitive_ms_on, poll=2000\napex.exe, r6.exe\n", []string{"cs2.exe", "apex.exe", "r6.exe"}},
+		{"槽位映射不拆", "cs2.exe=slot:2\nvalorant.exe, r6.exe\n", []string{"cs2.exe", "valorant.exe", "r6.exe"}},
+		{"key=value 不进白名单", "hit_poll=2000\ncs2.exe\n", []string{"cs2.exe"}},
+	}
+	for _, c := range cases {
+		cfg := loadTestConfig(t, c.text)
+		if !slices.Equal(cfg.Whitelist, c.want) {
+			t.Errorf("%s: Whitelist = %q, want %q", c.name, cfg.Whitelist, c.want)
+		}
+	}
+}
+
+// 配置文件被删除：保留内存中的配置且不重载；文件恢复后重新加载
+func TestReloadConfigDeleted(t *testing.T) {
+	path := writeTestConfig(t, "cs2.exe\n")
+	cfg, modTime, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := cfg
+	ok := func(*Config) error { return nil }
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if reloadConfigIfChanged(path, &cfg, &modTime, ok) {
+		t.Fatal("reloaded a deleted config")
+	}
+	if cfg != old || !modTime.IsZero() {
+		t.Fatalf("after delete: cfg replaced=%v, modTime=%v", cfg != old, modTime)
+	}
+
+	// 恢复的文件 mtime 比原来的还旧（如从备份拷回）也要重新加载
+	if err := os.WriteFile(path, []byte("valorant.exe\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-24 * time.Hour)
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatal(err)
+	}
+	if !reloadConfigIfChanged(path, &cfg, &modTime, ok) {
+		t.Fatal("restored config was not reloaded")
+	}
+	if !slices.Equal(cfg.Whitelist, []string{"valorant.exe"}) {
+		t.Errorf("Whitelist = %q after reload", cfg.Whitelist)
+	}
+}
+
+func TestIntervalForRule(t *testing.T) {
+	cfg := loadTestConfig(t, "interval_seconds=60\nhit_interval_seconds=10\ncs2.exe: interval=5\nvalorant.exe\n")
+	cases := []struct {
+		rule string
+		hit  bool
+		want time.Duration
+	}{
+		{"cs2.exe", true, 5 * time.Second},
+		{"valorant.exe", true, 10 * time.Second},
+		{"", false, 60 * time.Second},
+	}
+	for _, c := range cases {
+		if got := cfg.intervalFor(c.rule, c.hit); got != c.want {
+			t.Errorf("intervalFor(%q, %v) = %s, want %s", c.rule, c.hit, got, c.want)
+		}
+	}
+	if _, _, err := loadConfig(writeTestConfig(t, "cs2.exe: interval=0\n")); err == nil {
+		t.Error("interval=0 accepted")
+	}
+}
+
+// 按程序配置行算白名单行（与 loadConfig 一致），AddWhitelist 不重复追加
+func TestConfDocProfileLine(t *testing.T) {
+	path := writeTestConfig(t, "hit_poll=2000\ncs2.exe: mode=competitive_ms_on, poll=4000\n")
+	doc, err := readConfDoc(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k, ok := lineKey("cs2.exe: poll=4000"); ok {
+		t.Errorf("profile line parsed as key %q", k)
+	}
+	if !isWhitelistLine("cs2.exe: poll=4000") {
+		t.Error("profile line is not a whitelist line")
+	}
+	if doc.AddWhitelist("CS2.exe") {
+		t.Error("AddWhitelist duplicated a process from a profile line")
+	}
+	if !doc.AddWhitelist("valorant.exe") {
+		t.Fatal("AddWhitelist(valorant.exe) = false")
+	}
+	if want := "hit_poll=2000\ncs2.exe: mode=competitive_ms_on, poll=4000\nvalorant.exe\n"; string(doc.Bytes()) != want {
+		t.Errorf("doc = %q, want %q", doc.Bytes(), want)
+	}
+}
+
+// official_process 与 require_running 一样：全路径、引号、大小写都归一成进程名
+func TestLoadConfigOfficialProcess(t *testing.T) {
+	cfg := loadTestConfig(t, `official_process=C:\Program Files\VAXEE\VAXEE.exe, "Mouse, Setting.exe"`+"\n")
+	want := []string{"vaxee.exe", "mouse, setting.exe"}
+	if !slices.Equal(cfg.OfficialProcs, want) {
+		t.Errorf("OfficialProcs = %q, want %q", cfg.OfficialProcs, want)
+	}
+}
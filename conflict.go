@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// ==================== 官方软件冲突检测 ====================
+// 官方软件也会写同样的设置，两边同时运行会互相覆盖（表现为“设置总被改回去”）。
+
+// 冲突处理方式
+const (
+	conflictWarn = "warn" // 只打警告，继续运行
+	conflictExit = "exit" // 启动时检测到就拒绝开始自动切换
+)
+
+// defaultOfficialProcs 官方软件常见的进程名（小写）；安装版本不同时用 official_process 覆盖
+var defaultOfficialProcs = []string{"vaxee.exe", "vaxee mouse setting.exe"}
+
+// findOfficialProcess 返回正在运行的第一个官方软件进程名；枚举失败时视为未运行
+func findOfficialProcess(cfg *Config, procs *ProcSnapshot) (string, bool) {
+	if len(cfg.OfficialProcs) == 0 {
+		return "", false
+	}
+	running, err := procs.get()
+	if err != nil {
+		return "", false
+	}
+	for _, name := range cfg.OfficialProcs {
+		if _, ok := running[name]; ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// conflictBanner 醒目的多行警告
+func conflictBanner(name string) {
+	log.Printf("[CONFLICT] ==================================================")
+	log.Printf("[CONFLICT] 检测到官方软件正在运行：%s", name)
+	log.Printf("[CONFLICT] 两者会互相覆盖性能模式/回报率，请退出官方软件（或其后台托盘）。")
+	log.Printf("[CONFLICT] ==================================================")
+}
+
+// conflictRecheck 官方软件的复查间隔（不必每轮都看）
+const conflictRecheck = 30 * time.Second
+
+// ConflictWatcher 周期性复查：只在官方软件“新出现”时警告一次，退出后再出现会再警告
+type ConflictWatcher struct {
+	running string
+	checked time.Time // 上次复查的时间
+}
+
+// check 距上次复查满 conflictRecheck 时复查一次（第一次总会检查）；返回官方软件是否在运行
+func (w *ConflictWatcher) check(cfg *Config, procs *ProcSnapshot, now time.Time) bool {
+	if !w.checked.IsZero() && now.Sub(w.checked) < conflictRecheck {
+		return w.running != ""
+	}
+	w.checked = now
+	name, ok := findOfficialProcess(cfg, procs)
+	switch {
+	case ok && name != w.running:
+		conflictBanner(name)
+	case !ok && w.running != "":
+		log.Printf("[CONFLICT] 官方软件已退出：%s", w.running)
+	}
+	w.running = name
+	return ok
+}
+
+// parseConflict 解析 conflict=warn|exit
+func parseConflict(s string) (string, error) {
+	switch v := strings.ToLower(strings.TrimSpace(s)); v {
+	case conflictWarn, conflictExit:
+		return v, nil
+	}
+	return "", fmt.Errorf("invalid conflict: %s (warn|exit)", s)
+}
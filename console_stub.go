@@ -0,0 +1,9 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+func releaseConsoleFocus() error {
+	return errors.New("console focus is only supported on Windows")
+}
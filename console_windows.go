@@ -0,0 +1,28 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+var (
+	k32CW = syscall.NewLazyDLL("kernel32.dll")
+
+	procGetConsoleWindow = k32CW.NewProc("GetConsoleWindow")
+)
+
+const SW_SHOWMINNOACTIVE = 7
+
+// releaseConsoleFocus 把控制台窗口最小化且不激活，焦点回到启动前的前台窗口。
+// 控制台窗口由系统在进程启动时创建并激活，程序只能事后让出焦点。
+// 以 Windows Terminal 为默认终端时 GetConsoleWindow 拿到的是伪窗口，可能不起作用。
+func releaseConsoleFocus() error {
+	hwnd, _, _ := procGetConsoleWindow.Call()
+	if hwnd == 0 {
+		return errors.New("no console window")
+	}
+	procShowWindow.Call(hwnd, SW_SHOWMINNOACTIVE)
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+// Context 决策所需的外部状态（由调用方采集，Decide 本身不做任何 I/O）
+type Context struct {
+	Battery  bool // 当前是否电池供电
+	Wireless bool // 鼠标当前经 2.4G 接收器连接（见 LinkMode）
+
+	// Cmdline 按需取前台进程命令行：只有配置了 cmdline 规则且进程名未命中时才会调用。
+	// 为 nil 时 cmdline 规则一律不命中。
+	Cmdline func() (string, error)
+
+	// Memo 可选：上一次匹配结果的缓存（见 MatchMemo），nil 时每次都完整匹配
+	Memo *MatchMemo
+
+	// Running 当前运行中的进程（已归一化），用于 while_running；nil 时这类规则一律不生效
+	Running map[string]struct{}
+
+	// Serial / ContainerID 当前控制的设备，用于 device_profile；为空时这类配置一律不生效
+	Serial      string
+	ContainerID string
+}
+
+// Decision 一次决策的结果
+type Decision struct {
+	Perf     PerfMode
+	Poll     PollingRate
+	Debounce int
+	PerfByte RawByte // 原始字节，见 Profile
+	PollByte RawByte
+	Slot     int // 板载配置槽位（非 0 时只切槽位）
+	LED      LEDColor
+	Extra    ExtraReports
+	Hit      bool
+	Rule     string // 命中的规则（未命中为空）
+	Ambient  string // 按 while_running 选出配置时为对应的进程名（此时不看前台）
+
+	// Clamped 回报率被 max_poll/max_poll_battery/max_poll_wireless 压低过；RawPoll 为压低前的值
+	Clamped bool
+	RawPoll PollingRate
+}
+
+// Profile 决策对应的目标配置
+func (d Decision) Profile() Profile {
+	return Profile{Perf: d.Perf, Poll: d.Poll, Debounce: d.Debounce, PerfByte: d.PerfByte, PollByte: d.PollByte, Slot: d.Slot, LED: d.LED, Extra: d.Extra}
+}
+
+// Decide 纯策略：由配置、前台进程名（已归一化为小写 basename）和外部状态算出目标配置。
+// 没有副作用；tickOnce 与将来的解释/测试功能都走这一条路径。
+// title 为前台窗口标题，用于 title: 规则（为空时这类规则一律不命中）。
+func Decide(cfg *Config, proc, title string, flags Context) Decision {
+	// while_running：后台进程决定的配置优先于前台规则
+	if r, ok := cfg.ambientRule(flags.Running); ok {
+		p, clamped := cfg.capPoll(Profile{Perf: r.Profile.Perf, Poll: r.Profile.Poll}, flags.Battery, flags.Wireless)
+		return Decision{Perf: p.Perf, Poll: p.Poll, Ambient: r.Proc, Clamped: clamped, RawPoll: r.Profile.Poll}
+	}
+
+	rule, hit := flags.Memo.match(cfg, proc, title, flags.Cmdline)
+
+	// 映射到板载槽位的规则：只切槽位，参数由槽位自身决定
+	if slot := cfg.slotFor(rule, hit); slot != 0 {
+		return Decision{Slot: slot, Hit: hit, Rule: rule}
+	}
+
+	p := cfg.profileFor(rule, hit, flags.Battery)
+	if dp, ok := cfg.deviceProfile(flags.Serial, flags.ContainerID); hit && ok {
+		p.Perf, p.PerfByte, p.Poll, p.PollByte = dp.Perf, 0, dp.Poll, 0
+	}
+	if wp := cfg.wirelessPoll(hit); flags.Wireless && wp != 0 {
+		p.Poll, p.PollByte = wp, 0
+	}
+	raw := p.Poll
+
+	// 策略层：回报率上限
+	p, clamped := cfg.capPoll(p, flags.Battery, flags.Wireless)
+	return Decision{Perf: p.Perf, Poll: p.Poll, Debounce: p.Debounce, PerfByte: p.PerfByte, PollByte: p.PollByte,
+		LED: p.LED, Extra: p.Extra, Hit: hit, Rule: rule, Clamped: clamped, RawPoll: raw}
+}
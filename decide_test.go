@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+func TestDecide(t *testing.T) {
+	const base = "hit_mode=competitive_ms_off\nhit_poll=4000\ndefault_mode=standard_ms_off\ndefault_poll=1000\n"
+	cases := []struct {
+		name  string
+		text  string
+		proc  string
+		title string
+		flags Context
+		perf  PerfMode
+		poll  PollingRate
+		hit   bool
+		rule  string
+	}{
+		{name: "未命中", text: base + "cs2.exe\n", proc: "explorer.exe",
+			perf: PerfStandardMSOff, poll: Poll1000},
+		{name: "精确命中", text: base + "cs2.exe\n", proc: "cs2.exe",
+			perf: PerfCompetitiveMSOff, poll: Poll4000, hit: true, rule: "cs2.exe"},
+		{name: "按程序配置", text: base + "cs2.exe: poll=2000\n", proc: "cs2.exe",
+			perf: PerfCompetitiveMSOff, poll: Poll2000, hit: true, rule: "cs2.exe"},
+		{name: "优先级高者胜", text: base + "match_mode=prefix\ncs2\ncs2.exe: poll=2000\ncs @5\n", proc: "cs2.exe",
+			perf: PerfCompetitiveMSOff, poll: Poll4000, hit: true, rule: "cs"},
+		{name: "同优先级更具体者胜", text: base + "match_mode=prefix\ncs\ncs2.exe: poll=2000\n", proc: "cs2.exe",
+			perf: PerfCompetitiveMSOff, poll: Poll2000, hit: true, rule: "cs2.exe"},
+		{name: "标题规则", text: base + "title:\"Game: Edition\"\n", proc: "game.exe", title: "Game: Edition",
+			perf: PerfCompetitiveMSOff, poll: Poll4000, hit: true, rule: "title:game: edition"},
+		{name: "电池替代值", text: base + "hit_poll_battery=1000\ncs2.exe\n", proc: "cs2.exe", flags: Context{Battery: true},
+			perf: PerfCompetitiveMSOff, poll: Poll1000, hit: true, rule: "cs2.exe"},
+		{name: "回报率上限", text: base + "max_poll=2000\ncs2.exe\n", proc: "cs2.exe",
+			perf: PerfCompetitiveMSOff, poll: Poll2000, hit: true, rule: "cs2.exe"},
+		{name: "按序列号的设备配置", text: base + "device_profile=serial:ABC => competitive_ms_on,2000\ncs2.exe\n", proc: "cs2.exe",
+			flags: Context{Serial: "abc"},
+			perf:  PerfCompetitiveMSOn, poll: Poll2000, hit: true, rule: "cs2.exe"},
+		{name: "按容器 ID 的设备配置不匹配", text: base + "device_profile=container:{C1} => competitive_ms_on,2000\ncs2.exe\n", proc: "cs2.exe",
+			flags: Context{ContainerID: "{c2}"},
+			perf:  PerfCompetitiveMSOff, poll: Poll4000, hit: true, rule: "cs2.exe"},
+		{name: "设备配置不影响未命中", text: base + "device_profile=serial:ABC => competitive_ms_on,2000\ncs2.exe\n", proc: "explorer.exe",
+			flags: Context{Serial: "ABC"},
+			perf:  PerfStandardMSOff, poll: Poll1000},
+		{name: "while_running 优先于前台", text: base + "while_running=obs64.exe => standard_ms_on,2000\ncs2.exe\n", proc: "cs2.exe",
+			flags: Context{Running: map[string]struct{}{"obs64.exe": {}}},
+			perf:  PerfStandardMSOn, poll: Poll2000},
+	}
+	for _, c := range cases {
+		cfg := loadTestConfig(t, c.text)
+		d := Decide(cfg, c.proc, c.title, c.flags)
+		if d.Perf != c.perf || d.Poll != c.poll || d.Hit != c.hit || d.Rule != c.rule {
+			t.Errorf("%s: Decide = {Perf:%v Poll:%d Hit:%v Rule:%q}, want {Perf:%v Poll:%d Hit:%v Rule:%q}",
+				c.name, d.Perf, d.Poll, d.Hit, d.Rule, c.perf, c.poll, c.hit, c.rule)
+		}
+	}
+}
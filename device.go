@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+type VaxeeDeviceInfo struct {
+	Path         string
+	VID          uint16
+	PID          uint16
+	Manufacturer string
+	Product      string
+	Serial       string // HidD_GetSerialNumberString；设备不提供时为空
+	ContainerID  string // 物理设备的容器 ID（{...}，小写）；系统不提供时为空
+	UsagePage    uint16
+	Usage        uint16
+	FeatureLen   uint16 // 0 = caps 取不到，使用前由 featureLenFor 探测
+	OutputLen    uint16
+	CapsErr      string // queryCaps 失败的原因（成功为空）
+	Model        string // 设备信息命令读出的型号（不支持时为空）
+	Firmware     string
+}
+
+// HID Usage：Generic Desktop / Keyboard，以及 Consumer Control 页
+const (
+	usagePageGenericDesktop = 0x01
+	usageKeyboard           = 0x06
+	usagePageConsumer       = 0x0c
+)
+
+// isKbdPath Windows 给键盘集合的路径加 \kbd 后缀
+func isKbdPath(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), `\kbd`)
+}
+
+// isKeyboardOrConsumer 是否键盘/多媒体控制集合
+func isKeyboardOrConsumer(d VaxeeDeviceInfo) bool {
+	if d.UsagePage == usagePageGenericDesktop && d.Usage == usageKeyboard {
+		return true
+	}
+	if d.UsagePage == usagePageConsumer {
+		return true
+	}
+	return isKbdPath(d.Path)
+}
+
+// modelMatches 型号过滤（不区分大小写子串）；未配置时总是匹配
+func modelMatches(d VaxeeDeviceInfo, opts DeviceOptions) bool {
+	if opts.Model == "" {
+		return true
+	}
+	return strings.Contains(strings.ToLower(d.Model), opts.Model)
+}
+
+// serialMatches serial 过滤：未配置时都匹配；配置了则序列号必须相同（不区分大小写），空序列号不匹配
+func serialMatches(d VaxeeDeviceInfo, opts DeviceOptions) bool {
+	return opts.Serial == "" || (d.Serial != "" && strings.EqualFold(d.Serial, opts.Serial))
+}
+
+// normalizeContainerID 容器 ID 的统一写法：去空白、转小写、补上花括号
+func normalizeContainerID(s string) string {
+	s = strings.ToLower(strings.Trim(strings.TrimSpace(s), "{}"))
+	if s == "" {
+		return ""
+	}
+	return "{" + s + "}"
+}
+
+// containerMatches container_id 过滤：未配置时都匹配；配置了则容器 ID 必须相同，读不到容器 ID 的不匹配
+func containerMatches(d VaxeeDeviceInfo, opts DeviceOptions) bool {
+	return opts.ContainerID == "" || d.ContainerID == opts.ContainerID
+}
+
+// pathInterfaceKey 从 HID 路径取出“同一个 USB 接口”的部分：去掉硬件 ID 里的 &colNN 与实例 ID 末尾的集合序号，
+// 同一接口拆出来的各个顶级集合得到相同的键，例如 \\?\hid#vid_3057&pid_0001&mi_02&col01#9&1a2b3c4d&0&0000#{...}
+// 得到 vid_3057&pid_0001&mi_02#9&1a2b3c4d&0。不是这种格式的路径原样（小写）返回，自成一组
+func pathInterfaceKey(path string) string {
+	lp := strings.ToLower(path)
+	parts := strings.Split(lp, "#")
+	if len(parts) < 3 {
+		return lp
+	}
+	hw, inst := parts[1], parts[2]
+	if i := strings.Index(hw, "&col"); i >= 0 {
+		hw = hw[:i]
+	}
+	if i := strings.LastIndexByte(inst, '&'); i >= 0 {
+		inst = inst[:i]
+	}
+	return hw + "#" + inst
+}
+
+// physicalKey 同一物理设备的集合得到相同的键：优先用容器 ID（跨 USB 接口也一致），
+// 读不到时按路径只能归到同一接口
+func physicalKey(d VaxeeDeviceInfo) string {
+	if d.ContainerID != "" {
+		return d.ContainerID
+	}
+	return pathInterfaceKey(d.Path)
+}
+
+// physicalGroups 按枚举顺序给每个集合标上所属物理设备的序号（从 1 开始），并返回物理设备个数
+func physicalGroups(ds []VaxeeDeviceInfo) ([]int, int) {
+	ids := map[string]int{}
+	out := make([]int, len(ds))
+	for i, d := range ds {
+		k := physicalKey(d)
+		if _, ok := ids[k]; !ok {
+			ids[k] = len(ids) + 1
+		}
+		out[i] = ids[k]
+	}
+	return out, len(ids)
+}
+
+// ignoredPath 命中 ignore_path 的子串，返回命中的规则
+func ignoredPath(path string, opts DeviceOptions) (string, bool) {
+	lp := strings.ToLower(path)
+	for _, ig := range opts.IgnorePaths {
+		if strings.Contains(lp, ig) {
+			return ig, true
+		}
+	}
+	return "", false
+}
+
+// indexedDevice -device N：按枚举顺序（与启动日志里的 #N 一致）取设备
+func indexedDevice(ds []VaxeeDeviceInfo, opts DeviceOptions) (VaxeeDeviceInfo, error) {
+	if opts.Index < 1 || opts.Index > len(ds) {
+		return VaxeeDeviceInfo{}, fmt.Errorf("-device %d out of range: found %d VAXEE HID device(s)", opts.Index, len(ds))
+	}
+	d := ds[opts.Index-1]
+	if opts.SafeMode && isKeyboardOrConsumer(d) {
+		return VaxeeDeviceInfo{}, fmt.Errorf("-device %d is a keyboard/consumer collection, refused by safe_mode", opts.Index)
+	}
+	if _, ok := ignoredPath(d.Path, opts); ok {
+		return VaxeeDeviceInfo{}, fmt.Errorf("-device %d is excluded by ignore_path", opts.Index)
+	}
+	return d, nil
+}
+
+// pinnedControl 在候选集合里找 control_path 指定的那个
+func pinnedControl(ds []VaxeeDeviceInfo, opts DeviceOptions) (VaxeeDeviceInfo, bool) {
+	if opts.ControlPath == "" {
+		return VaxeeDeviceInfo{}, false
+	}
+	for _, d := range ds {
+		if strings.Contains(strings.ToLower(d.Path), opts.ControlPath) {
+			return d, true
+		}
+	}
+	return VaxeeDeviceInfo{}, false
+}
+
+// pinnedUsage control_usage_page/control_usage 指定的集合（caps 取不到的集合不参与）
+func pinnedUsage(ds []VaxeeDeviceInfo, opts DeviceOptions) (VaxeeDeviceInfo, bool) {
+	if opts.ControlUsagePage == 0 {
+		return VaxeeDeviceInfo{}, false
+	}
+	for _, d := range ds {
+		if d.CapsErr == "" && d.UsagePage == opts.ControlUsagePage &&
+			(opts.ControlUsage == 0 || d.Usage == opts.ControlUsage) {
+			return d, true
+		}
+	}
+	return VaxeeDeviceInfo{}, false
+}
+
+// 多个集合都接受 ReportID 时的选择方式（control_select）
+const (
+	controlSelectFirst   = "first"   // 第一个接受 GetFeature 的非键盘集合
+	controlSelectRewrite = "rewrite" // 读出当前值原样写回，写入成功的集合（默认，不改变设备状态）
+	controlSelectVerify  = "verify"  // 临时改写后读回，确认设置真正生效的集合
+)
+
+// filterControlCandidates 按选项剔除不允许探测/下发的集合，并记录日志
+func filterControlCandidates(ds []VaxeeDeviceInfo, opts DeviceOptions) []VaxeeDeviceInfo {
+	if !opts.SafeMode && len(opts.IgnorePaths) == 0 && opts.Serial == "" && opts.ContainerID == "" {
+		return ds
+	}
+	out := make([]VaxeeDeviceInfo, 0, len(ds))
+	for _, d := range ds {
+		if !serialMatches(d, opts) || !containerMatches(d, opts) {
+			continue
+		}
+		if ig, ok := ignoredPath(d.Path, opts); ok {
+			log.Printf("[DEV] ignore_path(%s) 跳过：%s", ig, d.Path)
+			continue
+		}
+		if opts.SafeMode && isKeyboardOrConsumer(d) {
+			log.Printf("[SAFE] 排除键盘/多媒体集合：UsagePage=0x%04x Usage=0x%04x Path=%s", d.UsagePage, d.Usage, d.Path)
+			continue
+		}
+		out = append(out, d)
+	}
+	return out
+}
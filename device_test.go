@@ -0,0 +1,76 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+const hidGUID = "#{4d1e55b2-f16f-11cf-88cb-001111000030}"
+
+func TestPathInterfaceKey(t *testing.T) {
+	cases := []struct {
+		path, want string
+	}{
+		{`\\?\HID#VID_3057&PID_0001&MI_02&Col01#9&1a2b3c4d&0&0000` + hidGUID, "vid_3057&pid_0001&mi_02#9&1a2b3c4d&0"},
+		{`\\?\hid#vid_3057&pid_0001&mi_02&col03#9&1a2b3c4d&0&0002` + hidGUID, "vid_3057&pid_0001&mi_02#9&1a2b3c4d&0"},
+		{`\\?\hid#vid_3057&pid_0001&mi_00#8&2b3c4d5e&0&0000` + hidGUID, "vid_3057&pid_0001&mi_00#8&2b3c4d5e&0"},
+		{`\\?\hid#vid_3057&pid_0001`, `\\?\hid#vid_3057&pid_0001`},
+		{"not-a-hid-path", "not-a-hid-path"},
+	}
+	for _, c := range cases {
+		if got := pathInterfaceKey(c.path); got != c.want {
+			t.Errorf("pathInterfaceKey(%q) = %q, want %q", c.path, got, c.want)
+		}
+	}
+}
+
+func TestPhysicalGroups(t *testing.T) {
+	path := func(hw, inst string) string { return `\\?\hid#` + hw + "#" + inst + hidGUID }
+	cases := []struct {
+		name  string
+		ds    []VaxeeDeviceInfo
+		want  []int
+		count int
+	}{
+		{
+			name: "一只鼠标的多个集合",
+			ds: []VaxeeDeviceInfo{
+				{Path: path("vid_3057&pid_0001&mi_02&col01", "9&1a2b3c4d&0&0000")},
+				{Path: path("vid_3057&pid_0001&mi_02&col02", "9&1a2b3c4d&0&0001")},
+				{Path: path("vid_3057&pid_0001&mi_02&col03", "9&1a2b3c4d&0&0002")},
+			},
+			want: []int{1, 1, 1}, count: 1,
+		},
+		{
+			name: "两只同 VID/PID 的鼠标",
+			ds: []VaxeeDeviceInfo{
+				{Path: path("vid_3057&pid_0001&mi_02&col01", "9&1a2b3c4d&0&0000")},
+				{Path: path("vid_3057&pid_0001&mi_02&col01", "9&5e6f7a8b&0&0000")},
+				{Path: path("vid_3057&pid_0001&mi_02&col02", "9&1a2b3c4d&0&0001")},
+			},
+			want: []int{1, 2, 1}, count: 2,
+		},
+		{
+			name: "没有集合后缀的路径",
+			ds: []VaxeeDeviceInfo{
+				{Path: path("vid_3057&pid_0001&mi_00", "8&2b3c4d5e&0&0000")},
+				{Path: path("vid_3057&pid_0001&mi_02&col01", "9&1a2b3c4d&0&0000")},
+			},
+			want: []int{1, 2}, count: 2,
+		},
+		{
+			name: "容器 ID 跨接口归为一组",
+			ds: []VaxeeDeviceInfo{
+				{Path: path("vid_3057&pid_0001&mi_00", "8&2b3c4d5e&0&0000"), ContainerID: "{c1}"},
+				{Path: path("vid_3057&pid_0001&mi_02&col01", "9&1a2b3c4d&0&0000"), ContainerID: "{c1}"},
+			},
+			want: []int{1, 1}, count: 1,
+		},
+	}
+	for _, c := range cases {
+		got, n := physicalGroups(c.ds)
+		if !slices.Equal(got, c.want) || n != c.count {
+			t.Errorf("%s: physicalGroups = %v, %d; want %v, %d", c.name, got, n, c.want, c.count)
+		}
+	}
+}
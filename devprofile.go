@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ==================== 按设备的配置（device_profile） ====================
+// 两只同型号鼠标命中时要用不同的模式/回报率：按序列号或容器 ID 指定，
+//   device_profile=serial:ABC123 => competitive_ms_on,4000
+//   device_profile=container:{...} => competitive_ms_off,2000
+// 命中白名单且当前控制的设备匹配时，取代 hit_*（及按程序配置）的模式与回报率；未命中时不影响。
+
+type DeviceProfile struct {
+	Serial      string // 非空时按序列号匹配（不区分大小写）
+	ContainerID string // 非空时按容器 ID 匹配（normalizeContainerID 后）
+	Profile     Profile
+}
+
+// parseDeviceProfile 解析 "serial:XXX => mode,poll" 或 "container:{...} => mode,poll"
+func parseDeviceProfile(s string) (DeviceProfile, error) {
+	id, spec, ok := strings.Cut(s, "=>")
+	if !ok {
+		return DeviceProfile{}, fmt.Errorf("want serial:XXX|container:{...} => mode,poll: %s", s)
+	}
+	var r DeviceProfile
+	kind, val, _ := strings.Cut(strings.TrimSpace(id), ":")
+	val = strings.TrimSpace(val)
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "serial":
+		r.Serial = val
+	case "container":
+		r.ContainerID = normalizeContainerID(val)
+	}
+	if r.Serial == "" && r.ContainerID == "" {
+		return DeviceProfile{}, fmt.Errorf("want serial:XXX|container:{...} => mode,poll: %s", s)
+	}
+	p, err := parseProfile(strings.Replace(strings.TrimSpace(spec), ",", ":", 1))
+	if err != nil {
+		return DeviceProfile{}, err
+	}
+	r.Profile = p
+	return r, nil
+}
+
+func (r DeviceProfile) String() string {
+	id := "serial:" + r.Serial
+	if r.ContainerID != "" {
+		id = "container:" + r.ContainerID
+	}
+	return fmt.Sprintf("%s => %s,%d", id, perfName(r.Profile.Perf), r.Profile.Poll)
+}
+
+// deviceProfile 第一条与当前设备匹配的 device_profile；设备的序列号/容器 ID 读不到时不匹配
+func (c *Config) deviceProfile(serial, container string) (Profile, bool) {
+	for _, r := range c.DeviceProfiles {
+		if r.Serial != "" && serial != "" && strings.EqualFold(r.Serial, serial) ||
+			r.ContainerID != "" && r.ContainerID == container {
+			return r.Profile, true
+		}
+	}
+	return Profile{}, false
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// ==================== 专用设备线程（apply_thread） ====================
+// 所有设备 I/O（枚举、探测、读回、下发）都在同一个锁定的 OS 线程上串行执行：
+// 该线程单独设好优先级/EcoQoS，不受 Go 调度把 goroutine 换到哪个线程的影响，
+// 同一时刻也只有一个操作在访问设备。apply_thread=false 时在调用方的 goroutine 上直接执行。
+//
+// 某个操作卡住（调用方 ctx 已结束仍没返回，或排队的调用方等到 ctx 结束都没轮到）时
+// 换一个新线程接手后续操作，旧线程做完手头这个操作后退出，不会让之后的每次下发都跟着超时。
+
+type DeviceThread struct {
+	mu       sync.Mutex
+	cur      *deviceWorker // nil = 尚未启动
+	disabled bool          // apply_thread=false
+}
+
+type deviceWorker struct {
+	jobs chan func()
+	quit chan struct{} // 关闭 = 已被替换，做完当前操作后退出
+}
+
+var deviceThread DeviceThread
+
+// setEnabled 按 apply_thread 开关设备线程（启动与重载时调用）
+func (t *DeviceThread) setEnabled(on bool) {
+	t.mu.Lock()
+	t.disabled = !on
+	t.mu.Unlock()
+}
+
+// worker 返回当前的设备线程（按需启动）；关闭时返回 nil
+func (t *DeviceThread) worker() *deviceWorker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.disabled {
+		return nil
+	}
+	if t.cur == nil {
+		t.cur = startDeviceWorker()
+	}
+	return t.cur
+}
+
+func startDeviceWorker() *deviceWorker {
+	w := &deviceWorker{jobs: make(chan func()), quit: make(chan struct{})}
+	go func() {
+		// 不解锁：goroutine 退出时线程随之销毁
+		runtime.LockOSThread()
+		setDeviceThreadPriority()
+		for {
+			select {
+			case job := <-w.jobs:
+				job()
+			case <-w.quit:
+				return
+			}
+		}
+	}()
+	return w
+}
+
+// retire 当前线程卡在某个操作上：之后的操作交给新线程
+func (t *DeviceThread) retire(w *deviceWorker) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cur == w {
+		t.cur = nil
+		close(w.quit)
+		debugf("设备线程上的操作超时未返回，后续操作改用新线程")
+	}
+}
+
+// run 在设备线程上执行 fn 并等待它返回。前一个操作还没结束时排队（线程被替换后改排到新线程）；
+// ctx 先结束则放弃等待、替换掉卡住的线程并返回 ctx.Err()（fn 已开始时由它自己在 ctx 取消后尽快返回）。
+// fn 里不能再调用 run（会等自己）。
+func (t *DeviceThread) run(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	job := func() { done <- fn() }
+	var w *deviceWorker
+	for queued := false; !queued; {
+		if w = t.worker(); w == nil {
+			return fn()
+		}
+		select {
+		case w.jobs <- job:
+			queued = true
+		case <-w.quit:
+		case <-ctx.Done():
+			// 排到 ctx 结束都没轮到：前一个操作卡住了
+			t.retire(w)
+			return ctx.Err()
+		}
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		t.retire(w)
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// 前一个操作卡住时，排队等到超时的调用方要把线程换掉，之后的操作不再跟着超时
+func TestDeviceThreadRetiresWhenQueuedJobTimesOut(t *testing.T) {
+	var th DeviceThread
+	release := make(chan struct{})
+	defer close(release)
+
+	started := make(chan struct{})
+	go th.run(context.Background(), func() error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := th.run(ctx, func() error { return nil }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("queued run = %v, want DeadlineExceeded", err)
+	}
+
+	ctx2, cancel2 := context.WithTimeout(context.Background(), time.Second)
+	defer cancel2()
+	ran := false
+	if err := th.run(ctx2, func() error { ran = true; return nil }); err != nil || !ran {
+		t.Fatalf("run after retire = %v (ran=%v), want nil on a new thread", err, ran)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// ==================== 诊断用隐藏参数 ====================
+// 不在 -h 中列出，只用于排查问题（例如某些 Windows 版本上枚举出乱码路径）。
+
+// SP_DEVICE_INTERFACE_DETAIL_DATA_W 布局覆盖（0 = 使用计算值）
+var (
+	detailCbSizeOverride uint
+	detailOffsetOverride uint
+)
+
+// hiddenFlags 不在用法说明里显示的参数
+var hiddenFlags = map[string]bool{}
+
+func registerDiagFlags() {
+	flag.UintVar(&detailCbSizeOverride, "detail-cbsize", 0, "覆盖 SP_DEVICE_INTERFACE_DETAIL_DATA_W.cbSize（诊断用）")
+	flag.UintVar(&detailOffsetOverride, "detail-offset", 0, "覆盖 DevicePath 在 DetailData 中的偏移（诊断用）")
+	hiddenFlags["detail-cbsize"] = true
+	hiddenFlags["detail-offset"] = true
+
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "Usage of %s:\n", flag.CommandLine.Name())
+		visible := flag.NewFlagSet(flag.CommandLine.Name(), flag.ContinueOnError)
+		visible.SetOutput(out)
+		flag.VisitAll(func(f *flag.Flag) {
+			if !hiddenFlags[f.Name] {
+				visible.Var(f.Value, f.Name, f.Usage)
+			}
+		})
+		visible.PrintDefaults()
+	}
+}
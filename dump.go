@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// formatConfig 把解析后的 Config 输出为规范的 .conf 文本（所有默认值都显式写出）
+func formatConfig(cfg *Config) string {
+	var b strings.Builder
+	kv := func(k string, v any) {
+		fmt.Fprintf(&b, "%s=%v\n", k, v)
+	}
+
+	b.WriteString("# VAXEE AutoSwitch 生效配置（-dump-config 生成）\n")
+	fmt.Fprintf(&b, "# source: %s\n", cfg.ConfigPath)
+	kv("interval_seconds", int(cfg.Interval.Seconds()))
+	if cfg.HitInterval > 0 {
+		kv("hit_interval_seconds", int(cfg.HitInterval.Seconds()))
+	}
+	if cfg.DefaultInterval > 0 {
+		kv("default_interval_seconds", int(cfg.DefaultInterval.Seconds()))
+	}
+	kv("interval_jitter_ms", cfg.Jitter.Milliseconds())
+	kv("idle_backoff_ticks", cfg.IdleTicks)
+	kv("idle_max_interval_seconds", int(cfg.IdleMaxInterval.Seconds()))
+	kv("match_mode", cfg.MatchMode)
+	kv("hit_mode", perfName(cfg.HitMode))
+	kv("hit_poll", int(cfg.HitPoll))
+	kv("default_mode", perfName(cfg.DefaultMode))
+	kv("default_poll", int(cfg.DefaultPoll))
+	if cfg.HitDebounce != 0 {
+		kv("hit_debounce", cfg.HitDebounce)
+	}
+	if cfg.DefaultDebounce != 0 {
+		kv("default_debounce", cfg.DefaultDebounce)
+	}
+	if cfg.HitModeRaw.Set() {
+		kv("hit_mode_raw", cfg.HitModeRaw)
+	}
+	if cfg.HitPollRaw.Set() {
+		kv("hit_poll_raw", cfg.HitPollRaw)
+	}
+	for _, r := range cfg.HitExtra.Reports() {
+		kv("extra_report_hit", formatExtraReport(r))
+	}
+	for _, r := range cfg.DefaultExtra.Reports() {
+		kv("extra_report_default", formatExtraReport(r))
+	}
+
+	if cfg.HitModeBattery != 0 {
+		kv("hit_mode_battery", perfName(cfg.HitModeBattery))
+	}
+	if cfg.HitPollBattery != 0 {
+		kv("hit_poll_battery", int(cfg.HitPollBattery))
+	}
+	if cfg.DefaultModeBattery != 0 {
+		kv("default_mode_battery", perfName(cfg.DefaultModeBattery))
+	}
+	if cfg.DefaultPollBattery != 0 {
+		kv("default_poll_battery", int(cfg.DefaultPollBattery))
+	}
+
+	if cfg.MaxPoll != 0 {
+		kv("max_poll", int(cfg.MaxPoll))
+	}
+	if cfg.MaxPollBattery != 0 {
+		kv("max_poll_battery", int(cfg.MaxPollBattery))
+	}
+	if cfg.HitPollWireless != 0 {
+		kv("hit_poll_wireless", int(cfg.HitPollWireless))
+	}
+	if cfg.DefaultPollWireless != 0 {
+		kv("default_poll_wireless", int(cfg.DefaultPollWireless))
+	}
+	if cfg.MaxPollWireless != 0 {
+		kv("max_poll_wireless", int(cfg.MaxPollWireless))
+	}
+	if len(cfg.Device.WirelessPIDs) > 0 {
+		kv("wireless_pid", formatPIDs(cfg.Device.WirelessPIDs))
+	}
+
+	kv("motion_sync_report", cfg.Device.MotionSyncReport)
+	kv("combined_report", cfg.Device.CombinedReport)
+	kv("persist", cfg.Device.Persist)
+	kv("apply_timeout_ms", cfg.ApplyTimeout.Milliseconds())
+	kv("apply_thread", cfg.ApplyThread)
+	kv("safe_mode", cfg.Device.SafeMode)
+	if cfg.Device.Model != "" {
+		kv("device_model", cfg.Device.Model)
+	}
+	if cfg.Device.Serial != "" {
+		kv("serial", cfg.Device.Serial)
+	}
+	if cfg.Device.ContainerID != "" {
+		kv("container_id", cfg.Device.ContainerID)
+	}
+	for _, r := range cfg.DeviceProfiles {
+		kv("device_profile", r)
+	}
+	for _, ig := range cfg.Device.IgnorePaths {
+		kv("ignore_path", ig)
+	}
+	if cfg.Device.ControlPath != "" {
+		kv("control_path", cfg.Device.ControlPath)
+	}
+	if cfg.Device.ControlUsagePage != 0 {
+		kv("control_usage_page", fmt.Sprintf("0x%04x", cfg.Device.ControlUsagePage))
+		kv("control_usage", fmt.Sprintf("0x%04x", cfg.Device.ControlUsage))
+	}
+	kv("control_select", cfg.Device.ControlSelect)
+	kv("dump_all_hid", cfg.DumpAllHID)
+	kv("probe_device_info", cfg.ProbeDeviceInfo)
+	kv("probe_caps", cfg.ProbeCaps)
+	kv("dump_all_hid_max", cfg.DumpAllHIDMax)
+	kv("report_gap_ms", cfg.Device.ReportGap.Milliseconds())
+	kv("enum_retries", cfg.Device.EnumRetries)
+	kv("report_id", fmt.Sprintf("0x%02x", cfg.Device.ReportID))
+	kv("report_id_in_buffer", cfg.Device.ReportIDInBuffer)
+	for _, f := range cfg.Device.FeatureLens {
+		kv("feature_length", f)
+	}
+	kv("cmd_perf", fmt.Sprintf("0x%02x", cfg.Device.Cmd.Perf))
+	kv("cmd_poll", fmt.Sprintf("0x%02x", cfg.Device.Cmd.Poll))
+	kv("cmd_motion_sync", fmt.Sprintf("0x%02x", cfg.Device.Cmd.MotionSync))
+	kv("cmd_debounce", fmt.Sprintf("0x%02x", cfg.Device.Cmd.Debounce))
+	kv("cmd_combined", fmt.Sprintf("0x%02x", cfg.Device.Cmd.Combined))
+	kv("cmd_save", fmt.Sprintf("0x%02x", cfg.Device.Cmd.Save))
+	kv("cmd_slot", fmt.Sprintf("0x%02x", cfg.Device.Cmd.Slot))
+	kv("cmd_led", fmt.Sprintf("0x%02x", cfg.Device.Cmd.LED))
+	if cfg.HitLED.Set() {
+		kv("hit_led", cfg.HitLED)
+	}
+	if cfg.DefaultLED.Set() {
+		kv("default_led", cfg.DefaultLED)
+	}
+	if cfg.DefaultSlot != 0 {
+		kv("default_slot", cfg.DefaultSlot)
+	}
+	if cfg.BaselineMode != 0 {
+		kv("baseline_mode", perfName(cfg.BaselineMode))
+	}
+	if cfg.BaselinePoll != 0 {
+		kv("baseline_poll", int(cfg.BaselinePoll))
+	}
+	kv("restore_on_exit", cfg.RestoreOnExit)
+	kv("restore_retry", cfg.RestoreRetry)
+	kv("apply_on_start", cfg.ApplyOnStart)
+	kv("resume_reapply", cfg.ResumeReapply)
+	kv("trust_device_readback", cfg.TrustDeviceReadback)
+	kv("notify_config_error", cfg.NotifyConfigError)
+	kv("self_exclude", cfg.SelfExclude)
+	kv("watch_process_start", cfg.WatchProcessStart)
+	kv("confirm_delay_ms", cfg.ConfirmDelay.Milliseconds())
+	if len(cfg.NoTouchProcs) > 0 {
+		kv("no_touch_while_focused", strings.Join(cfg.NoTouchProcs, ", "))
+	}
+	if len(cfg.RequireRunning) > 0 {
+		kv("require_running", strings.Join(cfg.RequireRunning, ", "))
+	}
+	for _, r := range cfg.AmbientRules {
+		kv("while_running", r)
+	}
+	kv("official_process", strings.Join(cfg.OfficialProcs, ", "))
+	kv("conflict", cfg.Conflict)
+	kv("session", cfg.Session)
+
+	if len(cfg.ManualProfiles) > 0 {
+		ps := make([]string, len(cfg.ManualProfiles))
+		for i, p := range cfg.ManualProfiles {
+			ps[i] = fmt.Sprintf("%s:%d", perfName(p.Perf), p.Poll)
+		}
+		kv("manual_profiles", strings.Join(ps, ", "))
+	}
+	if cfg.HotkeyCycle != nil {
+		kv("hotkey_cycle", cfg.HotkeyCycle.Spec)
+	}
+	if cfg.HotkeyRelease != nil {
+		kv("hotkey_release", cfg.HotkeyRelease.Spec)
+	}
+
+	kv("log_microseconds", cfg.Log.Microseconds)
+	kv("log_uptime", cfg.Log.Uptime)
+	kv("log_utc", cfg.Log.UTC)
+	kv("log_verbose", cfg.Log.Verbose)
+	kv("log_format", cfg.Log.Format)
+	kv("log_file_daily", cfg.Log.Daily)
+	kv("log_file_keep", cfg.Log.Keep)
+	kv("log_max_total_mb", cfg.Log.MaxTotalMB)
+	kv("heartbeat_seconds", int(cfg.Heartbeat.Seconds()))
+
+	if cfg.StatusFile != "" {
+		kv("status_file", cfg.StatusFile)
+	}
+	kv("gui", cfg.GUI)
+	kv("no_activate", cfg.NoActivate)
+	kv("quiet_hours", cfg.QuietHours)
+	if cfg.PipeName != "" {
+		kv("pipe_name", cfg.PipeName)
+	}
+	kv("learn_mode", cfg.LearnMode)
+	if cfg.LearnFile != "" {
+		kv("learn_file", cfg.LearnFile)
+	}
+
+	if cfg.HistoryFile != "" {
+		kv("history_file", cfg.HistoryFile)
+	}
+	kv("history_max_lines", cfg.HistoryMaxLines)
+
+	b.WriteString("\n# whitelist\n")
+	withPrio := func(rule string) string {
+		s := quoteValue(rule)
+		if r, ok := cutPrefixFold(rule, cmdlinePrefix); ok {
+			s = cmdlinePrefix + quoteValue(r)
+		} else if r, ok := cutPrefixFold(rule, titlePrefix); ok {
+			s = titlePrefix + quoteValue(r)
+		}
+		if p := cfg.RulePriority[rule]; p != 0 {
+			return fmt.Sprintf("%s @%d", s, p)
+		}
+		return s
+	}
+	for _, w := range cfg.Whitelist {
+		if slot := cfg.RuleSlots[w]; slot != 0 {
+			fmt.Fprintf(&b, "%s=slot:%d\n", withPrio(w), slot)
+			continue
+		}
+		if o, ok := cfg.RuleOverrides[w]; ok {
+			fmt.Fprintf(&b, "%s: %s\n", withPrio(w), o)
+			continue
+		}
+		b.WriteString(withPrio(w) + "\n")
+	}
+	for _, r := range cfg.CmdlineRules {
+		b.WriteString(withPrio(cmdlinePrefix+r) + "\n")
+	}
+	for _, r := range cfg.TitleRules {
+		b.WriteString(withPrio(titlePrefix+r) + "\n")
+	}
+	return b.String()
+}
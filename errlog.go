@@ -0,0 +1,71 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// ==================== [ERR] 日志限流 ====================
+// 同一个错误只在第一次出现时记录，之后的重复只计数；
+// 错误消失（一轮无错误）、换成另一个错误（之前连续重复过）或持续超过 errorSummaryInterval 时输出一条汇总。
+// 按错误内容分别计数，A/B 交替出现时也不会刷屏（交替时没有连续重复，只在消失或定期汇总时输出）。
+
+const errorSummaryInterval = 5 * time.Minute
+
+type errorRecord struct {
+	since      time.Time // 本次计数的起点
+	suppressed int       // 已省略的次数
+}
+
+type ErrorLog struct {
+	seen map[string]*errorRecord
+	cur  string // 上一轮的错误
+	run  int    // cur 连续重复的轮数
+}
+
+// handle 处理一轮的错误；errStr 为空表示本轮正常
+func (l *ErrorLog) handle(errStr string) {
+	now := time.Now()
+	if errStr == l.cur {
+		l.run++
+	} else {
+		// A 连续重复了若干轮后换成 B：先把 A 的汇总打出来，不等到错误全部消失
+		if r := l.seen[l.cur]; r != nil && l.run > 0 && r.suppressed > 0 {
+			logSuppressed(l.cur, r, now)
+			r.since, r.suppressed = now, 0
+		}
+		l.cur, l.run = errStr, 0
+	}
+	if errStr == "" {
+		l.flush(now)
+		return
+	}
+	if r, ok := l.seen[errStr]; ok {
+		r.suppressed++
+		// 长期持续的错误定期汇总一次，看日志时能知道它还在发生
+		if now.Sub(r.since) >= errorSummaryInterval {
+			logSuppressed(errStr, r, now)
+			r.since, r.suppressed = now, 0
+		}
+		return
+	}
+	if l.seen == nil {
+		l.seen = make(map[string]*errorRecord)
+	}
+	l.seen[errStr] = &errorRecord{since: now}
+	log.Printf("[ERR] %s", errStr)
+}
+
+// flush 错误已消失：输出各错误的省略汇总并清空记录
+func (l *ErrorLog) flush(now time.Time) {
+	for msg, r := range l.seen {
+		if r.suppressed > 0 {
+			logSuppressed(msg, r, now)
+		}
+	}
+	clear(l.seen)
+}
+
+func logSuppressed(msg string, r *errorRecord, now time.Time) {
+	log.Printf("[ERR] （%s 内省略了 %d 次相同错误）%s", now.Sub(r.since).Round(time.Second), r.suppressed, msg)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+// captureLog 运行 fn 期间把标准 log 的输出收集起来，按行返回
+func captureLog(t *testing.T, fn func()) []string {
+	t.Helper()
+	var buf bytes.Buffer
+	w, flags := log.Writer(), log.Flags()
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(w)
+		log.SetFlags(flags)
+	}()
+	fn()
+	return strings.Split(strings.TrimSpace(buf.String()), "\n")
+}
+
+func TestErrorLogSummary(t *testing.T) {
+	cases := []struct {
+		name   string
+		errs   []string
+		want   int    // 输出行数
+		substr string // 最后一行应包含
+	}{
+		{"A 连续重复后换成 B", []string{"A", "A", "A", "B"}, 3, "B"},
+		{"A/B 交替不刷屏", []string{"A", "B", "A", "B", "A", "B"}, 2, "B"},
+		{"错误消失时汇总", []string{"A", "A", ""}, 2, "省略了 1 次"},
+	}
+	for _, c := range cases {
+		var l ErrorLog
+		lines := captureLog(t, func() {
+			for _, e := range c.errs {
+				l.handle(e)
+			}
+		})
+		if len(lines) != c.want || !strings.Contains(lines[len(lines)-1], c.substr) {
+			t.Errorf("%s: got %q", c.name, lines)
+		}
+	}
+	// A 的汇总在 B 之前
+	var l ErrorLog
+	lines := captureLog(t, func() {
+		for _, e := range []string{"A", "A", "A", "B"} {
+			l.handle(e)
+		}
+	})
+	if len(lines) != 3 || !strings.Contains(lines[1], "省略了 2 次相同错误）A") {
+		t.Errorf("summary of A missing before B: %q", lines)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ==================== 额外报告（extra_report_hit / extra_report_default） ====================
+// 高级：新固件加了参数、程序还没支持时，直接在配置里写整条 Feature 报告的字节，
+// 例如 extra_report_hit=0e:a5:0e:02:01:03，切换到该配置时在标准报告之后原样发送（不足部分补 0）。
+// 载入时解析并校验字节；长度与 ReportID 在拿到设备的 caps 后校验（checkExtraReports）。
+
+// maxExtraReportLen 额外报告的长度上限（常见 Feature 报告长度为 64）
+const maxExtraReportLen = 64
+
+// ExtraReports 解析好的额外报告，按“长度 + 字节”依次拼成字符串保存，
+// 让 Profile 仍可直接用 == 比较
+type ExtraReports string
+
+// parseExtraReport 解析冒号分隔的十六进制字节 "0e:a5:0e:02:01:03"
+func parseExtraReport(s string) ([]byte, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) < 2 || len(parts) > maxExtraReportLen {
+		return nil, fmt.Errorf("want 2..%d colon-separated bytes: %s", maxExtraReportLen, s)
+	}
+	out := make([]byte, len(parts))
+	for i, p := range parts {
+		n, err := strconv.ParseUint(strings.TrimSpace(p), 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid byte %q (want 00..ff)", p)
+		}
+		out[i] = byte(n)
+	}
+	return out, nil
+}
+
+func (e ExtraReports) add(b []byte) ExtraReports {
+	return e + ExtraReports(append([]byte{byte(len(b))}, b...))
+}
+
+// Reports 拆回每条报告的字节
+func (e ExtraReports) Reports() [][]byte {
+	var out [][]byte
+	for s := string(e); len(s) > 0; {
+		n := int(s[0])
+		out = append(out, []byte(s[1:1+n]))
+		s = s[1+n:]
+	}
+	return out
+}
+
+func (e ExtraReports) String() string {
+	var parts []string
+	for _, r := range e.Reports() {
+		parts = append(parts, formatExtraReport(r))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// formatExtraReport 与配置文件相同的写法
+func formatExtraReport(b []byte) string {
+	parts := make([]string, len(b))
+	for i, x := range b {
+		parts[i] = fmt.Sprintf("%02x", x)
+	}
+	return strings.Join(parts, ":")
+}
+
+// checkExtraReports 按控制通道的 Feature 报告长度与 ReportID 校验所有额外报告
+func checkExtraReports(cfg *Config, flen int, reportID byte) error {
+	check := func(key string, e ExtraReports) error {
+		for _, r := range e.Reports() {
+			if flen > 0 && len(r) > flen {
+				return fmt.Errorf("%s=%s: %d bytes exceeds feature report length %d", key, formatExtraReport(r), len(r), flen)
+			}
+			if r[0] != reportID {
+				return fmt.Errorf("%s=%s: first byte must be ReportID 0x%02x", key, formatExtraReport(r), reportID)
+			}
+		}
+		return nil
+	}
+	if err := check("extra_report_hit", cfg.HitExtra); err != nil {
+		return err
+	}
+	return check("extra_report_default", cfg.DefaultExtra)
+}
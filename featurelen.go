@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ==================== feature_length 覆盖 ====================
+// caps 取不到或给错 FeatureReportByteLength 的设备，可以在配置里直接固定长度：
+//   feature_length=65                 所有设备
+//   feature_length=0x3057=65          该 VID 的设备
+//   feature_length=0x3057:0x0001=65   该 VID/PID 的设备
+// 多条时越具体的越优先；覆盖值先于 caps 和探测（featureLenFor）使用。
+
+const (
+	minFeatureLen = 6 // [reportID, 0xa5, cmd, op, len, val]
+	maxFeatureLen = 1024
+)
+
+type FeatureLenOverride struct {
+	VID uint16 // 0 = 任意
+	PID uint16 // 0 = 任意（只在 VID 非 0 时使用）
+	Len int
+}
+
+func parseFeatureLenOverride(s string) (FeatureLenOverride, error) {
+	var o FeatureLenOverride
+	lenPart := s
+	if i := strings.LastIndexByte(s, '='); i >= 0 {
+		ids := strings.TrimSpace(s[:i])
+		lenPart = s[i+1:]
+		vid, pid, hasPID := strings.Cut(ids, ":")
+		n, err := parseHexInt(vid)
+		if err != nil || n <= 0 || n > 0xffff {
+			return o, fmt.Errorf("bad vid %q", vid)
+		}
+		o.VID = uint16(n)
+		if hasPID {
+			n, err = parseHexInt(pid)
+			if err != nil || n <= 0 || n > 0xffff {
+				return o, fmt.Errorf("bad pid %q", pid)
+			}
+			o.PID = uint16(n)
+		}
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(lenPart))
+	if err != nil || n < minFeatureLen || n > maxFeatureLen {
+		return o, fmt.Errorf("length must be %d..%d", minFeatureLen, maxFeatureLen)
+	}
+	o.Len = n
+	return o, nil
+}
+
+func (o FeatureLenOverride) String() string {
+	switch {
+	case o.VID == 0:
+		return strconv.Itoa(o.Len)
+	case o.PID == 0:
+		return fmt.Sprintf("0x%04x=%d", o.VID, o.Len)
+	default:
+		return fmt.Sprintf("0x%04x:0x%04x=%d", o.VID, o.PID, o.Len)
+	}
+}
+
+// featureLenOverride 返回该 VID/PID 的覆盖长度（0 = 未配置）；VID/PID 都匹配 > 只匹配 VID > 通配
+func (o DeviceOptions) featureLenOverride(vid, pid uint16) int {
+	best, rank := 0, -1
+	for _, f := range o.FeatureLens {
+		r := -1
+		switch {
+		case f.VID == 0:
+			r = 0
+		case f.VID == vid && f.PID == 0:
+			r = 1
+		case f.VID == vid && f.PID == pid:
+			r = 2
+		}
+		if r > rank {
+			best, rank = f.Len, r
+		}
+	}
+	return best
+}
+
+// 超出 OutputReportByteLength 被拒绝的覆盖只提示一次（每轮查找设备都会经过这里）
+var (
+	featureLenWarnMu sync.Mutex
+	featureLenWarned = map[string]bool{}
+)
+
+// withFeatureLenOverrides 把配置的 feature_length 写进枚举结果，后续探测、读回、下发都按它收发。
+// OutputReportByteLength 已知且小于覆盖值时认为配置有误，保留 caps 的值。
+func withFeatureLenOverrides(ds []VaxeeDeviceInfo, opts DeviceOptions) []VaxeeDeviceInfo {
+	if len(opts.FeatureLens) == 0 {
+		return ds
+	}
+	for i := range ds {
+		d := &ds[i]
+		n := opts.featureLenOverride(d.VID, d.PID)
+		if n == 0 || n == int(d.FeatureLen) {
+			continue
+		}
+		if d.OutputLen > 0 && n > int(d.OutputLen) {
+			featureLenWarnMu.Lock()
+			if !featureLenWarned[d.Path] {
+				featureLenWarned[d.Path] = true
+				log.Printf("[DEV] feature_length=%d 超过该集合的 OutputReportByteLength=%d，忽略：%s", n, d.OutputLen, d.Path)
+			}
+			featureLenWarnMu.Unlock()
+			continue
+		}
+		debugf("feature_length=%d 覆盖 caps 长度 %d：%s", n, d.FeatureLen, d.Path)
+		d.FeatureLen = uint16(n)
+	}
+	return ds
+}
@@ -5,5 +5,19 @@ package main
 import "errors"
 
 func ForegroundProcessName() (string, error) {
+	if info, ok := simulatedForegroundInfo(); ok {
+		return info.Name, nil
+	}
 	return "", errors.New("ForegroundProcessName is only supported on Windows")
 }
+
+func ForegroundProcessCmdline() (string, error) {
+	return "", errors.New("ForegroundProcessCmdline is only supported on Windows")
+}
+
+func ForegroundWindowInfo() (ForegroundInfo, error) {
+	if info, ok := simulatedForegroundInfo(); ok {
+		return info, nil
+	}
+	return ForegroundInfo{}, errors.New("ForegroundWindowInfo is only supported on Windows")
+}
@@ -3,8 +3,7 @@
 package main
 
 import (
-	"path/filepath"
-	"strings"
+	"sync"
 	"syscall"
 	"unsafe"
 )
@@ -12,30 +11,128 @@ import (
 var (
 	user32FG = syscall.NewLazyDLL("user32.dll")
 	k32FG    = syscall.NewLazyDLL("kernel32.dll")
+	ntdllFG  = syscall.NewLazyDLL("ntdll.dll")
 
 	procGetForegroundWindowFG      = user32FG.NewProc("GetForegroundWindow")
 	procGetWindowThreadProcessIdFG = user32FG.NewProc("GetWindowThreadProcessId")
+	procEnumChildWindowsFG         = user32FG.NewProc("EnumChildWindows")
+	procGetWindowTextWFG           = user32FG.NewProc("GetWindowTextW")
 	procOpenProcessFG              = k32FG.NewProc("OpenProcess")
 	procCloseHandleFG              = k32FG.NewProc("CloseHandle")
 	procQueryFullProcessImageNameW = k32FG.NewProc("QueryFullProcessImageNameW")
+	procNtQueryInformationProcess  = ntdllFG.NewProc("NtQueryInformationProcess")
 )
 
 const PROCESS_QUERY_LIMITED_INFORMATION = 0x1000
 
-func ForegroundProcessName() (string, error) {
+// NtQueryInformationProcess: ProcessCommandLineInformation（Win 8.1+），
+// 只需要 PROCESS_QUERY_LIMITED_INFORMATION，不用读 PEB。
+const (
+	ProcessCommandLineInformation = 60
+	STATUS_INFO_LENGTH_MISMATCH   = 0xC0000004
+)
+
+// UNICODE_STRING 结构（Buffer 指向同一块返回缓冲区内）
+type UNICODE_STRING struct {
+	Length        uint16
+	MaximumLength uint16
+	Buffer        *uint16
+}
+
+// foregroundPID 获取前台窗口所属进程 PID。
+// UWP/商店应用的顶层窗口属于 ApplicationFrameHost.exe，真正的应用是它的子窗口，
+// 这种情况下换成子窗口所属进程。
+func foregroundPID() (uint32, error) {
 	hwnd, _, _ := procGetForegroundWindowFG.Call()
 	if hwnd == 0 {
-		return "", syscall.EINVAL
+		return 0, syscall.EINVAL
+	}
+
+	pid := windowPID(hwnd)
+	if pid == 0 {
+		return 0, syscall.EINVAL
+	}
+	if name, err := processImageName(pid); err == nil && name == frameHostExe {
+		if hosted := hostedAppPID(hwnd, pid); hosted != 0 {
+			return hosted, nil
+		}
 	}
+	return pid, nil
+}
 
+func windowPID(hwnd uintptr) uint32 {
 	var pid uint32
 	procGetWindowThreadProcessIdFG.Call(hwnd, uintptr(unsafe.Pointer(&pid)))
-	if pid == 0 {
-		return "", syscall.EINVAL
+	return pid
+}
+
+// UWP 宿主进程名（小写）
+const frameHostExe = "applicationframehost.exe"
+
+var (
+	enumChildOnce sync.Once
+	enumChildCB   uintptr
+
+	// EnumChildWindows 回调是全局的，用锁串行化并通过这两个变量传参/取结果
+	enumChildMu     sync.Mutex
+	enumChildSkip   uint32
+	enumChildResult uint32
+)
+
+func enumChildProc(hwnd, _ uintptr) uintptr {
+	if pid := windowPID(hwnd); pid != 0 && pid != enumChildSkip {
+		enumChildResult = pid
+		return 0 // 找到即停止枚举
 	}
+	return 1
+}
+
+// hostedAppPID 遍历 ApplicationFrameHost 窗口的子窗口，返回第一个不属于宿主的进程 PID；
+// 应用挂起/最小化时可能没有这样的子窗口，返回 0
+func hostedAppPID(hwnd uintptr, hostPID uint32) uint32 {
+	enumChildOnce.Do(func() {
+		enumChildCB = syscall.NewCallback(enumChildProc)
+	})
+
+	enumChildMu.Lock()
+	defer enumChildMu.Unlock()
+	enumChildSkip, enumChildResult = hostPID, 0
+	procEnumChildWindowsFG.Call(hwnd, enumChildCB, 0)
+	return enumChildResult
+}
 
+func openProcessForQuery(pid uint32) (uintptr, error) {
 	hProc, _, err := procOpenProcessFG.Call(PROCESS_QUERY_LIMITED_INFORMATION, 0, uintptr(pid))
 	if hProc == 0 {
+		return 0, err
+	}
+	return hProc, nil
+}
+
+func ForegroundProcessName() (string, error) {
+	if info, ok := simulatedForegroundInfo(); ok {
+		return info.Name, nil
+	}
+	pid, err := foregroundPID()
+	if err != nil {
+		return "", err
+	}
+	return processImageName(pid)
+}
+
+// processImageName 进程 exe 名（小写 basename）
+func processImageName(pid uint32) (string, error) {
+	full, err := processImagePath(pid)
+	if err != nil {
+		return "", err
+	}
+	return normalizeProcName(full), nil
+}
+
+// processImagePath 进程 exe 完整路径
+func processImagePath(pid uint32) (string, error) {
+	hProc, err := openProcessForQuery(pid)
+	if err != nil {
 		return "", err
 	}
 	defer procCloseHandleFG.Call(hProc)
@@ -51,8 +148,78 @@ func ForegroundProcessName() (string, error) {
 	if r1 == 0 {
 		return "", err
 	}
+	return syscall.UTF16ToString(buf[:size]), nil
+}
+
+// ForegroundWindowInfo 前台进程名、完整路径与窗口标题（-watch 诊断用）。
+// 标题取顶层窗口的；UWP 应用的标题在宿主窗口上，同样有效。
+func ForegroundWindowInfo() (ForegroundInfo, error) {
+	if info, ok := simulatedForegroundInfo(); ok {
+		return info, nil
+	}
+	hwnd, _, _ := procGetForegroundWindowFG.Call()
+	pid, err := foregroundPID()
+	if err != nil {
+		return ForegroundInfo{}, err
+	}
+	path, err := processImagePath(pid)
+	if err != nil {
+		return ForegroundInfo{}, err
+	}
+	buf := make([]uint16, 512)
+	n, _, _ := procGetWindowTextWFG.Call(hwnd, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	return ForegroundInfo{
+		Name:  normalizeProcName(path),
+		Path:  path,
+		Title: syscall.UTF16ToString(buf[:n]),
+	}, nil
+}
+
+// ForegroundProcessCmdline 读取前台进程命令行（尽力而为）
+// 提权进程/受保护进程会 OpenProcess 失败，调用方应静默跳过。
+func ForegroundProcessCmdline() (string, error) {
+	if simulatedForeground != "" {
+		return "", errSimulatedForeground
+	}
+	pid, err := foregroundPID()
+	if err != nil {
+		return "", err
+	}
 
-	full := syscall.UTF16ToString(buf[:size])
-	base := filepath.Base(full)
-	return strings.ToLower(base), nil
+	hProc, err := openProcessForQuery(pid)
+	if err != nil {
+		return "", err
+	}
+	defer procCloseHandleFG.Call(hProc)
+
+	// 先问长度，再按长度取
+	var need uint32
+	st, _, _ := procNtQueryInformationProcess.Call(
+		hProc,
+		uintptr(ProcessCommandLineInformation),
+		0, 0,
+		uintptr(unsafe.Pointer(&need)),
+	)
+	if uint32(st) != STATUS_INFO_LENGTH_MISMATCH || need < uint32(unsafe.Sizeof(UNICODE_STRING{})) {
+		return "", syscall.EINVAL
+	}
+
+	buf := make([]byte, need)
+	st, _, _ = procNtQueryInformationProcess.Call(
+		hProc,
+		uintptr(ProcessCommandLineInformation),
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(need),
+		uintptr(unsafe.Pointer(&need)),
+	)
+	if st != 0 {
+		return "", syscall.EINVAL
+	}
+
+	us := (*UNICODE_STRING)(unsafe.Pointer(&buf[0]))
+	if us.Buffer == nil || us.Length == 0 {
+		return "", nil
+	}
+	chars := unsafe.Slice(us.Buffer, int(us.Length)/2)
+	return syscall.UTF16ToString(chars), nil
 }
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+type StatusWindow struct{}
+
+func StartStatusWindow(st *State) (*StatusWindow, error) {
+	return nil, errors.New("status window is only supported on Windows")
+}
+
+func (w *StatusWindow) Stop() {}
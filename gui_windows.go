@@ -0,0 +1,262 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	user32GUI = syscall.NewLazyDLL("user32.dll")
+	gdi32GUI  = syscall.NewLazyDLL("gdi32.dll")
+
+	procSetTimer         = user32GUI.NewProc("SetTimer")
+	procKillTimer        = user32GUI.NewProc("KillTimer")
+	procShowWindow       = user32GUI.NewProc("ShowWindow")
+	procMoveWindow       = user32GUI.NewProc("MoveWindow")
+	procSendMessageW     = user32GUI.NewProc("SendMessageW")
+	procSetWindowTextW   = user32GUI.NewProc("SetWindowTextW")
+	procTranslateMessage = user32GUI.NewProc("TranslateMessage")
+	procGetStockObject   = gdi32GUI.NewProc("GetStockObject")
+)
+
+const (
+	WM_SIZE        = 0x0005
+	WM_SETFONT     = 0x0030
+	WM_TIMER       = 0x0113
+	WM_APP         = 0x8000
+	WM_APP_DESTROY = WM_APP + 1 // Stop 请求关闭窗口（WM_CLOSE 只最小化）
+
+	WS_OVERLAPPEDWINDOW = 0x00CF0000
+	WS_VISIBLE          = 0x10000000
+	WS_CHILD            = 0x40000000
+	WS_VSCROLL          = 0x00200000
+	ES_MULTILINE        = 0x0004
+	ES_AUTOVSCROLL      = 0x0040
+	ES_READONLY         = 0x0800
+	EM_SETSEL           = 0x00B1
+	EM_SCROLLCARET      = 0x00B7
+	CW_USEDEFAULT       = 0x80000000
+	SW_SHOWNOACTIVATE   = 4
+	SW_MINIMIZE         = 6
+	DEFAULT_GUI_FONT    = 17
+
+	statusTimerID       = 1
+	statusRefreshMillis = 1000
+)
+
+// StatusWindow 状态小窗口：定时从 State 快照和最近日志刷新内容。
+// 关闭按钮只最小化；程序退出时由 Stop 销毁。
+type StatusWindow struct {
+	hwnd uintptr
+	edit uintptr
+	st   *State
+	text string // 上次显示的内容，不变时不刷新（免得打断滚动/选中）
+	done chan struct{}
+}
+
+var (
+	statusWndProcOnce sync.Once
+	statusWndProcPtr  uintptr
+	statusClassName   = syscall.StringToUTF16Ptr("VaxeeAutoSwitchStatusWnd")
+
+	// 窗口过程是全局回调，靠它找回当前窗口
+	activeStatusMu sync.Mutex
+	activeStatus   *StatusWindow
+)
+
+func statusWndProc(hwnd, msg, wParam, lParam uintptr) uintptr {
+	activeStatusMu.Lock()
+	w := activeStatus
+	activeStatusMu.Unlock()
+
+	switch msg {
+	case WM_TIMER:
+		if w != nil {
+			w.refresh()
+		}
+		return 0
+	case WM_SIZE:
+		if w != nil && w.edit != 0 {
+			procMoveWindow.Call(w.edit, 0, 0, lParam&0xFFFF, (lParam>>16)&0xFFFF, 1)
+		}
+		return 0
+	case WM_CLOSE:
+		procShowWindow.Call(hwnd, SW_MINIMIZE)
+		return 0
+	case WM_APP_DESTROY:
+		procKillTimer.Call(hwnd, statusTimerID)
+		procDestroyWindow.Call(hwnd)
+		return 0
+	case WM_DESTROY:
+		procPostQuitMessage.Call(0)
+		return 0
+	}
+	r, _, _ := procDefWindowProcW.Call(hwnd, msg, wParam, lParam)
+	return r
+}
+
+// StartStatusWindow 创建状态窗口并在独立 OS 线程上跑消息循环
+func StartStatusWindow(st *State) (*StatusWindow, error) {
+	statusWndProcOnce.Do(func() {
+		statusWndProcPtr = syscall.NewCallback(statusWndProc)
+	})
+
+	w := &StatusWindow{st: st, done: make(chan struct{})}
+	ready := make(chan error, 1)
+
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		defer close(w.done)
+
+		hInst, _, _ := procGetModuleHandleW.Call(0)
+		wc := WNDCLASSEXW{
+			LpfnWndProc:   statusWndProcPtr,
+			HInstance:     hInst,
+			LpszClassName: statusClassName,
+		}
+		wc.CbSize = uint32(unsafe.Sizeof(wc))
+		procRegisterClassExW.Call(uintptr(unsafe.Pointer(&wc))) // 重复注册失败无所谓
+
+		activeStatusMu.Lock()
+		activeStatus = w
+		activeStatusMu.Unlock()
+		defer func() {
+			activeStatusMu.Lock()
+			if activeStatus == w {
+				activeStatus = nil
+			}
+			activeStatusMu.Unlock()
+		}()
+
+		title, _ := syscall.UTF16PtrFromString("VAXEE AutoSwitch 状态")
+		// 不置顶：游戏全屏时状态窗口不该盖在上面
+		hwnd, _, e := procCreateWindowExW.Call(
+			0,
+			uintptr(unsafe.Pointer(statusClassName)),
+			uintptr(unsafe.Pointer(title)),
+			WS_OVERLAPPEDWINDOW,
+			CW_USEDEFAULT, CW_USEDEFAULT, 560, 380,
+			0, 0, hInst, 0,
+		)
+		if hwnd == 0 {
+			ready <- fmt.Errorf("CreateWindowExW failed: %v", e)
+			return
+		}
+		w.hwnd = hwnd
+
+		editClass, _ := syscall.UTF16PtrFromString("EDIT")
+		edit, _, e := procCreateWindowExW.Call(
+			0,
+			uintptr(unsafe.Pointer(editClass)),
+			0,
+			WS_CHILD|WS_VISIBLE|WS_VSCROLL|ES_MULTILINE|ES_AUTOVSCROLL|ES_READONLY,
+			0, 0, 0, 0,
+			hwnd, 0, hInst, 0,
+		)
+		if edit == 0 {
+			procDestroyWindow.Call(hwnd)
+			ready <- fmt.Errorf("CreateWindowExW(EDIT) failed: %v", e)
+			return
+		}
+		w.edit = edit
+		font, _, _ := procGetStockObject.Call(DEFAULT_GUI_FONT)
+		procSendMessageW.Call(edit, WM_SETFONT, font, 0)
+
+		// 先设置好子控件再显示，首个 WM_SIZE 会把编辑框撑满窗口；
+		// 显示时不激活，不从正在前台的程序（可能是游戏）抢走焦点
+		procShowWindow.Call(hwnd, SW_SHOWNOACTIVATE)
+		w.refresh()
+		procSetTimer.Call(hwnd, statusTimerID, statusRefreshMillis, 0)
+		ready <- nil
+
+		var msg MSG
+		for {
+			r1, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+			if int32(r1) <= 0 {
+				return
+			}
+			procTranslateMessage.Call(uintptr(unsafe.Pointer(&msg)))
+			procDispatchMessageW.Call(uintptr(unsafe.Pointer(&msg)))
+		}
+	}()
+
+	if err := <-ready; err != nil {
+		<-w.done
+		return nil, err
+	}
+	return w, nil
+}
+
+// refresh 在窗口线程上调用：内容变化时重写编辑框并滚到末尾
+func (w *StatusWindow) refresh() {
+	text := statusWindowText(w.st.Snapshot(), logTail.Lines())
+	if text == w.text {
+		return
+	}
+	w.text = text
+	p, err := syscall.UTF16PtrFromString(text)
+	if err != nil {
+		return
+	}
+	procSetWindowTextW.Call(w.edit, uintptr(unsafe.Pointer(p)))
+	n := uintptr(len(syscall.StringToUTF16(text)))
+	procSendMessageW.Call(w.edit, EM_SETSEL, n, n)
+	procSendMessageW.Call(w.edit, EM_SCROLLCARET, 0, 0)
+}
+
+// statusWindowText 窗口内容：状态概要 + 最近日志（编辑框要求 CRLF 换行）
+func statusWindowText(s StatusSnapshot, logLines []string) string {
+	var b strings.Builder
+	proc := s.Proc
+	if proc == "" {
+		proc = "（无）"
+	}
+	fmt.Fprintf(&b, "前台进程：%s", proc)
+	if s.Hit {
+		fmt.Fprintf(&b, "（命中 %s）", s.Rule)
+	}
+	b.WriteString("\r\n")
+
+	if s.AppliedOK {
+		fmt.Fprintf(&b, "当前配置：%s（回报率 %dHz）", perfName(s.Applied.Perf), s.Applied.Poll)
+	} else {
+		b.WriteString("当前配置：（尚未下发）")
+	}
+	if s.Manual {
+		b.WriteString("（手动覆盖）")
+	}
+	if s.Battery {
+		b.WriteString("（电池）")
+	}
+	b.WriteString("\r\n")
+
+	device := s.Device
+	if device == "" {
+		device = "（无）"
+	}
+	fmt.Fprintf(&b, "设备：%s\r\n", device)
+	fmt.Fprintf(&b, "已切换：%d 次\r\n", s.Switches)
+	if s.LastError != "" {
+		fmt.Fprintf(&b, "错误：%s\r\n", s.LastError)
+	}
+
+	b.WriteString("\r\n—— 最近日志 ——\r\n")
+	b.WriteString(strings.Join(logLines, "\r\n"))
+	return b.String()
+}
+
+// Stop 销毁窗口并结束消息循环
+func (w *StatusWindow) Stop() {
+	if w == nil {
+		return
+	}
+	procPostMessageW.Call(w.hwnd, WM_APP_DESTROY, 0, 0)
+	<-w.done
+}
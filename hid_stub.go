@@ -2,28 +2,47 @@
 
 package main
 
-import "errors"
+import (
+	"context"
+	"errors"
+)
 
-type VaxeeDeviceInfo struct {
-	Path         string
-	VID          uint16
-	PID          uint16
-	Manufacturer string
-	Product      string
-}
-
-func EnumerateVaxeeDevices() ([]VaxeeDeviceInfo, error) {
+func EnumerateVaxeeDevices(ctx context.Context) ([]VaxeeDeviceInfo, error) {
 	return nil, errors.New("HID enumeration is only supported on Windows")
 }
 
-func FindOneVaxeeDevice() (VaxeeDeviceInfo, error) {
+func FindOneVaxeeDevice(ctx context.Context, opts DeviceOptions) (VaxeeDeviceInfo, error) {
 	return VaxeeDeviceInfo{}, errors.New("HID enumeration is only supported on Windows")
 }
 
-func ApplyVaxeeSetting(path string, perf PerfMode, poll PollingRate) error {
+func ApplyVaxeeSetting(ctx context.Context, opts DeviceOptions, path string, p Profile) error {
 	return errors.New("HID feature report is only supported on Windows")
 }
 
-func EnumerateAllHidDevices() ([]VaxeeDeviceInfo, error) {
+func EnumerateAllHidDevices(ctx context.Context) ([]VaxeeDeviceInfo, error) {
 	return nil, errors.New("HID enumeration is only supported on Windows")
 }
+
+func EnumerateAllHidDevicesFunc(ctx context.Context, fn func(VaxeeDeviceInfo) bool) error {
+	return errors.New("HID enumeration is only supported on Windows")
+}
+
+func ReadDeviceInfo(ctx context.Context, path string, opts DeviceOptions, flen int) (model, firmware string, err error) {
+	return "", "", errors.New("HID feature report is only supported on Windows")
+}
+
+func ReadCapabilities(ctx context.Context, path string, opts DeviceOptions, flen int) (Capabilities, error) {
+	return Capabilities{}, errors.New("HID feature report is only supported on Windows")
+}
+
+func ReadCurrentSettings(ctx context.Context, path string, opts DeviceOptions, flen int) (Profile, error) {
+	return Profile{}, errors.New("HID feature report is only supported on Windows")
+}
+
+func ReadActiveProfileSlot(ctx context.Context, path string, opts DeviceOptions, flen int) (int, error) {
+	return 0, errors.New("HID feature report is only supported on Windows")
+}
+
+func SelectProfileSlot(ctx context.Context, path string, opts DeviceOptions, flen int, slot int) error {
+	return errors.New("HID feature report is only supported on Windows")
+}
@@ -3,10 +3,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"strings"
+	"sync"
 	"syscall"
-	"time"
 	"unsafe"
 )
 
@@ -17,6 +20,13 @@ type GUID struct {
 	Data4 [8]byte
 }
 
+// String 注册表写法 {xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx}（小写）
+func (g GUID) String() string {
+	return fmt.Sprintf("{%08x-%04x-%04x-%02x%02x-%02x%02x%02x%02x%02x%02x}",
+		g.Data1, g.Data2, g.Data3, g.Data4[0], g.Data4[1],
+		g.Data4[2], g.Data4[3], g.Data4[4], g.Data4[5], g.Data4[6], g.Data4[7])
+}
+
 type HIDD_ATTRIBUTES struct {
 	Size      uint32
 	VendorID  uint16
@@ -31,6 +41,31 @@ type SP_DEVICE_INTERFACE_DATA struct {
 	Reserved           uintptr
 }
 
+// SP_DEVINFO_DATA（setupapi.h）：接口所属的设备节点
+type SP_DEVINFO_DATA struct {
+	CbSize    uint32
+	ClassGuid GUID
+	DevInst   uint32
+	Reserved  uintptr
+}
+
+// DEVPROPKEY（devpropdef.h）
+type DEVPROPKEY struct {
+	Fmtid GUID
+	Pid   uint32
+}
+
+// DEVPKEY_Device_ContainerId：同一物理设备的所有设备节点（各 HID 集合）共享同一个容器 ID，拔插、换口都不变
+var DEVPKEY_Device_ContainerId = DEVPROPKEY{
+	Fmtid: GUID{0x8c7ed206, 0x3f8a, 0x4827, [8]byte{0xb3, 0xab, 0xae, 0x9e, 0x1f, 0xae, 0xfc, 0x6c}},
+	Pid:   2,
+}
+
+const DEVPROP_TYPE_GUID = 0x0000000D
+
+// nullContainerID 系统给“无法归属物理设备”的节点填的容器 ID，视为没有
+const nullContainerID = "{00000000-0000-0000-ffff-ffffffffffff}"
+
 // HIDP_CAPS 结构：包含 FeatureReportByteLength（包含 ReportID 字节）[2](https://learn.microsoft.com/zh-tw/windows-hardware/drivers/ddi/hidpi/ns-hidpi-_hidp_caps)
 type HIDP_CAPS struct {
 	Usage                     uint16
@@ -66,11 +101,13 @@ var (
 	procSetupDiEnumDeviceInterfaces_HID      = setupapiHID.NewProc("SetupDiEnumDeviceInterfaces")
 	procSetupDiGetDeviceInterfaceDetailW_HID = setupapiHID.NewProc("SetupDiGetDeviceInterfaceDetailW")
 	procSetupDiDestroyDeviceInfoList_HID     = setupapiHID.NewProc("SetupDiDestroyDeviceInfoList")
+	procSetupDiGetDevicePropertyW_HID        = setupapiHID.NewProc("SetupDiGetDevicePropertyW")
 
 	procHidDGetHidGuid_HID            = hidDLLHID.NewProc("HidD_GetHidGuid")
 	procHidDGetAttributes_HID         = hidDLLHID.NewProc("HidD_GetAttributes")
 	procHidDGetManufacturerString_HID = hidDLLHID.NewProc("HidD_GetManufacturerString")
 	procHidDGetProductString_HID      = hidDLLHID.NewProc("HidD_GetProductString")
+	procHidDGetSerialNumberString_HID = hidDLLHID.NewProc("HidD_GetSerialNumberString")
 
 	procHidDSetFeature_HID        = hidDLLHID.NewProc("HidD_SetFeature") // [1](https://learn.microsoft.com/en-us/windows-hardware/drivers/ddi/hidsdi/nf-hidsdi-hidd_setfeature)
 	procHidDGetFeature_HID        = hidDLLHID.NewProc("HidD_GetFeature") // [3](https://learn.microsoft.com/en-us/windows-hardware/drivers/ddi/hidsdi/nf-hidsdi-hidd_getfeature)
@@ -79,6 +116,7 @@ var (
 	procHidPGetCaps_HID           = hidDLLHID.NewProc("HidP_GetCaps") // [4](https://learn.microsoft.com/en-us/windows-hardware/drivers/ddi/hidpi/nf-hidpi-hidp_getcaps)
 
 	procCreateFileW_HID  = k32HID.NewProc("CreateFileW")
+	procCancelIoEx_HID   = k32HID.NewProc("CancelIoEx")
 	procCloseHandle_HID  = k32HID.NewProc("CloseHandle")
 	procGetLastError_HID = k32HID.NewProc("GetLastError")
 )
@@ -106,30 +144,22 @@ func detailCbSizeW() uint32 {
 
 const detailDevicePathOffset = 4
 
-type VaxeeDeviceInfo struct {
-	Path         string
-	VID          uint16
-	PID          uint16
-	Manufacturer string
-	Product      string
-	UsagePage    uint16
-	Usage        uint16
-	FeatureLen   uint16
-}
+var detailLayoutOnce sync.Once
 
-// 生成指定长度的 feature report（保证 buffer 长度符合 caps.FeatureReportByteLength）[1](https://learn.microsoft.com/en-us/windows-hardware/drivers/ddi/hidsdi/nf-hidsdi-hidd_setfeature)[2](https://learn.microsoft.com/zh-tw/windows-hardware/drivers/ddi/hidpi/ns-hidpi-_hidp_caps)
-func buildReportSized(total int, cmd byte, val byte) []byte {
-	if total < 6 {
-		total = 6
+// detailLayout 实际使用的 cbSize / DevicePath 偏移（-detail-cbsize / -detail-offset 可覆盖，诊断用）
+func detailLayout() (cbSize uint32, offset uintptr) {
+	cbSize, offset = detailCbSizeW(), detailDevicePathOffset
+	cbNote, offNote := "", ""
+	if detailCbSizeOverride != 0 {
+		cbSize, cbNote = uint32(detailCbSizeOverride), "（覆盖）"
+	}
+	if detailOffsetOverride != 0 {
+		offset, offNote = uintptr(detailOffsetOverride), "（覆盖）"
 	}
-	buf := make([]byte, total)
-	buf[0] = 0x0e // ReportID 14（你的抓包就是 0x0e）[9](https://blog.csdn.net/frederick_master/article/details/78845161)
-	buf[1] = 0xa5
-	buf[2] = cmd
-	buf[3] = 0x02
-	buf[4] = 0x01
-	buf[5] = val
-	return buf
+	detailLayoutOnce.Do(func() {
+		log.Printf("[DEV] DetailData 布局：cbSize=%d%s DevicePath 偏移=%d%s", cbSize, cbNote, offset, offNote)
+	})
+	return cbSize, offset
 }
 
 func lastErrno() syscall.Errno {
@@ -137,37 +167,110 @@ func lastErrno() syscall.Errno {
 	return syscall.Errno(r1)
 }
 
-func sendFeatureReport(path string, report []byte) error {
+// 读写句柄上 SetFeature 被拒后试过只写句柄的路径：true = 只写句柄可用，之后直接只写打开；
+// false = 只写也不行，不再重试
+var (
+	writeOnlyMu    sync.Mutex
+	writeOnlyPaths = map[string]bool{}
+)
+
+// ERROR_INVALID_FUNCTION 读写句柄上 SetFeature 被拒时的错误（"Incorrect function."）
+const ERROR_INVALID_FUNCTION syscall.Errno = 1
+
+// sendFeatureReport 在设备线程上发送一条报告（探测、读请求用）：失败不换句柄重试
+func sendFeatureReport(ctx context.Context, path string, report []byte) error {
+	return deviceThread.run(ctx, func() error {
+		if len(report) == 0 {
+			return fmt.Errorf("empty report")
+		}
+		writeOnlyMu.Lock()
+		wo := writeOnlyPaths[path]
+		writeOnlyMu.Unlock()
+		_, err := setFeature(ctx, path, report, wo)
+		return err
+	})
+}
+
+// writeFeatureReport 在设备线程上下发一条设置报告；读写句柄上被拒（ERROR_INVALID_FUNCTION）时换只写句柄重发
+func writeFeatureReport(ctx context.Context, path string, report []byte) error {
+	return deviceThread.run(ctx, func() error {
+		return writeFeatureReportNow(ctx, path, report)
+	})
+}
+
+func writeFeatureReportNow(ctx context.Context, path string, report []byte) error {
 	if len(report) == 0 {
 		return fmt.Errorf("empty report")
 	}
-	h, err := openHIDPath(path)
-	if err != nil {
+	writeOnlyMu.Lock()
+	wo, tried := writeOnlyPaths[path]
+	writeOnlyMu.Unlock()
+
+	rw, err := setFeature(ctx, path, report, wo)
+	if err == nil || !rw || tried || ctx.Err() != nil || !errors.Is(err, ERROR_INVALID_FUNCTION) {
 		return err
 	}
+
+	// 有的设备允许以读写打开，却在读写句柄上以 ERROR_INVALID_FUNCTION 拒绝 SetFeature：换只写句柄重发一次
+	log.Printf("[APPLY] 读写句柄上 SetFeature 失败（%v），改用只写句柄重发：%s", err, path)
+	_, err2 := setFeature(ctx, path, report, true)
+	writeOnlyMu.Lock()
+	writeOnlyPaths[path] = err2 == nil
+	writeOnlyMu.Unlock()
+	if err2 != nil {
+		return fmt.Errorf("%w (write-only retry: %v)", err, err2)
+	}
+	log.Printf("[APPLY] 只写句柄重发成功，之后对该集合都用只写句柄。")
+	return nil
+}
+
+// setFeature 打开一次句柄发送一条报告；rw 表示用的是读写句柄
+func setFeature(ctx context.Context, path string, report []byte, writeOnly bool) (rw bool, err error) {
+	h, rw, err := openHIDPathCtx(ctx, path, writeOnly)
+	if err != nil {
+		return false, err
+	}
 	defer closeHandle(h)
 
-	r1, _, _ := procHidDSetFeature_HID.Call(
+	// ctx 取消（超时）时取消挂起的 I/O，让 HidD_SetFeature 尽快返回、句柄得以关闭
+	stop := cancelIoOnDone(ctx, h)
+	defer stop()
+
+	r1, _, e := procHidDSetFeature_HID.Call(
 		uintptr(h),
 		uintptr(unsafe.Pointer(&report[0])),
 		uintptr(len(report)),
 	)
 	if r1 == 0 {
-		return fmt.Errorf("HidD_SetFeature failed: %v", lastErrno()) // e.g. ERROR_INVALID_FUNCTION => "Incorrect function."
+		errno, _ := e.(syscall.Errno)
+		return rw, fmt.Errorf("HidD_SetFeature failed: %w", errno) // e.g. ERROR_INVALID_FUNCTION => "Incorrect function."
 	}
-	return nil
+	return rw, nil
 }
 
-func getFeature(path string, reportID byte, length int) ([]byte, error) {
+// getFeature 在设备线程上读一条 Feature 报告（见 DeviceThread）
+func getFeature(ctx context.Context, path string, reportID byte, length int) ([]byte, error) {
+	var buf []byte
+	err := deviceThread.run(ctx, func() (err error) {
+		buf, err = getFeatureNow(ctx, path, reportID, length)
+		return err
+	})
+	return buf, err
+}
+
+func getFeatureNow(ctx context.Context, path string, reportID byte, length int) ([]byte, error) {
 	if length <= 0 {
 		return nil, fmt.Errorf("invalid length")
 	}
-	h, err := openHIDPath(path)
+	h, _, err := openHIDPathCtx(ctx, path, false)
 	if err != nil {
 		return nil, err
 	}
 	defer closeHandle(h)
 
+	stop := cancelIoOnDone(ctx, h)
+	defer stop()
+
 	buf := make([]byte, length)
 	buf[0] = reportID // HidD_GetFeature 需要第一个字节写 report ID [3](https://learn.microsoft.com/en-us/windows-hardware/drivers/ddi/hidsdi/nf-hidsdi-hidd_getfeature)
 	r1, _, _ := procHidDGetFeature_HID.Call(
@@ -176,29 +279,32 @@ func getFeature(path string, reportID byte, length int) ([]byte, error) {
 		uintptr(len(buf)),
 	)
 	if r1 == 0 {
-		return nil, fmt.Errorf("HidD_GetFeature failed: %v", lastErrno())
+		return nil, fmt.Errorf("HidD_GetFeature failed: %w", lastErrno())
 	}
 	return buf, nil
 }
 
-func openHIDPath(path string) (syscall.Handle, error) {
+// openHIDPath 先以读写打开，失败再以只写打开；writeOnly 时直接只写打开。rw 表示拿到的是读写句柄
+func openHIDPath(path string, writeOnly bool) (h syscall.Handle, rw bool, err error) {
 	p16, err := syscall.UTF16PtrFromString(path)
 	if err != nil {
-		return 0, err
+		return 0, false, err
 	}
 
 	// RW
-	h, _, _ := procCreateFileW_HID.Call(
-		uintptr(unsafe.Pointer(p16)),
-		uintptr(GENERIC_READ|GENERIC_WRITE),
-		uintptr(FILE_SHARE_READ|FILE_SHARE_WRITE),
-		0,
-		uintptr(OPEN_EXISTING),
-		0,
-		0,
-	)
-	if h != 0 && h != uintptr(syscall.InvalidHandle) {
-		return syscall.Handle(h), nil
+	if !writeOnly {
+		h, _, _ := procCreateFileW_HID.Call(
+			uintptr(unsafe.Pointer(p16)),
+			uintptr(GENERIC_READ|GENERIC_WRITE),
+			uintptr(FILE_SHARE_READ|FILE_SHARE_WRITE),
+			0,
+			uintptr(OPEN_EXISTING),
+			0,
+			0,
+		)
+		if h != 0 && h != uintptr(syscall.InvalidHandle) {
+			return syscall.Handle(h), true, nil
+		}
 	}
 
 	// Write only（有些设备只允许写）
@@ -212,10 +318,86 @@ func openHIDPath(path string) (syscall.Handle, error) {
 		0,
 	)
 	if h2 != 0 && h2 != uintptr(syscall.InvalidHandle) {
-		return syscall.Handle(h2), nil
+		return syscall.Handle(h2), false, nil
 	}
 
-	return 0, fmt.Errorf("CreateFileW failed: %s (%v)", path, lastErrno())
+	return 0, false, fmt.Errorf("CreateFileW failed: %s (%v)", path, lastErrno())
+}
+
+// openHIDPathCtx 在后台 goroutine 里打开设备，ctx 取消时不再等待；
+// 被放弃的 CreateFileW 若之后成功返回，由后台 goroutine 负责关闭句柄。
+func openHIDPathCtx(ctx context.Context, path string, writeOnly bool) (syscall.Handle, bool, error) {
+	return openCtx(ctx, func() (syscall.Handle, bool, error) {
+		return openHIDPath(path, writeOnly)
+	})
+}
+
+// openHIDPathForQueryCtx 同 openHIDPathCtx，以查询权限打开（枚举用）
+func openHIDPathForQueryCtx(ctx context.Context, path string) (syscall.Handle, error) {
+	h, _, err := openCtx(ctx, func() (syscall.Handle, bool, error) {
+		h, err := openHIDPathForQuery(path)
+		return h, false, err
+	})
+	return h, err
+}
+
+func openCtx(ctx context.Context, open func() (syscall.Handle, bool, error)) (syscall.Handle, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, false, err
+	}
+
+	type result struct {
+		h   syscall.Handle
+		rw  bool
+		err error
+	}
+	ch := make(chan result, 1)
+	abandoned := make(chan struct{})
+	go func() {
+		h, rw, err := open()
+		select {
+		case ch <- result{h, rw, err}:
+		case <-abandoned:
+			if err == nil {
+				closeHandle(h)
+			}
+		}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.h, r.rw, r.err
+	case <-ctx.Done():
+		close(abandoned)
+		// 与发送方竞争：结果可能已写入缓冲 channel
+		select {
+		case r := <-ch:
+			if r.err == nil {
+				closeHandle(r.h)
+			}
+		default:
+		}
+		return 0, false, ctx.Err()
+	}
+}
+
+// cancelIoOnDone ctx 结束时对句柄执行 CancelIoEx；返回的 stop 用于正常结束时解除监听
+func cancelIoOnDone(ctx context.Context, h syscall.Handle) (stop func()) {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			procCancelIoEx_HID.Call(uintptr(h), 0)
+		case <-done:
+		}
+	}()
+	// 等监听 goroutine 退出后再让调用方关闭句柄，避免对已关闭句柄 CancelIoEx
+	return func() {
+		close(done)
+		<-exited
+	}
 }
 
 func openHIDPathForQuery(path string) (syscall.Handle, error) {
@@ -263,13 +445,44 @@ func utf16FromPtr(p *uint16) string {
 	return syscall.UTF16ToString(arr)
 }
 
+// HidD_Get*String 缓冲区不够时可能直接截断（不带 NUL），也可能返回失败
+const (
+	ERROR_INSUFFICIENT_BUFFER syscall.Errno = 122
+	ERROR_INVALID_USER_BUFFER syscall.Errno = 1784
+	hidStringMaxChars                       = 4096
+	hidStringInitialChars                   = 256
+)
+
+// hidGetString 读取 HID 字符串；没读到结尾 NUL 说明被截断，加大缓冲区重读，
+// 避免长名称被截断、或代理对（emoji 等）被从中间切开
 func hidGetString(h syscall.Handle, proc *syscall.LazyProc) string {
-	buf := make([]uint16, 256)
-	r1, _, _ := proc.Call(uintptr(h), uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)*2))
-	if r1 == 0 {
-		return ""
+	for n := hidStringInitialChars; n <= hidStringMaxChars; n *= 2 {
+		buf := make([]uint16, n)
+		r1, _, _ := proc.Call(uintptr(h), uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)*2))
+		if r1 == 0 {
+			if e := lastErrno(); e == ERROR_INSUFFICIENT_BUFFER || e == ERROR_INVALID_USER_BUFFER {
+				continue
+			}
+			return ""
+		}
+		for i, u := range buf {
+			if u == 0 {
+				return syscall.UTF16ToString(buf[:i])
+			}
+		}
+		if n*2 > hidStringMaxChars {
+			return syscall.UTF16ToString(trimLoneHighSurrogate(buf))
+		}
+	}
+	return ""
+}
+
+// trimLoneHighSurrogate 去掉被截断在末尾的半个代理对
+func trimLoneHighSurrogate(s []uint16) []uint16 {
+	if n := len(s); n > 0 && s[n-1] >= 0xd800 && s[n-1] < 0xdc00 {
+		return s[:n-1]
 	}
-	return syscall.UTF16ToString(buf)
+	return s
 }
 
 // 读取 HIDP_CAPS（拿 FeatureReportByteLength / UsagePage / Usage）[4](https://learn.microsoft.com/en-us/windows-hardware/drivers/ddi/hidpi/nf-hidpi-hidp_getcaps)[2](https://learn.microsoft.com/zh-tw/windows-hardware/drivers/ddi/hidpi/ns-hidpi-_hidp_caps)
@@ -289,13 +502,16 @@ func queryCaps(h syscall.Handle) (HIDP_CAPS, error) {
 	return caps, nil
 }
 
-func queryDeviceInfo(path string) (VaxeeDeviceInfo, bool) {
-	h, err := openHIDPathForQuery(path)
+func queryDeviceInfo(ctx context.Context, path string) (VaxeeDeviceInfo, bool) {
+	h, err := openHIDPathForQueryCtx(ctx, path)
 	if err != nil {
 		return VaxeeDeviceInfo{}, false
 	}
 	defer closeHandle(h)
 
+	stop := cancelIoOnDone(ctx, h)
+	defer stop()
+
 	var attr HIDD_ATTRIBUTES
 	attr.Size = uint32(unsafe.Sizeof(attr))
 	r1, _, _ := procHidDGetAttributes_HID.Call(uintptr(h), uintptr(unsafe.Pointer(&attr)))
@@ -305,179 +521,600 @@ func queryDeviceInfo(path string) (VaxeeDeviceInfo, bool) {
 
 	manu := hidGetString(h, procHidDGetManufacturerString_HID)
 	prod := hidGetString(h, procHidDGetProductString_HID)
+	serial := hidGetString(h, procHidDGetSerialNumberString_HID) // 很多设备没有序列号，失败时为空
 
 	caps, capErr := queryCaps(h)
 	// caps 失败不影响枚举展示，但会影响后续“选择控制通道”
 	if capErr != nil {
 		return VaxeeDeviceInfo{
 			Path: path, VID: attr.VendorID, PID: attr.ProductID,
-			Manufacturer: manu, Product: prod,
+			Manufacturer: manu, Product: prod, Serial: serial,
+			CapsErr: capErr.Error(),
 		}, true
 	}
 
 	return VaxeeDeviceInfo{
 		Path: path, VID: attr.VendorID, PID: attr.ProductID,
-		Manufacturer: manu, Product: prod,
+		Manufacturer: manu, Product: prod, Serial: serial,
 		UsagePage: caps.UsagePage, Usage: caps.Usage,
 		FeatureLen: caps.FeatureReportByteLength,
+		OutputLen:  caps.OutputReportByteLength,
 	}, true
 }
 
-func EnumerateVaxeeDevices() ([]VaxeeDeviceInfo, error) {
-	g := hidGuid()
+// caps 取不到 FeatureLen 时探测出的可用长度，按路径缓存（设备拔插后路径不变，长度也不变）
+var (
+	featureLenMu    sync.Mutex
+	featureLenCache = map[string]int{}
+)
 
-	hDevInfo, _, _ := procSetupDiGetClassDevsW_HID.Call(
-		uintptr(unsafe.Pointer(&g)), 0, 0,
-		uintptr(DIGCF_PRESENT|DIGCF_DEVICEINTERFACE),
-	)
-	if hDevInfo == 0 || hDevInfo == uintptr(syscall.InvalidHandle) {
-		return nil, fmt.Errorf("SetupDiGetClassDevsW failed: %v", lastErrno())
+// featureLenFor 返回该集合可用的 Feature 报告长度。
+// caps 正常时直接用 FeatureReportByteLength；否则依次用 64（抓包 wLength=64）、
+// OutputReportByteLength、32 做一次 GetFeature（只读，无副作用），第一个成功的长度被缓存。
+func featureLenFor(ctx context.Context, d VaxeeDeviceInfo, reportID byte) (int, error) {
+	if d.FeatureLen > 0 {
+		return int(d.FeatureLen), nil
+	}
+	featureLenMu.Lock()
+	n, ok := featureLenCache[d.Path]
+	featureLenMu.Unlock()
+	if ok {
+		return n, nil
 	}
-	defer procSetupDiDestroyDeviceInfoList_HID.Call(hDevInfo)
-
-	var out []VaxeeDeviceInfo
-	for idx := 0; ; idx++ {
-		var ifData SP_DEVICE_INTERFACE_DATA
-		ifData.CbSize = uint32(unsafe.Sizeof(ifData))
 
-		r1, _, eEnum := procSetupDiEnumDeviceInterfaces_HID.Call(
-			hDevInfo, 0,
-			uintptr(unsafe.Pointer(&g)),
-			uintptr(idx),
-			uintptr(unsafe.Pointer(&ifData)),
-		)
-		if r1 == 0 {
-			if errno, ok := eEnum.(syscall.Errno); ok && errno == ERROR_NO_MORE_ITEMS {
-				break
+	candidates := []int{64}
+	if o := int(d.OutputLen); o > 0 && o != 64 && o != 32 {
+		candidates = append(candidates, o)
+	}
+	candidates = append(candidates, 32)
+
+	var lastErr error
+	for _, n := range candidates {
+		resp, err := getFeature(ctx, d.Path, reportID, n)
+		debugFeatureProbe(d.Path, n, resp, err)
+		if err != nil {
+			if ctx.Err() != nil {
+				return 0, ctx.Err()
 			}
-			break
-		}
-
-		var required uint32
-		procSetupDiGetDeviceInterfaceDetailW_HID.Call(
-			hDevInfo,
-			uintptr(unsafe.Pointer(&ifData)),
-			0, 0,
-			uintptr(unsafe.Pointer(&required)),
-			0,
-		)
-		if required == 0 {
+			lastErr = err
 			continue
 		}
-
-		buf := make([]byte, required)
-		*(*uint32)(unsafe.Pointer(&buf[0])) = detailCbSizeW()
-
-		r2, _, _ := procSetupDiGetDeviceInterfaceDetailW_HID.Call(
-			hDevInfo,
-			uintptr(unsafe.Pointer(&ifData)),
-			uintptr(unsafe.Pointer(&buf[0])),
-			uintptr(required),
-			uintptr(unsafe.Pointer(&required)),
-			0,
-		)
-		if r2 == 0 {
-			continue
-		}
-
-		pathPtr := (*uint16)(unsafe.Pointer(uintptr(unsafe.Pointer(&buf[0])) + detailDevicePathOffset))
-		path := utf16FromPtr(pathPtr)
-		if path == "" {
-			continue
+		if n != 64 {
+			log.Printf("[DEV] caps 不可用，探测到 Feature 报告长度 %d：%s", n, d.Path)
 		}
+		featureLenMu.Lock()
+		featureLenCache[d.Path] = n
+		featureLenMu.Unlock()
+		return n, nil
+	}
+	return 0, lastErr
+}
 
-		info, ok := queryDeviceInfo(path)
-		if !ok {
-			continue
-		}
+func EnumerateVaxeeDevices(ctx context.Context) ([]VaxeeDeviceInfo, error) {
+	var out []VaxeeDeviceInfo
+	err := EnumerateAllHidDevicesFunc(ctx, func(info VaxeeDeviceInfo) bool {
 		m := strings.ToLower(info.Manufacturer)
 		p := strings.ToLower(info.Product)
 		if strings.Contains(m, "vaxee") || strings.Contains(p, "vaxee") {
 			out = append(out, info)
 		}
+		return true
+	})
+	return out, err
+}
+
+// debugFeatureProbe 探测时每个集合 GetFeature 的结果（log_verbose）；末尾的 0 省略
+func debugFeatureProbe(path string, n int, resp []byte, err error) {
+	if !verboseLog {
+		return
+	}
+	if err != nil {
+		var errno syscall.Errno
+		if errors.As(err, &errno) {
+			debugf("probe len=%d %s：%v (errno=%d)", n, path, err, uint32(errno))
+		} else {
+			debugf("probe len=%d %s：%v", n, path, err)
+		}
+		return
 	}
-	return out, nil
+	end := len(resp)
+	for end > 1 && resp[end-1] == 0 {
+		end--
+	}
+	debugf("probe len=%d %s：% x", n, path, resp[:end])
 }
 
 // 选择“真正能收发 ReportID=0x0e Feature Report”的顶级集合
 // 用 HidD_GetFeature 探测最安全：失败就换下一个。[3](https://learn.microsoft.com/en-us/windows-hardware/drivers/ddi/hidsdi/nf-hidsdi-hidd_getfeature)[2](https://learn.microsoft.com/zh-tw/windows-hardware/drivers/ddi/hidpi/ns-hidpi-_hidp_caps)
-func SelectVaxeeControlPath() (VaxeeDeviceInfo, error) {
-	ds, err := EnumerateVaxeeDevices()
+func SelectVaxeeControlPath(ctx context.Context, opts DeviceOptions) (VaxeeDeviceInfo, error) {
+	ds, err := EnumerateVaxeeDevices(ctx)
 	if err != nil {
 		return VaxeeDeviceInfo{}, err
 	}
 	if len(ds) == 0 {
-		return VaxeeDeviceInfo{}, fmt.Errorf("no VAXEE HID device found")
+		return VaxeeDeviceInfo{}, errNoVaxeeDevice
+	}
+	ds = withFeatureLenOverrides(ds, opts)
+
+	// -device N：用户指定了枚举序号，直接用（不探测，只补上 caps 缺失时的报告长度）
+	if opts.Index > 0 {
+		d, err := indexedDevice(ds, opts)
+		if err != nil {
+			return VaxeeDeviceInfo{}, err
+		}
+		if d.FeatureLen == 0 {
+			if n, err := featureLenFor(ctx, d, opts.bufferReportID()); err == nil {
+				d.FeatureLen = uint16(n)
+			}
+		}
+		return d, nil
+	}
+
+	// safe_mode / ignore_path / serial / container_id：先剔除不允许碰的集合
+	ds = filterControlCandidates(ds, opts)
+
+	// control_path：用户已知哪个集合可用，直接用，不做任何探测
+	if d, ok := pinnedControl(ds, opts); ok {
+		return d, nil
+	}
+	if opts.ControlPath != "" {
+		log.Printf("[DEV] control_path(%s) 未找到，回退到自动探测。", opts.ControlPath)
+	}
+	// control_usage_page/control_usage：按 caps 里的用途固定，比路径更不容易随重启变化
+	if d, ok := pinnedUsage(ds, opts); ok {
+		return d, nil
+	}
+	if opts.ControlUsagePage != 0 {
+		log.Printf("[DEV] control_usage_page=0x%04x control_usage=0x%04x 没有匹配的集合，回退到自动探测。",
+			opts.ControlUsagePage, opts.ControlUsage)
 	}
 
 	// 先把 \kbd 的放后面（避免先撞键盘集合）
 	order := make([]VaxeeDeviceInfo, 0, len(ds))
 	for _, d := range ds {
-		if strings.HasSuffix(strings.ToLower(d.Path), `\kbd`) {
+		if isKbdPath(d.Path) {
 			continue
 		}
 		order = append(order, d)
 	}
 	for _, d := range ds {
-		if strings.HasSuffix(strings.ToLower(d.Path), `\kbd`) {
+		if isKbdPath(d.Path) {
 			order = append(order, d)
 		}
 	}
 
-	// 逐个探测
+	// 逐个探测；rewrite/verify 模式收集所有可用集合，之后再逐个确认
+	var accepted []VaxeeDeviceInfo
 	for _, d := range order {
-		flen := int(d.FeatureLen)
-		// 如果 caps 取不到，就先用 64 试探（你的抓包 wLength=64）[9](https://blog.csdn.net/frederick_master/article/details/78845161)
-		if flen <= 0 {
-			flen = 64
+		// caps 取不到长度时 featureLenFor 会尝试几个候选长度[9](https://blog.csdn.net/frederick_master/article/details/78845161)
+		var e error
+		if d.FeatureLen > 0 {
+			var resp []byte
+			resp, e = getFeature(ctx, d.Path, opts.bufferReportID(), int(d.FeatureLen))
+			debugFeatureProbe(d.Path, int(d.FeatureLen), resp, e)
+		} else {
+			var flen int
+			flen, e = featureLenFor(ctx, d, opts.bufferReportID())
+			d.FeatureLen = uint16(flen) // 后续读写都按探测出的长度
 		}
+		if ctx.Err() != nil {
+			return VaxeeDeviceInfo{}, ctx.Err()
+		}
+		if e != nil {
+			continue
+		}
+		flen := int(d.FeatureLen)
 
-		_, e := getFeature(d.Path, 0x0e, flen)
-		if e == nil {
-			// 找到了可用控制通道
+		// 找到了可用控制通道；配置了型号过滤时再读一次设备信息
+		if opts.Model != "" {
+			d.Model, d.Firmware, _ = ReadDeviceInfo(ctx, d.Path, opts, flen)
+			if !modelMatches(d, opts) {
+				continue
+			}
+		}
+		if opts.ControlSelect == controlSelectFirst {
 			return d, nil
 		}
+		accepted = append(accepted, d)
+	}
+	if len(accepted) > 0 {
+		return verifiedControl(ctx, accepted, opts), nil
+	}
+
+	return VaxeeDeviceInfo{}, fmt.Errorf("no VAXEE top-level collection accepts Feature ReportID=0x%02x", opts.bufferReportID())
+}
+
+// control_select=rewrite/verify 的结果：每次运行只确认一次
+var (
+	verifiedMu   sync.Mutex
+	verifiedPath string
+)
+
+// verifiedControl 在接受 ReportID 的集合里确认真正的控制通道：
+// rewrite 读出当前性能模式再原样写回，写入成功即是（不改变设备状态）；
+// verify 临时改回报率并读回（会短暂改变设置）。
+// 已确认过且仍在列表中则直接用；只有一个候选不必确认；都确认不了时退回第一个（原有探测结果）
+func verifiedControl(ctx context.Context, ds []VaxeeDeviceInfo, opts DeviceOptions) VaxeeDeviceInfo {
+	if len(ds) == 1 {
+		return ds[0]
+	}
+	verifiedMu.Lock()
+	defer verifiedMu.Unlock()
+	for _, d := range ds {
+		if d.Path == verifiedPath {
+			return d
+		}
+	}
+
+	check, how, notIt := rewriteControl, "读出当前值并原样写回", "写回当前值失败"
+	if opts.ControlSelect == controlSelectVerify {
+		check, how, notIt = verifyControl, "写入并读回", "写入后读回未变化"
+	}
+	log.Printf("[DEV] %d 个集合都接受 ReportID=0x%02x，逐个%s确认控制通道……", len(ds), opts.bufferReportID(), how)
+	for _, d := range ds {
+		ok, err := check(ctx, d, opts)
+		switch {
+		case err != nil:
+			log.Printf("[DEV]   无法确认 %s：%v", d.Path, err)
+		case ok:
+			log.Printf("[DEV]   已确认控制通道：%s", d.Path)
+			verifiedPath = d.Path
+			return d
+		default:
+			log.Printf("[DEV]   %s，不是控制通道：%s", notIt, d.Path)
+		}
+	}
+	log.Printf("[DEV] 未能确认控制通道，使用第一个可用集合：%s", ds[0].Path)
+	verifiedPath = ds[0].Path
+	return ds[0]
+}
+
+// rewriteControl 读出当前性能模式字节并原样写回，SetFeature 成功即视为控制通道。
+// 返回 error 表示读不出当前值（无法判断）。
+func rewriteControl(ctx context.Context, d VaxeeDeviceInfo, opts DeviceOptions) (bool, error) {
+	flen := int(d.FeatureLen)
+	data, err := readSetting(ctx, d.Path, opts.bufferReportID(), flen, opts.Cmd.Perf)
+	if err != nil {
+		return false, err
+	}
+	if len(data) < 1 {
+		return false, fmt.Errorf("empty response for cmd 0x%02x", opts.Cmd.Perf)
+	}
+	if err := sleepCtx(ctx, opts.ReportGap); err != nil {
+		return false, err
+	}
+	return sendFeatureReport(ctx, d.Path, buildReportSized(flen, opts.bufferReportID(), opts.Cmd.Perf, data[0])) == nil, nil
+}
+
+// verifyControl 临时把回报率改成另一个值，读回确认后立即恢复。
+// 返回 error 表示读回不可用（无法判断）。
+func verifyControl(ctx context.Context, d VaxeeDeviceInfo, opts DeviceOptions) (bool, error) {
+	flen := int(d.FeatureLen)
+	orig, err := ReadCurrentSettings(ctx, d.Path, opts, flen)
+	if err != nil {
+		return false, err
+	}
+	test := Poll1000
+	if orig.Poll == Poll1000 {
+		test = Poll2000
+	}
+	yy, _ := pollingToYY(test)
+	origYY, _ := pollingToYY(orig.Poll)
+	if err := sendFeatureReport(ctx, d.Path, buildReportSized(flen, opts.bufferReportID(), opts.Cmd.Poll, yy)); err != nil {
+		return false, nil
+	}
+	// 不管结果如何都恢复原值
+	defer func() {
+		sleepCtx(ctx, opts.ReportGap)
+		if err := sendFeatureReport(ctx, d.Path, buildReportSized(flen, opts.bufferReportID(), opts.Cmd.Poll, origYY)); err != nil {
+			log.Printf("[DEV]   恢复回报率 %dHz 失败：%v", orig.Poll, err)
+		}
+	}()
+	if err := sleepCtx(ctx, opts.ReportGap); err != nil {
+		return false, err
+	}
+	got, err := ReadCurrentSettings(ctx, d.Path, opts, flen)
+	if err != nil {
+		return false, err
+	}
+	return got.Poll == test, nil
+}
+
+// readSetting 发读请求并取回某个命令的数据段（推测协议，见 protocol.go）
+func readSetting(ctx context.Context, path string, reportID byte, flen int, cmd byte) ([]byte, error) {
+	if err := sendFeatureReport(ctx, path, buildReadRequest(flen, reportID, cmd)); err != nil {
+		return nil, err
+	}
+	resp, err := getFeature(ctx, path, reportID, flen)
+	if err != nil {
+		return nil, err
+	}
+	return parseReadResponse(resp, reportID, cmd)
+}
+
+// ReadDeviceInfo 读取型号/固件版本；固件不支持时返回错误，调用方留空即可
+func ReadDeviceInfo(ctx context.Context, path string, opts DeviceOptions, flen int) (model, firmware string, err error) {
+	if flen <= 0 {
+		flen = 64
+	}
+	data, err := readSetting(ctx, path, opts.bufferReportID(), flen, cmdDeviceInfo)
+	if err != nil {
+		return "", "", err
+	}
+	model, firmware = parseDeviceInfo(data)
+	return model, firmware, nil
+}
+
+// ReadCapabilities 读取设备自报的能力；固件不支持时返回错误
+func ReadCapabilities(ctx context.Context, path string, opts DeviceOptions, flen int) (Capabilities, error) {
+	if flen <= 0 {
+		flen = 64
+	}
+	data, err := readSetting(ctx, path, opts.bufferReportID(), flen, cmdCapabilities)
+	if err != nil {
+		return Capabilities{}, err
+	}
+	return parseCapabilities(data)
+}
+
+// ReadCurrentSettings 读回设备当前的性能模式、回报率与消抖（推测协议：对 cmd_perf/cmd_poll/cmd_debounce 发读请求）；
+// motion_sync_report=true 时再读 cmd_motion_sync 合成完整模式。固件不支持时返回错误。
+func ReadCurrentSettings(ctx context.Context, path string, opts DeviceOptions, flen int) (Profile, error) {
+	if flen <= 0 {
+		flen = 64
+	}
+	read1 := func(cmd byte) (byte, error) {
+		data, err := readSetting(ctx, path, opts.bufferReportID(), flen, cmd)
+		if err != nil {
+			return 0, err
+		}
+		if len(data) < 1 {
+			return 0, fmt.Errorf("empty response for cmd 0x%02x", cmd)
+		}
+		return data[0], nil
+	}
+
+	pb, err := read1(opts.Cmd.Perf)
+	if err != nil {
+		return Profile{}, err
+	}
+	perf := PerfMode(pb)
+	if opts.MotionSyncReport {
+		ms, err := read1(opts.Cmd.MotionSync)
+		if err != nil {
+			return Profile{}, err
+		}
+		perf = withMotionSync(perf, ms == motionSyncOnVal)
+	}
+	if perf < PerfCompetitiveMSOff || perf > PerfStandardMSOn {
+		return Profile{}, fmt.Errorf("unknown perf mode value: 0x%02x", pb)
+	}
+
+	yy, err := read1(opts.Cmd.Poll)
+	if err != nil {
+		return Profile{}, err
+	}
+	poll, err := yyToPolling(yy)
+	if err != nil {
+		return Profile{}, err
 	}
 
-	return VaxeeDeviceInfo{}, fmt.Errorf("no VAXEE top-level collection accepts Feature ReportID=0x0e")
+	// 消抖读不到（固件不支持该读命令）时留 0，校验时跳过
+	debounce := 0
+	if b, err := read1(opts.Cmd.Debounce); err == nil {
+		debounce = int(b)
+	}
+	return Profile{Perf: perf, Poll: poll, Debounce: debounce}, nil
 }
 
-func FindOneVaxeeDevice() (VaxeeDeviceInfo, error) {
-	return SelectVaxeeControlPath()
+// ReadActiveProfileSlot 读取当前生效的板载配置槽位（推测协议：cmd_slot 读请求，值从 1 开始）；
+// 固件不支持时返回错误
+func ReadActiveProfileSlot(ctx context.Context, path string, opts DeviceOptions, flen int) (int, error) {
+	if flen <= 0 {
+		flen = 64
+	}
+	data, err := readSetting(ctx, path, opts.bufferReportID(), flen, opts.Cmd.Slot)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) < 1 {
+		return 0, fmt.Errorf("empty response for cmd 0x%02x", opts.Cmd.Slot)
+	}
+	return int(data[0]), nil
+}
+
+// SelectProfileSlot 切换到指定板载配置槽位（推测协议：cmd_slot 写报告）
+func SelectProfileSlot(ctx context.Context, path string, opts DeviceOptions, flen int, slot int) error {
+	if slot < 1 || slot > maxProfileSlot {
+		return fmt.Errorf("invalid profile slot: %d", slot)
+	}
+	if flen <= 0 {
+		flen = 64
+	}
+	if err := writeFeatureReport(ctx, path, buildReportSized(flen, opts.bufferReportID(), opts.Cmd.Slot, byte(slot))); err != nil {
+		return fmt.Errorf("slot feature report failed: %w", err)
+	}
+	return nil
+}
+
+// errNoVaxeeDevice 枚举结果里一个 VAXEE 集合都没有（拔出或 USB 瞬断）
+var errNoVaxeeDevice = errors.New("no VAXEE HID device found")
+
+// FindOneVaxeeDevice 找控制通道；一个设备都没枚举到时隔 enumRetryDelay 重试 opts.EnumRetries 次，
+// 避免 USB 瞬断让这一轮直接报“未找到设备”。重试后仍没有则视为已拔出，清掉已确认的控制通道
+func FindOneVaxeeDevice(ctx context.Context, opts DeviceOptions) (VaxeeDeviceInfo, error) {
+	d, err := SelectVaxeeControlPath(ctx, opts)
+	for i := 0; i < opts.EnumRetries && errors.Is(err, errNoVaxeeDevice); i++ {
+		debugf("枚举不到 VAXEE 设备，%s 后重试（%d/%d）", enumRetryDelay, i+1, opts.EnumRetries)
+		if serr := sleepCtx(ctx, enumRetryDelay); serr != nil {
+			return VaxeeDeviceInfo{}, serr
+		}
+		d, err = SelectVaxeeControlPath(ctx, opts)
+	}
+	if errors.Is(err, errNoVaxeeDevice) {
+		verifiedMu.Lock()
+		verifiedPath = ""
+		verifiedMu.Unlock()
+	}
+	return d, err
 }
 
 // 应用设置：按 caps.FeatureLen 发送，避免长度不匹配[1](https://learn.microsoft.com/en-us/windows-hardware/drivers/ddi/hidsdi/nf-hidsdi-hidd_setfeature)[2](https://learn.microsoft.com/zh-tw/windows-hardware/drivers/ddi/hidpi/ns-hidpi-_hidp_caps)
-func ApplyVaxeeSetting(path string, perf PerfMode, poll PollingRate) error {
+// 报告顺序见 settingReports；p 中为 0 的项表示已生效、本次跳过（见 Applied.pending）。
+// 失败时返回 *ApplyError，说明哪些设置已经生效。
+func ApplyVaxeeSetting(ctx context.Context, opts DeviceOptions, path string, p Profile) error {
 	// 重新查一次当前控制通道 caps（保证 feature length 正确）
-	dev, err := FindOneVaxeeDevice()
+	dev, err := FindOneVaxeeDevice(ctx, opts)
 	if err == nil && dev.Path != "" {
 		path = dev.Path
 	}
 	flen := int(dev.FeatureLen)
 	if flen <= 0 {
-		flen = 64
+		// 没找到设备时只有不限 VID 的 feature_length 适用
+		if flen = opts.featureLenOverride(0, 0); flen == 0 {
+			flen = 64
+		}
 	}
 
-	// 1) 性能模式 cmd=0x08
-	if err := sendFeatureReport(path, buildReportSized(flen, 0x08, byte(perf))); err != nil {
-		return fmt.Errorf("perf feature report failed: %w", err)
+	// 板载槽位：一条切换命令代替逐项下发
+	if p.Slot != 0 {
+		return SelectProfileSlot(ctx, path, opts, flen, p.Slot)
 	}
-	time.Sleep(25 * time.Millisecond)
 
-	// 2) 回报率 cmd=0x07
-	yy, err := pollingToYY(poll)
+	// 先做映射校验，避免性能模式已发出、回报率却因映射失败半途而废
+	reports, err := settingReports(opts, p)
 	if err != nil {
 		return err
 	}
-	if err := sendFeatureReport(path, buildReportSized(flen, 0x07, yy)); err != nil {
-		return fmt.Errorf("poll feature report failed: %w", err)
+
+	var done [fieldOther]bool // perf / poll 是否已完整生效
+	// 某一项的最后一条报告发完，这一项才算生效（性能模式要等 Motion Sync 也发完）
+	markSent := func(i int) {
+		f := reports[i].field
+		if f >= fieldOther {
+			return
+		}
+		for _, r := range reports[i+1:] {
+			if r.field == f {
+				return
+			}
+		}
+		done[f] = true
+	}
+	fail := func(err error) error {
+		return &ApplyError{PerfApplied: done[fieldPerf], PollApplied: done[fieldPoll], Err: err}
+	}
+
+	sentAny := false
+
+	// 0) 组合报告：一条报告同时设置性能模式和回报率，要么都生效要么都不生效（只补发一项时不用）
+	if opts.CombinedReport && !combinedSupport.rejected(path) &&
+		(p.Perf != 0 || p.PerfByte.Set()) && (p.Poll != 0 || p.PollByte.Set()) {
+		var perfByte, yy byte
+		rest := reports[:0:0]
+		for _, r := range reports {
+			// 按报告种类区分，不比较命令字节：cmd_* 覆盖后 Motion Sync 的命令可能与性能模式相同
+			switch {
+			case r.isPerfByte():
+				perfByte = r.val
+			case r.field == fieldPoll:
+				yy = r.val
+			default:
+				rest = append(rest, r)
+			}
+		}
+		cerr := writeFeatureReport(ctx, path, buildCombinedReport(flen, opts.bufferReportID(), opts.Cmd.Combined, perfByte, yy))
+		switch {
+		case cerr == nil:
+			sentAny = true
+			reports = rest
+			done[fieldPoll] = true
+			done[fieldPerf] = true
+			for _, r := range rest {
+				if r.field == fieldPerf {
+					done[fieldPerf] = false // 还差 Motion Sync
+				}
+			}
+		case ctx.Err() != nil:
+			return &ApplyError{Err: ctx.Err()}
+		default:
+			combinedSupport.reject(path, cerr)
+		}
+	}
+
+	// 1) 按顺序逐条发送，相邻两条之间留 report_gap_ms
+	for i, r := range reports {
+		if r.field == fieldLED && ledSupport.rejected(path) {
+			continue
+		}
+		if sentAny {
+			if err := sleepCtx(ctx, opts.ReportGap); err != nil {
+				return fail(err)
+			}
+		}
+		sentAny = true
+		buf := buildReportSized(flen, opts.bufferReportID(), r.cmd, r.val)
+		if r.raw != nil {
+			var err error
+			if buf, err = buildRawReport(flen, r.raw); err != nil {
+				return fail(fmt.Errorf("%s: %w", r.name, err))
+			}
+		}
+		if err := writeFeatureReport(ctx, path, buf); err != nil {
+			if r.field == fieldLED && ctx.Err() == nil {
+				ledSupport.reject(path, err)
+				continue
+			}
+			return fail(fmt.Errorf("%s feature report failed: %w", r.name, err))
+		}
+		markSent(i)
 	}
 	return nil
 }
 
 // EnumerateAllHidDevices 枚举所有 HID 顶级集合（能读到 attributes/字符串的接口）
 // 用于：启动时找不到 VAXEE 时打印一次全量设备信息（便于定位识别规则）。
-func EnumerateAllHidDevices() ([]VaxeeDeviceInfo, error) {
+func EnumerateAllHidDevices(ctx context.Context) ([]VaxeeDeviceInfo, error) {
+	var out []VaxeeDeviceInfo
+	err := EnumerateAllHidDevicesFunc(ctx, func(info VaxeeDeviceInfo) bool {
+		out = append(out, info)
+		return true
+	})
+	return out, err
+}
+
+// deviceContainerID 读取设备节点的容器 ID；系统不提供（旧系统、虚拟设备）时返回空
+func deviceContainerID(hDevInfo uintptr, devData *SP_DEVINFO_DATA) string {
+	var g GUID
+	var propType, required uint32
+	r, _, _ := procSetupDiGetDevicePropertyW_HID.Call(
+		hDevInfo,
+		uintptr(unsafe.Pointer(devData)),
+		uintptr(unsafe.Pointer(&DEVPKEY_Device_ContainerId)),
+		uintptr(unsafe.Pointer(&propType)),
+		uintptr(unsafe.Pointer(&g)),
+		unsafe.Sizeof(g),
+		uintptr(unsafe.Pointer(&required)),
+		0,
+	)
+	if r == 0 || propType != DEVPROP_TYPE_GUID {
+		return ""
+	}
+	if id := g.String(); id != nullContainerID {
+		return id
+	}
+	return ""
+}
+
+// EnumerateAllHidDevicesFunc 逐个枚举 HID 顶级集合，每查到一个就回调一次；
+// 回调返回 false 时提前结束（不再打开后续设备）。整个枚举在设备线程上执行，回调里不能再访问设备。
+func EnumerateAllHidDevicesFunc(ctx context.Context, fn func(VaxeeDeviceInfo) bool) error {
+	return deviceThread.run(ctx, func() error {
+		return enumerateHidDevicesNow(ctx, fn)
+	})
+}
+
+func enumerateHidDevicesNow(ctx context.Context, fn func(VaxeeDeviceInfo) bool) error {
 	g := hidGuid()
 
 	hDevInfo, _, _ := procSetupDiGetClassDevsW_HID.Call(
@@ -485,12 +1122,15 @@ func EnumerateAllHidDevices() ([]VaxeeDeviceInfo, error) {
 		uintptr(DIGCF_PRESENT|DIGCF_DEVICEINTERFACE),
 	)
 	if hDevInfo == 0 || hDevInfo == uintptr(syscall.InvalidHandle) {
-		return nil, fmt.Errorf("SetupDiGetClassDevsW failed: %v", lastErrno())
+		return fmt.Errorf("SetupDiGetClassDevsW failed: %v", lastErrno())
 	}
 	defer procSetupDiDestroyDeviceInfoList_HID.Call(hDevInfo)
 
-	var out []VaxeeDeviceInfo
+	cbSize, pathOffset := detailLayout()
 	for idx := 0; ; idx++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		var ifData SP_DEVICE_INTERFACE_DATA
 		ifData.CbSize = uint32(unsafe.Sizeof(ifData))
 
@@ -521,31 +1161,39 @@ func EnumerateAllHidDevices() ([]VaxeeDeviceInfo, error) {
 		}
 
 		buf := make([]byte, required)
-		*(*uint32)(unsafe.Pointer(&buf[0])) = detailCbSizeW()
+		*(*uint32)(unsafe.Pointer(&buf[0])) = cbSize
 
+		var devData SP_DEVINFO_DATA
+		devData.CbSize = uint32(unsafe.Sizeof(devData))
 		r2, _, _ := procSetupDiGetDeviceInterfaceDetailW_HID.Call(
 			hDevInfo,
 			uintptr(unsafe.Pointer(&ifData)),
 			uintptr(unsafe.Pointer(&buf[0])),
 			uintptr(required),
 			uintptr(unsafe.Pointer(&required)),
-			0,
+			uintptr(unsafe.Pointer(&devData)),
 		)
 		if r2 == 0 {
 			continue
 		}
 
-		pathPtr := (*uint16)(unsafe.Pointer(uintptr(unsafe.Pointer(&buf[0])) + detailDevicePathOffset))
+		if pathOffset+2 > uintptr(len(buf)) {
+			continue
+		}
+		pathPtr := (*uint16)(unsafe.Pointer(&buf[pathOffset]))
 		path := utf16FromPtr(pathPtr)
 		if path == "" {
 			continue
 		}
 
-		info, ok := queryDeviceInfo(path)
+		info, ok := queryDeviceInfo(ctx, path)
 		if !ok {
 			continue
 		}
-		out = append(out, info)
+		info.ContainerID = deviceContainerID(hDevInfo, &devData)
+		if !fn(info) {
+			break
+		}
 	}
-	return out, nil
+	return nil
 }
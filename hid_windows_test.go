@@ -0,0 +1,38 @@
+//go:build windows
+
+package main
+
+import (
+	"slices"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestUTF16FromPtr(t *testing.T) {
+	for _, s := range []string{"", "VAXEE XE-S", "XE 😀 Wireless", "日本語 𝄞"} {
+		buf := append(utf16.Encode([]rune(s)), 0)
+		if got := utf16FromPtr(&buf[0]); got != s {
+			t.Errorf("utf16FromPtr(%q) = %q", s, got)
+		}
+	}
+	if got := utf16FromPtr(nil); got != "" {
+		t.Errorf("utf16FromPtr(nil) = %q", got)
+	}
+}
+
+func TestTrimLoneHighSurrogate(t *testing.T) {
+	emoji := utf16.Encode([]rune("a😀")) // a, 高代理, 低代理
+	cases := []struct {
+		in, want []uint16
+	}{
+		{emoji, emoji},
+		{emoji[:2], emoji[:1]}, // 截断在代理对中间
+		{emoji[:1], emoji[:1]},
+		{nil, nil},
+	}
+	for _, c := range cases {
+		if got := trimLoneHighSurrogate(c.in); !slices.Equal(got, c.want) {
+			t.Errorf("trimLoneHighSurrogate(%x) = %x, want %x", c.in, got, c.want)
+		}
+	}
+}
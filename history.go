@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// 切换历史（CSV，便于 Excel 打开），与普通日志分开存放
+var historyHeader = []string{"time", "process", "old_mode", "old_poll", "new_mode", "new_poll", "result"}
+
+// utf8BOM 让 Excel 按 UTF-8 识别中文错误信息
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+const defaultHistoryMaxLines = 10000
+
+// historyPath 相对路径按配置文件所在目录解析
+func historyPath(cfg *Config) string {
+	if cfg.HistoryFile == "" || filepath.IsAbs(cfg.HistoryFile) {
+		return cfg.HistoryFile
+	}
+	return filepath.Join(filepath.Dir(cfg.ConfigPath), cfg.HistoryFile)
+}
+
+// recordSwitch 追加一条切换记录；写失败只打日志，不影响切换本身
+func recordSwitch(cfg *Config, proc string, old Applied, want Profile, applyErr error) {
+	path := historyPath(cfg)
+	if path == "" {
+		return
+	}
+
+	oldMode, oldPoll := "", ""
+	if old.perfOK {
+		oldMode = perfName(old.perf)
+	}
+	if old.pollOK {
+		oldPoll = strconv.Itoa(int(old.poll))
+	}
+	result := "ok"
+	if applyErr != nil {
+		result = applyErr.Error()
+	}
+	row := []string{
+		time.Now().Format("2006-01-02 15:04:05"),
+		proc,
+		oldMode, oldPoll,
+		perfName(want.Perf), strconv.Itoa(int(want.Poll)),
+		result,
+	}
+
+	if err := appendHistoryRow(path, row, cfg.HistoryMaxLines); err != nil {
+		log.Printf("[HIST] 写入切换历史失败：%v", err)
+	}
+}
+
+func appendHistoryRow(path string, row []string, maxLines int) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if fi.Size() == 0 {
+		buf.Write(utf8BOM)
+		w.Write(historyHeader)
+	}
+	w.Write(row)
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if maxLines > 0 {
+		return capHistory(path, maxLines)
+	}
+	return nil
+}
+
+// capHistory 超过 maxLines 条记录时只保留最新的 maxLines 条（表头保留）
+func capHistory(path string, maxLines int) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var lines [][]byte
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		lines = append(lines, append([]byte(nil), sc.Bytes()...))
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	if len(lines)-1 <= maxLines {
+		return nil
+	}
+
+	var out bytes.Buffer
+	out.Write(lines[0]) // 表头（含 BOM）
+	out.WriteByte('\n')
+	for _, l := range lines[len(lines)-maxLines:] {
+		out.Write(l)
+		out.WriteByte('\n')
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, out.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("rotate history: %w", err)
+	}
+	return nil
+}
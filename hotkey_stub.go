@@ -0,0 +1,17 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+type HotkeyListener struct{}
+
+func StartHotkeys(keys []Hotkey) (*HotkeyListener, error) {
+	return nil, errors.New("global hotkeys are only supported on Windows")
+}
+
+func (l *HotkeyListener) Events() <-chan int {
+	return nil
+}
+
+func (l *HotkeyListener) Stop() {}
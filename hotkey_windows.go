@@ -0,0 +1,116 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	user32HK = syscall.NewLazyDLL("user32.dll")
+	k32HK    = syscall.NewLazyDLL("kernel32.dll")
+
+	procRegisterHotKey     = user32HK.NewProc("RegisterHotKey")
+	procUnregisterHotKey   = user32HK.NewProc("UnregisterHotKey")
+	procGetMessageW        = user32HK.NewProc("GetMessageW")
+	procPostThreadMessageW = user32HK.NewProc("PostThreadMessageW")
+	procGetCurrentThreadId = k32HK.NewProc("GetCurrentThreadId")
+)
+
+const (
+	WM_QUIT   = 0x0012
+	WM_HOTKEY = 0x0312
+
+	MOD_NOREPEAT = 0x4000
+)
+
+type MSG struct {
+	Hwnd    uintptr
+	Message uint32
+	WParam  uintptr
+	LParam  uintptr
+	Time    uint32
+	PtX     int32
+	PtY     int32
+	Private uint32
+}
+
+// HotkeyListener 在独立 OS 线程上跑消息循环：
+// RegisterHotKey(NULL, ...) 的 WM_HOTKEY 只会投递到注册它的线程。
+type HotkeyListener struct {
+	tid  uint32
+	c    chan int
+	done chan struct{}
+}
+
+// StartHotkeys 注册一组热键，按下第 i 个时向 Events() 发送 i
+func StartHotkeys(keys []Hotkey) (*HotkeyListener, error) {
+	l := &HotkeyListener{c: make(chan int, 4), done: make(chan struct{})}
+	ready := make(chan error, 1)
+
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		defer close(l.done)
+
+		tid, _, _ := procGetCurrentThreadId.Call()
+		l.tid = uint32(tid)
+
+		for i, k := range keys {
+			r1, _, e := procRegisterHotKey.Call(0, uintptr(i+1), uintptr(k.Mods|MOD_NOREPEAT), uintptr(k.VK))
+			if r1 == 0 {
+				for j := 0; j < i; j++ {
+					procUnregisterHotKey.Call(0, uintptr(j+1))
+				}
+				ready <- fmt.Errorf("RegisterHotKey(%s) failed: %v", k.Spec, e)
+				return
+			}
+		}
+		defer func() {
+			for i := range keys {
+				procUnregisterHotKey.Call(0, uintptr(i+1))
+			}
+		}()
+		ready <- nil
+
+		var msg MSG
+		for {
+			r1, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+			if int32(r1) <= 0 { // WM_QUIT 或出错
+				return
+			}
+			if msg.Message == WM_HOTKEY {
+				select {
+				case l.c <- int(msg.WParam) - 1:
+				default: // 主循环来不及处理就丢弃，避免阻塞消息循环
+				}
+			}
+		}
+	}()
+
+	if err := <-ready; err != nil {
+		<-l.done
+		return nil, err
+	}
+	return l, nil
+}
+
+// Events 热键事件；l 为 nil 时返回 nil channel（select 永不触发）
+func (l *HotkeyListener) Events() <-chan int {
+	if l == nil {
+		return nil
+	}
+	return l.c
+}
+
+// Stop 注销热键并结束消息循环
+func (l *HotkeyListener) Stop() {
+	if l == nil {
+		return
+	}
+	procPostThreadMessageW.Call(uintptr(l.tid), WM_QUIT, 0, 0)
+	<-l.done
+}
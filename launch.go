@@ -0,0 +1,112 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// ==================== 进程启动检测（watch_process_start） ====================
+// 白名单进程一启动（还在加载、尚未拿到前台）就提前切到命中配置。
+// 切回仍按前台判断：游戏拿到前台后回到正常逻辑；迟迟没拿到前台时 launchHoldMax 后失效。
+// 优先订阅 WMI 的 Win32_ProcessStartTrace 事件（见 launch_windows.go，需要管理员权限）；
+// 订阅不了时退回每 processStartPoll 对比一次进程快照。
+
+const (
+	processStartPoll = time.Second
+	launchHoldMax    = 3 * time.Minute
+)
+
+// ProcessStartWatcher 报告新出现的进程名（已 normalizeProcName），是否在白名单里由主循环判断
+type ProcessStartWatcher struct {
+	c    chan string
+	stop chan struct{}
+	done chan struct{}
+}
+
+func StartProcessStartWatcher() (*ProcessStartWatcher, error) {
+	w := &ProcessStartWatcher{c: make(chan string, 16), stop: make(chan struct{}), done: make(chan struct{})}
+	err := startProcessStartTrace(w)
+	if err == nil {
+		log.Printf("[LAUNCH] 已订阅进程启动事件（WMI Win32_ProcessStartTrace）。")
+		return w, nil
+	}
+	log.Printf("[LAUNCH] 无法订阅进程启动事件（%v；通常需要以管理员运行），改为每 %s 对比一次进程列表。", err, processStartPoll)
+	prev, err := RunningProcessNames()
+	if err != nil {
+		return nil, err
+	}
+	go w.poll(prev)
+	return w, nil
+}
+
+// emit 转发一个新进程名；主循环忙时丢弃，前台检测兜底
+func (w *ProcessStartWatcher) emit(name string) {
+	select {
+	case w.c <- normalizeProcName(name):
+	default:
+	}
+}
+
+// poll 订阅不了事件时的退路：定时对比进程快照
+func (w *ProcessStartWatcher) poll(prev map[string]struct{}) {
+	defer close(w.done)
+	t := time.NewTicker(processStartPoll)
+	defer t.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-t.C:
+		}
+		cur, err := RunningProcessNames()
+		if err != nil {
+			continue
+		}
+		for name := range cur {
+			if _, ok := prev[name]; !ok {
+				w.emit(name)
+			}
+		}
+		prev = cur
+	}
+}
+
+// Events 新进程名；w 为 nil（未启用）时返回 nil，select 中永远不就绪
+func (w *ProcessStartWatcher) Events() <-chan string {
+	if w == nil {
+		return nil
+	}
+	return w.c
+}
+
+func (w *ProcessStartWatcher) Stop() {
+	if w == nil {
+		return
+	}
+	close(w.stop)
+	<-w.done
+}
+
+// LaunchHold 提前切换期间按刚启动的游戏决策，直到它拿到前台或超时
+type LaunchHold struct {
+	proc  string
+	until time.Time
+}
+
+var launchHold LaunchHold
+
+func (h *LaunchHold) start(proc string) {
+	h.proc, h.until = proc, time.Now().Add(launchHoldMax)
+}
+
+// target 决策用的进程名：提前切换中且游戏还没拿到前台时返回游戏进程名，否则返回前台 fg
+func (h *LaunchHold) target(fg string) (string, bool) {
+	if h.proc == "" {
+		return fg, false
+	}
+	if fg == h.proc || time.Now().After(h.until) {
+		h.proc = ""
+		return fg, false
+	}
+	return h.proc, true
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+func startProcessStartTrace(w *ProcessStartWatcher) error {
+	return errors.New("process start events are only supported on Windows")
+}
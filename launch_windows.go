@@ -0,0 +1,208 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// ==================== WMI 进程启动事件（Win32_ProcessStartTrace） ====================
+// 直接走 COM vtable 调用 WMI：IWbemLocator -> IWbemServices.ExecNotificationQuery -> IEnumWbemClassObject.Next。
+// Win32_ProcessStartTrace 由内核事件驱动（不是 WMI 内部轮询），但需要管理员权限；订阅失败时调用方退回轮询。
+
+var (
+	ole32LW    = syscall.NewLazyDLL("ole32.dll")
+	oleaut32LW = syscall.NewLazyDLL("oleaut32.dll")
+
+	procCoInitializeEx       = ole32LW.NewProc("CoInitializeEx")
+	procCoUninitialize       = ole32LW.NewProc("CoUninitialize")
+	procCoInitializeSecurity = ole32LW.NewProc("CoInitializeSecurity")
+	procCoCreateInstance     = ole32LW.NewProc("CoCreateInstance")
+	procCoSetProxyBlanket    = ole32LW.NewProc("CoSetProxyBlanket")
+	procSysAllocString       = oleaut32LW.NewProc("SysAllocString")
+	procSysFreeString        = oleaut32LW.NewProc("SysFreeString")
+	procVariantClear         = oleaut32LW.NewProc("VariantClear")
+)
+
+const (
+	COINIT_MULTITHREADED        = 0x0
+	CLSCTX_INPROC_SERVER        = 0x1
+	RPC_C_AUTHN_WINNT           = 10
+	RPC_C_AUTHZ_NONE            = 0
+	RPC_C_AUTHN_LEVEL_DEFAULT   = 0
+	RPC_C_AUTHN_LEVEL_CALL      = 3
+	RPC_C_IMP_LEVEL_IMPERSONATE = 3
+	EOAC_NONE                   = 0
+
+	WBEM_FLAG_RETURN_IMMEDIATELY = 0x10
+	WBEM_FLAG_FORWARD_ONLY       = 0x20
+	WBEM_S_TIMEDOUT              = 0x40004
+
+	RPC_E_TOO_LATE      = 0x80010119 // 本进程已初始化过 COM 安全设置
+	VT_BSTR             = 8
+	processTraceTimeout = 500 // IEnumWbemClassObject.Next 的等待（毫秒），到时检查是否要退出
+)
+
+// vtable 下标（按 wbemcli.h 中的方法顺序）
+const (
+	vtRelease                     = 2
+	vtLocatorConnectServer        = 3
+	vtServicesExecNotificationQry = 22
+	vtEnumNext                    = 4
+	vtClassObjectGet              = 4
+)
+
+var (
+	CLSID_WbemLocator = syscall.GUID{Data1: 0x4590f811, Data2: 0x1d3a, Data3: 0x11d0, Data4: [8]byte{0x89, 0x1f, 0x00, 0xaa, 0x00, 0x4b, 0x2e, 0x24}}
+	IID_IWbemLocator  = syscall.GUID{Data1: 0xdc12a687, Data2: 0x737f, Data3: 0x11cf, Data4: [8]byte{0x88, 0x4d, 0x00, 0xaa, 0x00, 0x4b, 0x2e, 0x24}}
+)
+
+// comObject 任意 COM 接口指针：首个字段指向 vtable
+type comObject struct {
+	vtbl *[32]uintptr
+}
+
+func (o *comObject) call(method int, args ...uintptr) uint32 {
+	r, _, _ := syscall.SyscallN(o.vtbl[method], append([]uintptr{uintptr(unsafe.Pointer(o))}, args...)...)
+	return uint32(r)
+}
+
+func (o *comObject) release() {
+	if o != nil {
+		o.call(vtRelease)
+	}
+}
+
+// VARIANT（只用到 BSTR）：32 位 16 字节，64 位 24 字节
+type VARIANT struct {
+	VT   uint16
+	_    [3]uint16
+	BStr *uint16
+	_    uintptr
+}
+
+func hresultFailed(hr uint32) bool { return int32(hr) < 0 }
+
+func sysAllocString(s string) uintptr {
+	p, _ := syscall.UTF16PtrFromString(s)
+	r, _, _ := procSysAllocString.Call(uintptr(unsafe.Pointer(p)))
+	return r
+}
+
+// processStartTrace 一个 Win32_ProcessStartTrace 订阅；只能在创建它的（已锁定的）线程上使用
+type processStartTrace struct {
+	loc, svc, enum *comObject
+}
+
+func openProcessStartTrace() (t *processStartTrace, err error) {
+	hr, _, _ := procCoInitializeEx.Call(0, COINIT_MULTITHREADED)
+	if hresultFailed(uint32(hr)) {
+		return nil, fmt.Errorf("CoInitializeEx: 0x%08x", uint32(hr))
+	}
+	t = &processStartTrace{}
+	defer func() {
+		if err != nil {
+			t.close()
+		}
+	}()
+
+	hr, _, _ = procCoInitializeSecurity.Call(0, ^uintptr(0), 0, 0, RPC_C_AUTHN_LEVEL_DEFAULT, RPC_C_IMP_LEVEL_IMPERSONATE, 0, EOAC_NONE, 0)
+	if hresultFailed(uint32(hr)) && uint32(hr) != RPC_E_TOO_LATE {
+		return nil, fmt.Errorf("CoInitializeSecurity: 0x%08x", uint32(hr))
+	}
+	hr, _, _ = procCoCreateInstance.Call(uintptr(unsafe.Pointer(&CLSID_WbemLocator)), 0, CLSCTX_INPROC_SERVER,
+		uintptr(unsafe.Pointer(&IID_IWbemLocator)), uintptr(unsafe.Pointer(&t.loc)))
+	if hresultFailed(uint32(hr)) {
+		return nil, fmt.Errorf("CoCreateInstance(WbemLocator): 0x%08x", uint32(hr))
+	}
+
+	ns := sysAllocString(`ROOT\CIMV2`)
+	defer procSysFreeString.Call(ns)
+	if hr := t.loc.call(vtLocatorConnectServer, ns, 0, 0, 0, 0, 0, 0, uintptr(unsafe.Pointer(&t.svc))); hresultFailed(hr) {
+		return nil, fmt.Errorf("IWbemLocator.ConnectServer: 0x%08x", hr)
+	}
+	hr, _, _ = procCoSetProxyBlanket.Call(uintptr(unsafe.Pointer(t.svc)), RPC_C_AUTHN_WINNT, RPC_C_AUTHZ_NONE, 0,
+		RPC_C_AUTHN_LEVEL_CALL, RPC_C_IMP_LEVEL_IMPERSONATE, 0, EOAC_NONE)
+	if hresultFailed(uint32(hr)) {
+		return nil, fmt.Errorf("CoSetProxyBlanket: 0x%08x", uint32(hr))
+	}
+
+	lang, query := sysAllocString("WQL"), sysAllocString("SELECT ProcessName FROM Win32_ProcessStartTrace")
+	defer procSysFreeString.Call(lang)
+	defer procSysFreeString.Call(query)
+	if hr := t.svc.call(vtServicesExecNotificationQry, lang, query, WBEM_FLAG_RETURN_IMMEDIATELY|WBEM_FLAG_FORWARD_ONLY, 0,
+		uintptr(unsafe.Pointer(&t.enum))); hresultFailed(hr) {
+		return nil, fmt.Errorf("ExecNotificationQuery(Win32_ProcessStartTrace): 0x%08x", hr) // 0x80041003 = 需要管理员权限
+	}
+	return t, nil
+}
+
+// next 等待下一个进程启动事件；超时返回 ok=false
+func (t *processStartTrace) next() (name string, ok bool, err error) {
+	var obj *comObject
+	var n uint32
+	hr := t.enum.call(vtEnumNext, processTraceTimeout, 1, uintptr(unsafe.Pointer(&obj)), uintptr(unsafe.Pointer(&n)))
+	if hr == WBEM_S_TIMEDOUT || (!hresultFailed(hr) && n == 0) {
+		return "", false, nil
+	}
+	if hresultFailed(hr) {
+		return "", false, fmt.Errorf("IEnumWbemClassObject.Next: 0x%08x", hr)
+	}
+	defer obj.release()
+
+	prop, _ := syscall.UTF16PtrFromString("ProcessName")
+	var v VARIANT
+	if hr := obj.call(vtClassObjectGet, uintptr(unsafe.Pointer(prop)), 0, uintptr(unsafe.Pointer(&v)), 0, 0); hresultFailed(hr) {
+		return "", false, fmt.Errorf("IWbemClassObject.Get(ProcessName): 0x%08x", hr)
+	}
+	defer procVariantClear.Call(uintptr(unsafe.Pointer(&v)))
+	if v.VT != VT_BSTR || v.BStr == nil {
+		return "", false, nil
+	}
+	return utf16FromPtr(v.BStr), true, nil
+}
+
+func (t *processStartTrace) close() {
+	t.enum.release()
+	t.svc.release()
+	t.loc.release()
+	procCoUninitialize.Call()
+}
+
+// startProcessStartTrace 订阅进程启动事件并在专用线程上转发给 w；订阅失败时返回错误（w 不变）
+func startProcessStartTrace(w *ProcessStartWatcher) error {
+	ready := make(chan error, 1)
+	go func() {
+		// COM 初始化与接口指针都属于这个线程
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		t, err := openProcessStartTrace()
+		ready <- err
+		if err != nil {
+			return
+		}
+		defer close(w.done)
+		defer t.close()
+		for {
+			select {
+			case <-w.stop:
+				return
+			default:
+			}
+			name, ok, err := t.next()
+			if err != nil {
+				// 订阅断开（如 WMI 服务重启）：不再提前切换，前台检测照常
+				log.Printf("[WARN] 进程启动事件订阅已中断：%v", err)
+				return
+			}
+			if ok {
+				w.emit(name)
+			}
+		}
+	}()
+	return <-ready
+}
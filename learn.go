@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ==================== 学习模式（learn_mode） ====================
+// 不切换、不碰设备，只记录每个新出现的前台进程名，便于整理白名单。
+
+// learnPath 相对路径按配置文件所在目录解析（与 history_file 一致）
+func learnPath(cfg *Config) string {
+	if cfg.LearnFile == "" || filepath.IsAbs(cfg.LearnFile) {
+		return cfg.LearnFile
+	}
+	return filepath.Join(filepath.Dir(cfg.ConfigPath), cfg.LearnFile)
+}
+
+// Learner 已见过的进程名（去重）
+type Learner struct {
+	seen map[string]bool
+	file string // 已预读过的学习文件，换文件时重新预读
+}
+
+// observe 读一次前台进程，第一次见到时打日志并（可选）追加到学习文件
+func (l *Learner) observe(cfg *Config) {
+	proc, err := ForegroundProcessName()
+	if err != nil || proc == "" {
+		return
+	}
+	proc = normalizeProcName(proc)
+
+	path := learnPath(cfg)
+	if l.seen == nil || l.file != path {
+		l.seen = map[string]bool{}
+		l.file = path
+		l.preload(path)
+	}
+	if l.seen[proc] {
+		return
+	}
+	l.seen[proc] = true
+
+	note := ""
+	if rule, hit := matchWhitelist(cfg, proc, "", nil); hit {
+		note = fmt.Sprintf("（已命中白名单：%s）", rule)
+	}
+	log.Printf("[LEARN] 新的前台进程：%s%s", proc, note)
+
+	if path == "" {
+		return
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		log.Printf("[LEARN] 写入学习文件失败：%v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintf(f, "# %s\n%s\n", time.Now().Format("2006-01-02 15:04:05"), proc); err != nil {
+		log.Printf("[LEARN] 写入学习文件失败：%v", err)
+	}
+}
+
+// preload 把学习文件里已有的进程名算作见过，重启后不重复记录
+func (l *Learner) preload(path string) {
+	if path == "" {
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			l.seen[strings.ToLower(line)] = true
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ==================== 指示灯颜色（hit_led / default_led） ====================
+// 切换时顺带把鼠标指示灯设成该配置的颜色，作为实体反馈。
+// 报告格式未经验证（命令字节与数据布局都是猜的）：cmd_led(0x10) 写，数据长度 3，依次 R G B：
+//   [ReportID, 0xa5, 0x10, 0x02, 0x03, R, G, B]
+// 固件拒绝该报告时记下这个控制通道，之后不再发送（见 LEDSupport）；不配置 *_led 就不发送。
+
+// LEDColor 0xRRGGBB；0 = 未设置（黑色/熄灭本身也是合法值，所以用高位标记“已设置”）
+type LEDColor uint32
+
+const ledColorSet = 1 << 24
+
+// ledColorNames 常用颜色的名字
+var ledColorNames = map[string]uint32{
+	"off":    0x000000,
+	"red":    0xff0000,
+	"green":  0x00ff00,
+	"blue":   0x0000ff,
+	"yellow": 0xffff00,
+	"cyan":   0x00ffff,
+	"purple": 0xff00ff,
+	"white":  0xffffff,
+}
+
+// parseLEDColor 解析 "#ff8000" / "ff8000" / "red" / "off"
+func parseLEDColor(s string) (LEDColor, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if rgb, ok := ledColorNames[s]; ok {
+		return LEDColor(ledColorSet | rgb), nil
+	}
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) != 6 {
+		return 0, fmt.Errorf("want #rrggbb or a color name: %s", s)
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("want #rrggbb or a color name: %s", s)
+	}
+	return LEDColor(ledColorSet | uint32(n)), nil
+}
+
+func (c LEDColor) Set() bool { return c != 0 }
+
+// RGB 三个颜色分量
+func (c LEDColor) RGB() (r, g, b byte) {
+	return byte(c >> 16), byte(c >> 8), byte(c)
+}
+
+func (c LEDColor) String() string {
+	return fmt.Sprintf("#%06x", uint32(c)&0xffffff)
+}
+
+// LEDSupport 拒绝过指示灯报告的控制通道（按路径）。被拒绝一次后不再发送，
+// 否则记录里的指示灯永远对不上，每轮都会重发、每轮都失败
+type LEDSupport struct {
+	mu      sync.Mutex
+	refused map[string]bool
+}
+
+var ledSupport LEDSupport
+
+func (s *LEDSupport) rejected(path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refused[path]
+}
+
+func (s *LEDSupport) reject(path string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.refused == nil {
+		s.refused = map[string]bool{}
+	}
+	s.refused[path] = true
+	log.Printf("[WARN] 设备拒绝指示灯报告（%v），之后不再发送 hit_led/default_led：%s", err, path)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+)
+
+// ==================== 有线 / 无线（2.4G 接收器） ====================
+// 固件不报告连接方式，按枚举到的接口推断：PID 在 wireless_pid 列表里，或产品名是接收器（receiver/dongle）的视为无线。
+// 只在配置了 *_wireless 项时才每轮确认连接方式（需要枚举设备）。
+
+// wirelessProductHints 接收器的产品名里常见的字样（小写子串）。
+// 不含 "wireless"/"2.4g"：双模鼠标插线时产品名里也有这些字样
+var wirelessProductHints = []string{"receiver", "dongle"}
+
+// isWirelessLink 该接口是否来自 2.4G 接收器
+func isWirelessLink(d VaxeeDeviceInfo, opts DeviceOptions) bool {
+	if slices.Contains(opts.WirelessPIDs, d.PID) {
+		return true
+	}
+	p := strings.ToLower(d.Product)
+	for _, h := range wirelessProductHints {
+		if strings.Contains(p, h) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatPIDs wireless_pid 的写法："0x1234, 0x5678"
+func formatPIDs(pids []uint16) string {
+	parts := make([]string, len(pids))
+	for i, p := range pids {
+		parts[i] = fmt.Sprintf("0x%04x", p)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func linkName(wireless bool) string {
+	if wireless {
+		return "无线"
+	}
+	return "有线"
+}
+
+// LinkMode 最近一次确认到的连接方式（确认前按有线决策）
+type LinkMode struct {
+	known    bool
+	wireless bool
+}
+
+var linkMode LinkMode
+
+// observe 记录设备当前的连接方式；与上次决策所用的不同时返回 true（需要重新决策）
+func (l *LinkMode) observe(d VaxeeDeviceInfo, opts DeviceOptions) bool {
+	w := isWirelessLink(d, opts)
+	changed := w != l.wireless
+	if changed || !l.known {
+		log.Printf("[LINK] 连接方式：%s（PID=0x%04x %s）", linkName(w), d.PID, d.Product)
+	}
+	l.known, l.wireless = true, w
+	return changed
+}
+
+// hasWirelessVariants 是否配置了任何无线专用项
+func (c *Config) hasWirelessVariants() bool {
+	return c.HitPollWireless != 0 || c.DefaultPollWireless != 0 || c.MaxPollWireless != 0
+}
+
+// wirelessPoll 无线连接时替代的回报率（0 = 未配置，沿用有线的值）
+func (c *Config) wirelessPoll(hit bool) PollingRate {
+	if hit {
+		return c.HitPollWireless
+	}
+	return c.DefaultPollWireless
+}
+
+// linkCap 在电源状态的上限之上再叠加无线上限，取较低者（0 = 不限）
+func (c *Config) linkCap(battery, wireless bool) PollingRate {
+	limit := c.pollCap(battery)
+	if wireless && c.MaxPollWireless != 0 && (limit == 0 || c.MaxPollWireless < limit) {
+		return c.MaxPollWireless
+	}
+	return limit
+}
+
+// capPoll 回报率上限的唯一实现：把 p 的回报率压到当前上限以内（原始回报率字节不经映射，不受限制），
+// 返回是否压低过。Decide 用它得出决策，applyProfile 再兜底一次（手动覆盖、-reset、-soak 不经 Decide）
+func (c *Config) capPoll(p Profile, battery, wireless bool) (Profile, bool) {
+	limit := c.linkCap(battery, wireless)
+	if limit == 0 || p.PollByte.Set() || p.Poll <= limit {
+		return p, false
+	}
+	p.Poll = limit
+	return p, true
+}
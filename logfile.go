@@ -0,0 +1,157 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ==================== 按天的日志文件（log_file_daily） ====================
+// 日志同时写入配置目录下 logs\vaxee-2024-06-01.log，每天一个文件，过了本地零点的第一条日志换新文件；
+// 只保留最近 log_file_keep 个（0 = 全部保留），总大小不超过 log_max_total_mb（0 = 不限）；
+// 启动后第一条日志打开文件时、以及每次换文件时清理，删掉的文件记一条日志。
+
+const (
+	logFilePrefix  = "vaxee-"
+	logFileSuffix  = ".log"
+	logFileDate    = "2006-01-02"
+	defaultLogKeep = 14
+)
+
+// DailyLog 当天的日志文件；写失败不影响控制台输出
+type DailyLog struct {
+	mu       sync.Mutex
+	dir      string
+	keep     int
+	maxBytes int64
+	day      string
+	f        *os.File
+}
+
+// dailyLog 整个进程共用一个（重载配置时只更新目录和保留个数，不重开文件）
+var dailyLog *DailyLog
+
+// setDailyLog 按配置开启/关闭按天日志，返回当前的 writer（关闭时为 nil）
+func setDailyLog(o LogOptions) *DailyLog {
+	if !o.Daily {
+		if dailyLog != nil {
+			dailyLog.Close()
+			dailyLog = nil
+		}
+		return nil
+	}
+	if dailyLog == nil {
+		dailyLog = &DailyLog{}
+	}
+	dailyLog.mu.Lock()
+	if dailyLog.dir != o.Dir {
+		dailyLog.closeLocked() // 目录变了：下一条日志在新目录开文件
+	}
+	dailyLog.dir, dailyLog.keep, dailyLog.maxBytes = o.Dir, o.Keep, int64(o.MaxTotalMB)<<20
+	dailyLog.mu.Unlock()
+	return dailyLog
+}
+
+func (d *DailyLog) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if day := time.Now().Format(logFileDate); day != d.day || d.f == nil {
+		if err := d.rotateLocked(day); err != nil {
+			return len(p), nil // 打不开文件时只丢文件这一路，控制台照常输出
+		}
+	}
+	d.f.Write(p)
+	return len(p), nil
+}
+
+// rotateLocked 关闭旧文件，打开（追加）当天的文件，并清理超出保留个数的旧文件
+func (d *DailyLog) rotateLocked(day string) error {
+	d.closeLocked()
+	if err := os.MkdirAll(d.dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(d.dir, logFilePrefix+day+logFileSuffix), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	d.f, d.day = f, day
+	// 此时持有 d.mu（log 包也正在写这一条），清理结果的日志放到后台打
+	go pruneDailyLogs(d.dir, day, d.keep, d.maxBytes)
+	return nil
+}
+
+func (d *DailyLog) closeLocked() {
+	if d.f != nil {
+		d.f.Close()
+		d.f, d.day = nil, ""
+	}
+}
+
+func (d *DailyLog) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closeLocked()
+}
+
+// pruneDailyLogs 只保留日期最新的 keep 个 vaxee-YYYY-MM-DD.log，并从最旧的开始删到总大小不超过 maxBytes；
+// 当天（today）正在写的文件不删
+func pruneDailyLogs(dir, today string, keep int, maxBytes int64) {
+	if keep <= 0 && maxBytes <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Printf("[LOG] 清理旧日志失败：%v", err)
+		return
+	}
+	type logFile struct {
+		day  string
+		size int64
+	}
+	var files []logFile
+	var total int64
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, logFilePrefix) || !strings.HasSuffix(name, logFileSuffix) {
+			continue
+		}
+		day := strings.TrimSuffix(strings.TrimPrefix(name, logFilePrefix), logFileSuffix)
+		if _, err := time.Parse(logFileDate, day); err != nil {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, logFile{day, info.Size()})
+		total += info.Size()
+	}
+	// YYYY-MM-DD 按字符串排序即按日期，旧的在前
+	slices.SortFunc(files, func(a, b logFile) int { return strings.Compare(a.day, b.day) })
+
+	var removed []string
+	for len(files) > 0 && files[0].day != today &&
+		((keep > 0 && len(files) > keep) || (maxBytes > 0 && total > maxBytes)) {
+		name := logFilePrefix + files[0].day + logFileSuffix
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			log.Printf("[LOG] 删除旧日志 %s 失败：%v", name, err)
+			break
+		}
+		removed = append(removed, name)
+		total -= files[0].size
+		files = files[1:]
+	}
+	if len(removed) > 0 {
+		log.Printf("[LOG] 已清理 %d 个旧日志（保留 %d 个，共 %.1f MB）：%s",
+			len(removed), len(files), float64(total)/(1<<20), strings.Join(removed, ", "))
+	}
+}
+
+// dailyLogPath 当天日志文件的路径
+func dailyLogPath(o LogOptions) string {
+	return filepath.Join(o.Dir, logFilePrefix+time.Now().Format(logFileDate)+logFileSuffix)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LogOptions 日志格式相关选项
+type LogOptions struct {
+	Microseconds bool   // 时间戳精确到微秒（log.Lmicroseconds）
+	Uptime       bool   // 每行前加进程运行时长
+	UTC          bool   // 时间戳用 UTC（log.LUTC），默认本地时间
+	Verbose      bool   // 输出 [DBG] 调试日志
+	Format       string // text（默认）/ json，见 logjson.go
+	Daily        bool   // 同时写入按天的日志文件，见 logfile.go
+	Keep         int    // 按天日志保留的文件个数（0 = 全部保留）
+	MaxTotalMB   int    // 按天日志的总大小上限（MB，0 = 不限）
+	Dir          string // 按天日志的目录（配置文件目录下的 logs，由 loadConfig 填入）
+}
+
+// verboseLog 由 applyLogOptions 设置；设备层等拿不到配置的地方用 debugf 判断
+var verboseLog bool
+
+// debugf 仅在 log_verbose=true 时输出
+func debugf(format string, args ...any) {
+	if verboseLog {
+		log.Printf("[DBG] "+format, args...)
+	}
+}
+
+// quietLog 安静时段（quiet_hours）内为 true：只输出 [ERR] / [WARN] 行
+var quietLog atomic.Bool
+
+// quietFilter 安静时段内丢弃错误、警告以外的日志（log 包每条日志只调用一次 Write）
+type quietFilter struct {
+	w io.Writer
+}
+
+func (q quietFilter) Write(p []byte) (int, error) {
+	if quietLog.Load() && !bytes.Contains(p, []byte("[ERR]")) && !bytes.Contains(p, []byte("[WARN]")) {
+		return len(p), nil
+	}
+	return q.w.Write(p)
+}
+
+// processStart 用于计算运行时长（单调时钟）
+var processStart = time.Now()
+
+// uptimeWriter 在每条日志前加 [+秒数]
+type uptimeWriter struct {
+	w io.Writer
+}
+
+func (u uptimeWriter) Write(p []byte) (int, error) {
+	prefix := fmt.Sprintf("[+%.3fs] ", time.Since(processStart).Seconds())
+	if _, err := io.WriteString(u.w, prefix); err != nil {
+		return 0, err
+	}
+	return u.w.Write(p)
+}
+
+// applyLogOptions 按配置设置标准 log 包的格式与输出
+func applyLogOptions(o LogOptions) {
+	flags := log.LstdFlags
+	if o.Microseconds {
+		flags |= log.Lmicroseconds
+	}
+	if o.UTC {
+		flags |= log.LUTC
+	}
+	log.SetFlags(flags)
+	verboseLog = o.Verbose
+
+	var w io.Writer = os.Stderr
+	if f := setDailyLog(o); f != nil {
+		w = io.MultiWriter(w, f)
+	}
+	jsonLog = nil
+	if o.Format == logFormatJSON {
+		// 时间戳由 JSON 的 ts 字段给出，行内不再加前缀
+		log.SetFlags(0)
+		jsonLog = &jsonWriter{w: w, utc: o.UTC}
+		w = jsonLog
+	} else if o.Uptime {
+		w = uptimeWriter{w: w}
+	}
+	// 状态窗口的日志尾巴不带运行时长前缀（uptimeWriter 分两次写，放在外层会被拆成两行）
+	log.SetOutput(quietFilter{w: io.MultiWriter(w, &logTail)})
+}
+
+// logTailMax 状态窗口显示的最近日志行数
+const logTailMax = 50
+
+// LogTail 保留最近的若干行日志（给状态窗口显示）
+type LogTail struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+var logTail LogTail
+
+func (t *LogTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		t.lines = append(t.lines, line)
+	}
+	if n := len(t.lines); n > logTailMax {
+		t.lines = append(t.lines[:0], t.lines[n-logTailMax:]...)
+	}
+	return len(p), nil
+}
+
+// Lines 返回最近日志的副本（旧的在前）
+func (t *LogTail) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.lines...)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ==================== JSON 日志（log_format=json） ====================
+// 每条日志输出为一行 JSON，便于 Loki 等采集：
+//   {"ts":"...","level":"info","event":"switch","proc":"cs2.exe","mode":"competitive_ms_off","poll":2000,...,"msg":"..."}
+// 普通 log.Printf 按行首标签归类（[SWITCH] -> switch、[ERR] -> error、[WARN] -> warn、[CFG] -> config ...）；
+// 切换、心跳等关键事件走 logEvent，带上进程/模式/回报率/设备字段。
+
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// LogEvent 一条结构化日志
+type LogEvent struct {
+	TS     string `json:"ts"`
+	Level  string `json:"level"`
+	Event  string `json:"event"`
+	Proc   string `json:"proc,omitempty"`
+	Mode   string `json:"mode,omitempty"`
+	Poll   int    `json:"poll,omitempty"`
+	Device string `json:"device,omitempty"`
+	Err    string `json:"err,omitempty"`
+	Msg    string `json:"msg"`
+}
+
+// setProfile 填入模式/回报率字段（槽位、原始字节按 profileName 的写法）
+func (e *LogEvent) setProfile(p Profile) {
+	switch {
+	case p.Slot != 0:
+		e.Mode = profileName(p)
+	case p.PerfByte.Set():
+		e.Mode = "raw:" + p.PerfByte.String()
+	case p.Perf != 0:
+		e.Mode = perfName(p.Perf)
+	}
+	e.Poll = int(p.Poll)
+}
+
+// stateEvent 由运行状态生成一条带进程/配置/设备的事件
+func stateEvent(event, msg string, s StatusSnapshot) LogEvent {
+	e := LogEvent{Event: event, Proc: s.Proc, Device: s.Device, Msg: msg}
+	if s.AppliedOK {
+		e.setProfile(s.Applied)
+	}
+	return e
+}
+
+// jsonLog log_format=json 时非 nil（由 applyLogOptions 设置）
+var jsonLog *jsonWriter
+
+// jsonWriter 把 log 包输出的每行文本包装成 JSON；logEvent 也经它输出，共用一把锁避免交错
+type jsonWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	utc bool
+}
+
+func (j *jsonWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		if err := j.emit(classifyLine(line)); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (j *jsonWriter) emit(e LogEvent) error {
+	now := time.Now()
+	if j.utc {
+		now = now.UTC()
+	}
+	e.TS = now.Format(time.RFC3339Nano)
+	if e.Level == "" {
+		e.Level = "info"
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err = j.w.Write(append(b, '\n'))
+	return err
+}
+
+// classifyLine 按行首标签归类一行普通日志
+func classifyLine(line string) LogEvent {
+	e := LogEvent{Event: "log", Msg: line}
+	tag, rest, ok := strings.Cut(strings.TrimPrefix(line, "["), "] ")
+	if !strings.HasPrefix(line, "[") || !ok || strings.ContainsAny(tag, " []") {
+		return e
+	}
+	switch tag {
+	case "ERR":
+		e.Level, e.Event, e.Err = "error", "error", rest
+	case "WARN":
+		e.Level, e.Event = "warn", "warn"
+	case "DBG":
+		e.Level, e.Event = "debug", "debug"
+	case "ALIVE":
+		e.Event = "heartbeat"
+	default:
+		e.Event = strings.ToLower(tag)
+	}
+	return e
+}
+
+// logEvent 输出一条关键事件：text 格式下就是 log.Print(e.Msg)，json 格式下带上结构化字段
+func logEvent(e LogEvent) {
+	if jsonLog == nil {
+		log.Print(e.Msg)
+		return
+	}
+	if quietLog.Load() && e.Level != "error" && e.Level != "warn" {
+		return
+	}
+	if err := jsonLog.emit(e); err == nil {
+		logTail.Write([]byte(e.Msg + "\n"))
+	}
+}
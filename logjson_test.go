@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestClassifyLineLevels(t *testing.T) {
+	cases := []struct {
+		line, level, event string
+	}{
+		{"[ERR] 下发失败", "error", "error"},
+		{"[WARN] 读回设备设置失败", "warn", "warn"},
+		{"[DBG] x", "debug", "debug"},
+		{"[CFG] match_mode=exact", "", "cfg"},
+		{"普通一行", "", "log"},
+	}
+	for _, c := range cases {
+		e := classifyLine(c.line)
+		if e.Level != c.level || e.Event != c.event {
+			t.Errorf("classifyLine(%q) = level %q event %q, want %q %q", c.line, e.Level, e.Event, c.level, c.event)
+		}
+	}
+}
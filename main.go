@@ -1,73 +1,32 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
+	"math/rand/v2"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
-	"unsafe"
 )
 
-// Applied 记录当前应用的设置
+// Applied 记录当前应用的设置（各项分别记录是否已生效，见 pending / update）
 type Applied struct {
-	perf PerfMode
-	poll PollingRate
-	ok   bool
-}
-
-// Windows API 相关常量和变量
-var (
-	kernel32DLL = syscall.NewLazyDLL("kernel32.dll")
-
-	// Windows API 函数
-	procGetCurrentProcess     = kernel32DLL.NewProc("GetCurrentProcess")
-	procGetCurrentThread      = kernel32DLL.NewProc("GetCurrentThread")
-	procSetPriorityClass      = kernel32DLL.NewProc("SetPriorityClass")
-	procSetThreadPriority     = kernel32DLL.NewProc("SetThreadPriority")
-	procSetProcessInformation = kernel32DLL.NewProc("SetProcessInformation")
-	procSetThreadInformation  = kernel32DLL.NewProc("SetThreadInformation")
-)
-
-// Windows 优先级常量
-const (
-	// SetPriorityClass dwPriorityClass
-	IDLE_PRIORITY_CLASS           = 0x00000040
-	BELOW_NORMAL_PRIORITY_CLASS   = 0x00004000
-	PROCESS_MODE_BACKGROUND_BEGIN = 0x00100000
+	perf     PerfMode
+	poll     PollingRate
+	perfOK   bool
+	pollOK   bool
+	debounce int // 已确认的消抖值（0 = 未设置过/未知）
 
-	// SetThreadPriority nPriority
-	THREAD_PRIORITY_LOWEST       = -2
-	THREAD_PRIORITY_IDLE         = -15
-	THREAD_MODE_BACKGROUND_BEGIN = 0x00010000
+	perfByte, pollByte RawByte // 随 perf/poll 一起确认的原始字节
+	slot               int     // 已确认切换到的板载槽位（0 = 按参数下发/未知）
 
-	// SetProcessInformation ProcessInformationClass
-	ProcessPowerThrottling = 4
-
-	// SetThreadInformation ThreadInformationClass
-	ThreadPowerThrottling = 5
-
-	// PROCESS/THREAD_POWER_THROTTLING_STATE
-	PROCESS_POWER_THROTTLING_CURRENT_VERSION = 1
-	PROCESS_POWER_THROTTLING_EXECUTION_SPEED = 0x1
-
-	THREAD_POWER_THROTTLING_CURRENT_VERSION = 1
-	THREAD_POWER_THROTTLING_EXECUTION_SPEED = 0x1
-)
-
-// Windows 结构体定义
-type PROCESS_POWER_THROTTLING_STATE struct {
-	Version     uint32
-	ControlMask uint32
-	StateMask   uint32
-}
-
-type THREAD_POWER_THROTTLING_STATE struct {
-	Version     uint32
-	ControlMask uint32
-	StateMask   uint32
+	led   LEDColor     // 已设置成功的指示灯颜色
+	extra ExtraReports // 已发送成功的额外报告
 }
 
 // ==================== 工具函数 ====================
@@ -81,11 +40,6 @@ func exeDir() string {
 	return filepath.Dir(exe)
 }
 
-// u32ptrFromI32 将 int32 转换为 uintptr
-func u32ptrFromI32(v int32) uintptr {
-	return uintptr(uint32(v))
-}
-
 // ==================== 打印函数 ====================
 
 // printBanner 打印程序横幅
@@ -99,145 +53,414 @@ func printBanner(cfgPath string) {
 // printConfig 打印配置信息
 func printConfig(cfg *Config) {
 	log.Printf("[CFG] interval=%s", cfg.Interval)
+	if cfg.HitInterval > 0 || cfg.DefaultInterval > 0 {
+		log.Printf("[CFG] interval: hit=%s default=%s", cfg.intervalFor("", true), cfg.intervalFor("", false))
+	}
+	if cfg.Jitter > 0 {
+		log.Printf("[CFG] interval_jitter=%s", cfg.Jitter)
+	}
+	if cfg.IdleTicks > 0 {
+		log.Printf("[CFG] idle_backoff: 前台连续 %d 轮不变后间隔逐步翻倍，最长 %s", cfg.IdleTicks, cfg.IdleMaxInterval)
+	}
+	if !cfg.ApplyOnStart {
+		log.Printf("[CFG] apply_on_start=false：启动时不切换，等满第一个检查间隔")
+	}
+	if cfg.ConfirmDelay > 0 {
+		log.Printf("[CFG] confirm_delay=%s", cfg.ConfirmDelay)
+	}
+	if cfg.Heartbeat > 0 {
+		log.Printf("[CFG] heartbeat=%s", cfg.Heartbeat)
+	}
+	if cfg.Device.Model != "" {
+		log.Printf("[CFG] device_model=%s", cfg.Device.Model)
+	}
+	if cfg.Device.Serial != "" {
+		log.Printf("[CFG] serial=%s", cfg.Device.Serial)
+	}
+	if cfg.Device.ContainerID != "" {
+		log.Printf("[CFG] container_id=%s", cfg.Device.ContainerID)
+	}
+	for _, r := range cfg.DeviceProfiles {
+		log.Printf("[CFG] device_profile: %s", r)
+	}
+	for _, ig := range cfg.Device.IgnorePaths {
+		log.Printf("[CFG] ignore_path=%s", ig)
+	}
+	if cfg.Device.ControlPath != "" {
+		log.Printf("[CFG] control_path=%s", cfg.Device.ControlPath)
+	}
+	if cfg.Device.ControlUsagePage != 0 {
+		log.Printf("[CFG] control_usage_page=0x%04x control_usage=0x%04x", cfg.Device.ControlUsagePage, cfg.Device.ControlUsage)
+	}
+	if !cfg.Device.ReportIDInBuffer {
+		log.Printf("[CFG] report_id_in_buffer=false：报告缓冲区首字节写 0（无编号报告），不写 ReportID 0x%02x", cfg.Device.ReportID)
+	}
+	for _, f := range cfg.Device.FeatureLens {
+		log.Printf("[CFG] feature_length=%s（先于 caps 的报告长度）", f)
+	}
+	if cfg.NotifyConfigError {
+		log.Printf("[CFG] notify_config_error=on：配置重载失败时弹窗提示")
+	}
+	if cfg.ProbeDeviceInfo {
+		log.Printf("[CFG] probe_device_info=on：启动时读取型号/固件（推测协议）")
+	}
+	if cfg.ProbeCaps {
+		log.Printf("[CFG] probe_caps=on：启动时读取设备能力并对照配置（推测协议，不符只警告）")
+	}
+	if cfg.Device.ControlSelect != controlSelectRewrite {
+		log.Printf("[CFG] control_select=%s", cfg.Device.ControlSelect)
+	}
+	if cfg.Device.Index > 0 {
+		log.Printf("[CFG] -device %d（固定使用枚举列表中的该设备）", cfg.Device.Index)
+	}
+	if cfg.Device.SafeMode {
+		log.Printf("[CFG] safe_mode=on（不触碰键盘/多媒体集合）")
+	}
+	if cfg.Device.MotionSyncReport {
+		log.Printf("[CFG] motion_sync_report=on（Motion Sync 使用独立 0x%02x 报告）", cfg.Device.Cmd.MotionSync)
+	}
+	if cfg.ApplyTimeout != defaultApplyTimeout {
+		log.Printf("[CFG] apply_timeout_ms=%d", cfg.ApplyTimeout.Milliseconds())
+	}
+	if cfg.Device.EnumRetries != defaultEnumRetries {
+		log.Printf("[CFG] enum_retries=%d", cfg.Device.EnumRetries)
+	}
+	if cfg.Device.Cmd != defaultCommandBytes {
+		c := cfg.Device.Cmd
+		log.Printf("[CFG] 高级：命令字节已覆盖 perf=0x%02x poll=0x%02x motion_sync=0x%02x debounce=0x%02x combined=0x%02x save=0x%02x slot=0x%02x led=0x%02x",
+			c.Perf, c.Poll, c.MotionSync, c.Debounce, c.Combined, c.Save, c.Slot, c.LED)
+	}
+	if cfg.HitModeRaw.Set() || cfg.HitPollRaw.Set() {
+		log.Printf("[CFG] 高级：命中时使用原始字节 %s（hit_mode_raw / hit_poll_raw，不做校验）", profileName(cfg.effectiveProfile(true, false)))
+	}
+	if cfg.HitExtra != "" || cfg.DefaultExtra != "" {
+		log.Printf("[CFG] 高级：额外报告 hit=[%s] default=[%s]", cfg.HitExtra, cfg.DefaultExtra)
+	}
+	if cfg.Device.Persist {
+		log.Printf("[CFG] persist=on：每次切换都会写入鼠标闪存。闪存擦写次数有限，切换频繁时会加速损耗；")
+		log.Printf("[CFG]   只想本次开机生效的话请保持 persist=false。")
+	}
+	if cfg.TrustDeviceReadback {
+		log.Printf("[CFG] trust_device_readback=on（启动、重新连接、唤醒后读回设备实际设置）")
+	}
+	if !cfg.ApplyThread {
+		log.Printf("[CFG] apply_thread=off（设备访问在调用方直接执行）")
+	}
+	if cfg.RestoreOnExit {
+		log.Printf("[CFG] restore_on_exit=on：退出时恢复到基线 %s 并读回确认", profileName(cfg.baselineProfile()))
+	}
+	if !cfg.SelfExclude {
+		log.Printf("[CFG] self_exclude=off（本程序窗口在前台时也参与匹配）")
+	}
 	log.Printf("[CFG] hit    : mode=%s poll=%dHz", perfName(cfg.HitMode), cfg.HitPoll)
 	log.Printf("[CFG] default: mode=%s poll=%dHz", perfName(cfg.DefaultMode), cfg.DefaultPoll)
-	log.Printf("[CFG] whitelist(%d): %s", len(cfg.Whitelist), strings.Join(cfg.Whitelist, ", "))
-}
-
-// waitForever 等待程序退出
-func waitForever() {
-	log.Printf("按 Ctrl+C 退出。")
-	select {}
-}
-
-// ==================== Windows 优先级设置 ====================
-
-// setLowPriorityDefaults 设置低优先级默认值
-func setLowPriorityDefaults(enableBackgroundMode bool, enableEcoQoS bool) {
-	// 获取当前进程和线程句柄
-	hProc, _, _ := procGetCurrentProcess.Call()
-	hThread, _, _ := procGetCurrentThread.Call()
-
-	// 1. 设置进程优先级为 BELOW_NORMAL
-	if r, _, e := procSetPriorityClass.Call(hProc, uintptr(BELOW_NORMAL_PRIORITY_CLASS)); r == 0 {
-		log.Printf("[PRIO] SetPriorityClass(BELOW_NORMAL) failed: %v", e)
-	} else {
-		log.Printf("[PRIO] Process priority set to BELOW_NORMAL.")
+	if cfg.HitDebounce != 0 || cfg.DefaultDebounce != 0 {
+		log.Printf("[CFG] debounce: hit=%dms default=%dms（0 = 不修改）", cfg.HitDebounce, cfg.DefaultDebounce)
 	}
-
-	// 2. 设置线程优先级为 LOWEST
-	if r, _, e := procSetThreadPriority.Call(hThread, uintptr(u32ptrFromI32(THREAD_PRIORITY_LOWEST))); r == 0 {
-		log.Printf("[PRIO] SetThreadPriority(LOWEST) failed: %v", e)
-	} else {
-		log.Printf("[PRIO] Thread priority set to LOWEST.")
+	if cfg.MaxPoll != 0 || cfg.MaxPollBattery != 0 {
+		log.Printf("[CFG] max_poll: ac=%d battery=%d（0 = 不限）", cfg.pollCap(false), cfg.pollCap(true))
 	}
-
-	// 3. 可选：启用后台处理模式
-	if enableBackgroundMode {
-		if r, _, e := procSetPriorityClass.Call(hProc, uintptr(PROCESS_MODE_BACKGROUND_BEGIN)); r == 0 {
-			log.Printf("[PRIO] PROCESS_MODE_BACKGROUND_BEGIN failed: %v", e)
-		} else {
-			log.Printf("[PRIO] Process background mode enabled.")
+	if cfg.hasBatteryVariants() {
+		hb, db := cfg.effectiveProfile(true, true), cfg.effectiveProfile(false, true)
+		log.Printf("[CFG] battery: hit=%s default=%s", profileName(hb), profileName(db))
+	}
+	if cfg.hasWirelessVariants() {
+		log.Printf("[CFG] wireless: hit_poll=%d default_poll=%d max_poll=%d（0 = 同有线 / 不限）",
+			cfg.HitPollWireless, cfg.DefaultPollWireless, cfg.MaxPollWireless)
+		if len(cfg.Device.WirelessPIDs) > 0 {
+			log.Printf("[CFG] wireless_pid=%s", formatPIDs(cfg.Device.WirelessPIDs))
 		}
-
-		if r, _, e := procSetThreadPriority.Call(hThread, uintptr(THREAD_MODE_BACKGROUND_BEGIN)); r == 0 {
-			log.Printf("[PRIO] THREAD_MODE_BACKGROUND_BEGIN failed: %v", e)
-		} else {
-			log.Printf("[PRIO] Thread background mode enabled.")
+	}
+	log.Printf("[CFG] rules(%d，按优先级)：%s", len(cfg.Whitelist)+len(cfg.CmdlineRules)+len(cfg.TitleRules), ruleSummary(cfg))
+	log.Printf("[CFG]   命中 -> %s；未命中 -> %s",
+		profileName(cfg.effectiveProfile(true, false)), profileName(cfg.effectiveProfile(false, false)))
+	if cfg.switchIsNoop() {
+		log.Printf("[WARN] 命中与未命中的配置完全相同，切换不会有任何效果（请检查 hit_mode/hit_poll 与 default_mode/default_poll）")
+	}
+	for _, w := range cfg.Whitelist {
+		if p, ok := cfg.RuleProfiles[w]; ok {
+			log.Printf("[CFG]   %s -> %s（按程序配置）", w, profileName(p))
 		}
 	}
-
-	// 4. 可选：启用 EcoQoS/执行速度节流
-	if enableEcoQoS {
-		setProcessPowerThrottling(hProc)
-		setThreadPowerThrottling(hThread)
+	if len(cfg.RuleSlots) > 0 || cfg.DefaultSlot != 0 {
+		log.Printf("[CFG] 板载槽位：%d 条规则映射到槽位，default_slot=%d（0 = 逐项下发）", len(cfg.RuleSlots), cfg.DefaultSlot)
 	}
-}
-
-// setProcessPowerThrottling 设置进程电源节流
-func setProcessPowerThrottling(hProc uintptr) {
-	state := PROCESS_POWER_THROTTLING_STATE{
-		Version:     PROCESS_POWER_THROTTLING_CURRENT_VERSION,
-		ControlMask: PROCESS_POWER_THROTTLING_EXECUTION_SPEED,
-		StateMask:   PROCESS_POWER_THROTTLING_EXECUTION_SPEED,
+	if cfg.MatchMode != matchExact {
+		log.Printf("[CFG] match_mode=%s", cfg.MatchMode)
 	}
-
-	r, _, e := procSetProcessInformation.Call(
-		hProc,
-		uintptr(ProcessPowerThrottling),
-		uintptr(unsafe.Pointer(&state)),
-		unsafe.Sizeof(state),
-	)
-
-	if r == 0 {
-		log.Printf("[PRIO] Process EcoQoS/PowerThrottling failed: %v", e)
-	} else {
-		log.Printf("[PRIO] Process EcoQoS/PowerThrottling enabled.")
+	if cfg.HistoryFile != "" {
+		log.Printf("[CFG] history: %s (max %d lines)", historyPath(cfg), cfg.HistoryMaxLines)
+	}
+	if cfg.GUI {
+		log.Printf("[CFG] gui=on（状态窗口）")
+	}
+	if cfg.PipeName != "" {
+		log.Printf("[CFG] pipe_name=%s", cfg.PipeName)
+	}
+	if cfg.Log.Format == logFormatJSON {
+		log.Printf("[CFG] log_format=json")
+	}
+	if cfg.Log.Daily {
+		log.Printf("[CFG] log_file_daily=on：%s（保留 %d 个、总计 %d MB，0 = 不限）", dailyLogPath(cfg.Log), cfg.Log.Keep, cfg.Log.MaxTotalMB)
+	}
+	if cfg.WatchProcessStart {
+		log.Printf("[CFG] watch_process_start=on（白名单进程启动时提前切换）")
+	}
+	if cfg.QuietHours.Set() {
+		log.Printf("[CFG] quiet_hours=%s（期间暂停自动切换）", cfg.QuietHours)
+	}
+	if cfg.StatusFile != "" {
+		log.Printf("[CFG] status_file: %s", statusPath(cfg))
+	}
+	if len(cfg.NoTouchProcs) > 0 {
+		log.Printf("[CFG] no_touch_while_focused: %s", strings.Join(cfg.NoTouchProcs, ", "))
+	}
+	if len(cfg.RequireRunning) > 0 {
+		log.Printf("[CFG] require_running: %s（都不在运行时保持 default）", strings.Join(cfg.RequireRunning, ", "))
+	}
+	for _, r := range cfg.AmbientRules {
+		log.Printf("[CFG] while_running: %s 运行时 -> %s（优先于白名单）", r.Proc, profileName(r.Profile))
+	}
+	if len(cfg.OfficialProcs) > 0 {
+		log.Printf("[CFG] official_process: %s (conflict=%s)", strings.Join(cfg.OfficialProcs, ", "), cfg.Conflict)
+	}
+	if cfg.Session != sessionAny {
+		log.Printf("[CFG] session=%s（前台不在该会话时不切换）", cfg.Session)
+	}
+	if len(cfg.ManualProfiles) > 0 {
+		names := make([]string, len(cfg.ManualProfiles))
+		for i, p := range cfg.ManualProfiles {
+			names[i] = profileName(p)
+		}
+		log.Printf("[CFG] manual(%d): %s", len(names), strings.Join(names, ", "))
 	}
 }
 
-// setThreadPowerThrottling 设置线程电源节流
-func setThreadPowerThrottling(hThread uintptr) {
-	state := THREAD_POWER_THROTTLING_STATE{
-		Version:     THREAD_POWER_THROTTLING_CURRENT_VERSION,
-		ControlMask: THREAD_POWER_THROTTLING_EXECUTION_SPEED,
-		StateMask:   THREAD_POWER_THROTTLING_EXECUTION_SPEED,
-	}
-
-	_, _, _ = procSetThreadInformation.Call(
-		hThread,
-		uintptr(ThreadPowerThrottling),
-		uintptr(unsafe.Pointer(&state)),
-		unsafe.Sizeof(state),
-	)
-	// 线程侧失败也无所谓，不影响主流程
+// waitForever 等待程序退出（Ctrl+C 取消 ctx）
+func waitForever(ctx context.Context) {
+	log.Printf("按 Ctrl+C 退出。")
+	<-ctx.Done()
 }
 
 // ==================== 主逻辑函数 ====================
 
+// tickMemo 主循环的匹配缓存（同一前台进程连续多轮时跳过白名单扫描）
+var tickMemo MatchMemo
+
 // tickOnce 执行一次检查并切换
-func tickOnce(cfg *Config, last *Applied) (switchMsg string, errStr string) {
+// procs 为本轮共享的进程列表（nil 时本轮自行枚举一次）
+func tickOnce(ctx context.Context, cfg *Config, last *Applied, st *State, procs *ProcSnapshot) (switchMsg string, errStr string) {
+	if procs == nil {
+		procs = &ProcSnapshot{}
+	}
 	// 获取前台进程名
 	proc, err := ForegroundProcessName()
 	if err != nil {
 		return "", ""
 	}
-	proc = strings.ToLower(filepath.Base(proc))
+	proc = normalizeProcName(proc)
 
-	// 检查是否在白名单中
-	_, hit := cfg.WhitelistSet[proc]
-	wantPerf := cfg.DefaultMode
-	wantPoll := cfg.DefaultPoll
+	// 前台在远程桌面等其它会话时不动作
+	if !sessionGate.allow(cfg) {
+		return "", ""
+	}
 
-	if hit {
-		wantPerf = cfg.HitMode
-		wantPoll = cfg.HitPoll
+	// 本程序自己的窗口在前台：不当作触发进程
+	if selfExclude.skip(cfg, proc) {
+		return "", ""
+	}
+
+	// 按白名单（进程名 / 命令行）与电源状态得出目标配置
+	battery := onBattery(cfg)
+	// watch_process_start：刚启动的白名单进程还没拿到前台时，按它决策（命令行属于前台进程，不用）
+	ctxFlags := Context{Battery: battery, Wireless: cfg.hasWirelessVariants() && linkMode.wireless, Cmdline: ForegroundProcessCmdline, Memo: &tickMemo,
+		Running: ambientRunning(cfg, procs)}
+	// 窗口标题只在有 title: 规则时才读
+	var title string
+	if len(cfg.TitleRules) > 0 {
+		if info, err := ForegroundWindowInfo(); err == nil {
+			title = info.Title
+		}
+	}
+	target, launched := launchHold.target(proc)
+	if launched {
+		ctxFlags.Cmdline, title = nil, ""
+	}
+	// require_running：门控进程都没在运行时按“未命中”决策
+	if !requireRunning.allow(cfg, procs) {
+		target, ctxFlags.Cmdline, title = "", nil, ""
+	}
+	d := Decide(cfg, target, title, ctxFlags)
+	rule, hit, want := d.Rule, d.Hit, d.Profile()
+	st.update(func(s *StatusSnapshot) {
+		s.Proc, s.Rule, s.Hit, s.Battery, s.Desired = proc, rule, hit, battery, want
+	})
+
+	// 反作弊敏感进程：切过来时下发过一次后就不再碰设备
+	if noTouchGate.hold(cfg, proc) {
+		return "", ""
+	}
+
+	// 读回模式（发现重新连接）/ 有线无线分开配置 / 按设备配置时，每轮都先找到设备
+	var dev VaxeeDeviceInfo
+	var findErr error
+	if cfg.TrustDeviceReadback || cfg.hasWirelessVariants() || len(cfg.DeviceProfiles) > 0 {
+		dev, findErr = FindOneVaxeeDevice(ctx, cfg.Device)
+	}
+
+	// 连接方式与决策时所用的不同（刚插拔切换），或有按设备的配置：按找到的设备重新决策
+	if findErr == nil && dev.Path != "" {
+		redecide := false
+		if cfg.hasWirelessVariants() && linkMode.observe(dev, cfg.Device) {
+			ctxFlags.Wireless, redecide = linkMode.wireless, true
+		}
+		if len(cfg.DeviceProfiles) > 0 {
+			ctxFlags.Serial, ctxFlags.ContainerID, redecide = dev.Serial, dev.ContainerID, true
+		}
+		if redecide {
+			d = Decide(cfg, target, title, ctxFlags)
+			want = d.Profile()
+			st.update(func(s *StatusSnapshot) { s.Desired = want })
+		}
+	}
+
+	// 读回模式：启动、重新连接、唤醒后以设备实际状态为准更新缓存（读不回来时沿用缓存）
+	if cfg.TrustDeviceReadback && deviceReadback.due(dev, findErr == nil && dev.Path != "") {
+		syncAppliedFromDevice(ctx, cfg, dev, last, want)
 	}
 
 	// 如果设置没有变化，直接返回
-	if last.ok && last.perf == wantPerf && last.poll == wantPoll {
+	if last.matches(want) {
+		noTouchGate.touched(cfg, proc)
+		return "", ""
+	}
+
+	// 二次确认：过滤启动过程中短暂抢到前台的提示框/闪屏
+	if !confirmForeground(ctx, cfg, proc) {
 		return "", ""
 	}
 
 	// 查找 VAXEE 设备
-	dev, findErr := FindOneVaxeeDevice()
+	if dev.Path == "" {
+		dev, findErr = FindOneVaxeeDevice(ctx, cfg.Device)
+	}
 	if findErr != nil {
 		return "", "未找到可用 VAXEE 设备：" + findErr.Error()
 	}
 
-	// 应用设置
-	if err := ApplyVaxeeSetting(dev.Path, wantPerf, wantPoll); err != nil {
-		return "", "应用设置失败：" + err.Error()
+	// 应用设置（只发还没生效的部分）
+	applyErr := applyProfile(ctx, cfg, dev.Path, last.pending(want))
+	recordSwitch(cfg, proc, *last, want, applyErr)
+	last.update(want, applyErr)
+	if applyErr != nil {
+		return "", "应用设置失败：" + applyErr.Error()
 	}
 
 	// 更新记录
-	*last = Applied{perf: wantPerf, poll: wantPoll, ok: true}
+	st.recordApplied(want, dev.Path)
+	noTouchGate.touched(cfg, proc)
 
 	// 返回切换信息
+	suffix := ""
+	if battery {
+		suffix = "（电池）"
+	}
+	if ctxFlags.Wireless {
+		suffix += "（无线）"
+	}
+	if d.Clamped {
+		suffix += fmt.Sprintf("（回报率上限 %dHz，规则要求 %dHz）", d.Poll, d.RawPoll)
+	}
+	if d.Ambient != "" {
+		return fmt.Sprintf("[SWITCH] %s 运行中(while_running) -> %s%s", d.Ambient, profileName(want), suffix), ""
+	}
 	if hit {
-		return fmt.Sprintf("[SWITCH] 命中白名单(%s) -> %s + %dHz", proc, perfName(wantPerf), wantPoll), ""
+		if rule != proc {
+			return fmt.Sprintf("[SWITCH] 命中白名单(%s, %s) -> %s%s", proc, rule, profileName(want), suffix), ""
+		}
+		return fmt.Sprintf("[SWITCH] 命中白名单(%s) -> %s%s", proc, profileName(want), suffix), ""
 	}
-	return fmt.Sprintf("[SWITCH] 未命中白名单(%s) -> %s + %dHz", proc, perfName(wantPerf), wantPoll), ""
+	return fmt.Sprintf("[SWITCH] 未命中白名单(%s) -> %s%s", proc, profileName(want), suffix), ""
+}
+
+// nextWait 本轮等待时长：（按命中的规则与否选取的）间隔 + 随机抖动
+func nextWait(cfg *Config, rule string, hit bool) time.Duration {
+	d := cfg.intervalFor(rule, hit)
+	if cfg.Jitter <= 0 {
+		return d
+	}
+	return d + rand.N(cfg.Jitter+1)
+}
+
+// defaultIdleMaxInterval idle_backoff 拉长后的默认间隔上限
+const defaultIdleMaxInterval = 5 * time.Minute
+
+// IdleBackoff 前台长时间不变（离开电脑）时逐步拉长检查间隔；前台一变就恢复。
+// 只在下一次检查时才能发现变化，所以 idle_max_interval_seconds 同时也是最长的切换延迟
+type IdleBackoff struct {
+	proc string
+	same int // 前台连续不变的轮数
+}
+
+// observe 每轮检查后记录前台进程
+func (b *IdleBackoff) observe(proc string) {
+	if proc != b.proc {
+		b.proc, b.same = proc, 0
+		return
+	}
+	b.same++
+}
+
+// stretch 按连续不变的轮数拉长间隔：每满 IdleTicks 轮翻一倍，不超过 IdleMaxInterval
+func (b *IdleBackoff) stretch(cfg *Config, d time.Duration) time.Duration {
+	if cfg.IdleTicks <= 0 || b.same < cfg.IdleTicks {
+		return d
+	}
+	out := d
+	for n := b.same / cfg.IdleTicks; n > 0 && out < cfg.IdleMaxInterval; n-- {
+		out *= 2
+	}
+	if out > cfg.IdleMaxInterval {
+		out = max(cfg.IdleMaxInterval, d) // 上限比正常间隔还短时保持正常间隔
+	}
+	return out
+}
+
+// Cadence 固定节拍：下一轮从本轮开始时刻起算，而不是从本轮结束起算，
+// 慢的一轮（设备卡住、下发超时）不会把之后的检查整体推后
+type Cadence struct {
+	start time.Time
+}
+
+func (c *Cadence) begin() {
+	c.start = time.Now()
+}
+
+// wait 距下一轮还需等待的时长；本轮已经超过间隔时打一条日志，立即开始下一轮
+func (c *Cadence) wait(d time.Duration) time.Duration {
+	elapsed := time.Since(c.start)
+	if elapsed > d {
+		log.Printf("[TICK] 本轮耗时 %s，超过检查间隔 %s，立即开始下一轮。", elapsed.Round(time.Millisecond), d)
+		return 0
+	}
+	return d - elapsed
+}
+
+// confirmForeground 间隔 ConfirmDelay 后再读一次前台，进程不变才返回 true
+func confirmForeground(ctx context.Context, cfg *Config, proc string) bool {
+	if cfg.ConfirmDelay <= 0 {
+		return true
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(cfg.ConfirmDelay):
+	}
+	again, err := ForegroundProcessName()
+	if err != nil {
+		return false
+	}
+	return normalizeProcName(again) == proc
 }
 
 // ==================== 主函数 ====================
@@ -245,14 +468,92 @@ func tickOnce(cfg *Config, last *Applied) (switchMsg string, errStr string) {
 func main() {
 	log.SetFlags(log.LstdFlags)
 
-	// 配置文件路径
-	cfgPath := filepath.Join(exeDir(), configFileName)
+	configFlag := flag.String("config", "", "配置文件路径（默认依次查找 %APPDATA%\\vaxee-autoswitch\\ 与程序目录）")
+	checkConfig := flag.String("check-config", "", "只解析并校验指定配置文件，打印警告/错误后退出（0=可用，1=有错误），不访问设备")
+	addWhitelist := flag.String("add-whitelist", "", "把指定进程名加入配置文件白名单后退出（保留原有注释与顺序）")
+	dumpConfig := flag.Bool("dump-config", false, "打印解析后的完整生效配置（.conf 格式）并退出")
+	once := flag.Bool("once", false, "按当前前台程序评估一次并应用，然后退出（退出码 0=成功）")
+	reset := flag.Bool("reset", false, "把鼠标恢复到基线配置（baseline_mode/baseline_poll）后退出")
+	capsFlag := flag.Bool("caps", false, "列出每个 VAXEE HID 集合的 UsagePage/Usage 与 Feature 报告长度后退出（不发送任何报告）")
+	watch := flag.Bool("watch", false, "诊断：持续打印前台进程/路径/窗口标题的每次变化及是否命中规则，不访问鼠标（Ctrl+C 退出）")
+	soak := flag.Int("soak", 0, "压力测试：交替下发两组配置 N 次并读回校验，统计后恢复原状态退出")
+	soakDelay := flag.Duration("soak-delay", defaultSoakDelay, "-soak 每次下发之间的间隔")
+	pollState := flag.Duration("poll-state", 0, "诊断：按该间隔（如 1s）持续读回设备当前性能模式/回报率并标出变化，不下发任何设置（Ctrl+C 退出）")
+	flag.IntVar(&sessionDeviceIndex, "device", 0, "固定使用启动日志中第 N 个 VAXEE 设备（从 1 开始，仅本次运行有效）")
+	diagFlag := flag.Bool("diag", false, "生成诊断包 vaxee-diag.txt（生效配置、设备枚举、全部 HID 设备、系统版本、最近日志）后退出")
+	simulate := flag.String("simulate-foreground", "", "测试：本次运行把前台进程固定为该进程名（如 cs2.exe），配合 -once 验证下发到真实鼠标")
+	registerDiagFlags()
+	flag.Parse()
+
+	if *simulate != "" {
+		simulatedForeground = normalizeProcName(*simulate)
+		log.Printf("[SIM] 前台进程已模拟为 %s（-simulate-foreground），不读取真实前台窗口。", simulatedForeground)
+	}
 
+	if *checkConfig != "" {
+		os.Exit(runCheckConfig(*checkConfig))
+	}
+
+	cfgPath := resolveConfigPath(*configFlag)
+	if *dumpConfig {
+		os.Exit(runDumpConfig(cfgPath))
+	}
+	if *addWhitelist != "" {
+		os.Exit(runAddWhitelist(cfgPath, *addWhitelist))
+	}
+
+	// Ctrl+C / SIGTERM 取消 ctx，主循环和设备操作随之退出
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *capsFlag {
+		code := runCaps(ctx)
+		stop()
+		os.Exit(code)
+	}
+	if *diagFlag {
+		code := runDiag(ctx, cfgPath)
+		stop()
+		os.Exit(code)
+	}
+	if *once {
+		code := runOnce(ctx, cfgPath)
+		stop()
+		os.Exit(code)
+	}
+	if *reset {
+		code := runReset(ctx, cfgPath)
+		stop()
+		os.Exit(code)
+	}
+	if *watch {
+		code := runWatch(ctx, cfgPath)
+		stop()
+		os.Exit(code)
+	}
+	if *soak != 0 {
+		code := runSoak(ctx, cfgPath, *soak, *soakDelay)
+		stop()
+		os.Exit(code)
+	}
+	if *pollState != 0 {
+		code := runPollState(ctx, cfgPath, *pollState)
+		stop()
+		os.Exit(code)
+	}
+
+	Run(ctx, cfgPath)
+	log.Printf("已退出。")
+}
+
+// Run 运行监控主循环，直到 ctx 被取消
+func Run(ctx context.Context, cfgPath string) {
 	// 确保配置文件存在
 	if err := ensureConfigExists(cfgPath); err != nil {
 		log.Printf("[ERR] 无法创建配置文件：%v", err)
 		log.Printf("程序不会退出（窗口保留）。请检查权限/路径：%s", cfgPath)
-		waitForever()
+		waitForever(ctx)
+		return
 	}
 
 	// 加载配置
@@ -260,114 +561,407 @@ func main() {
 	if err != nil {
 		log.Printf("[ERR] 读取配置失败：%v", err)
 		log.Printf("程序不会退出（窗口保留）。请修复配置后保存：%s", cfgPath)
-		waitForever()
+		waitForever(ctx)
+		return
+	}
+	applySessionFlags(cfg)
+
+	applyLogOptions(cfg.Log)
+	deviceThread.setEnabled(cfg.ApplyThread)
+
+	// 尽早让出焦点：控制台在进程启动时已被系统激活
+	if cfg.NoActivate {
+		if err := releaseConsoleFocus(); err != nil {
+			log.Printf("[CFG] no_activate 未生效：%v", err)
+		}
 	}
 
 	// 打印横幅和配置
 	printBanner(cfgPath)
 	printConfig(cfg)
+	warnSessionZero()
+
+	// 枚举 VAXEE 设备（学习模式不碰设备）
+	var ctrl VaxeeDeviceInfo
+	hasCtrl := false
+	if cfg.LearnMode {
+		log.Printf("[LEARN] 学习模式：不切换、不访问鼠标，只记录新出现的前台进程。")
+	} else {
+		ctrl, hasCtrl = enumerateDevices(ctx, cfg)
+	}
+
+	// 额外报告按控制通道的 Feature 报告长度与 ReportID 校验（重载时也一样）。
+	// probe_caps 时再读设备自报能力：能力命令是推测的，不符只警告、照常使用配置
+	validate := func(*Config) error { return nil }
+	if hasCtrl {
+		checkCaps := func(*Config) {}
+		if cfg.ProbeCaps {
+			caps, e := ReadCapabilities(ctx, ctrl.Path, cfg.Device, int(ctrl.FeatureLen))
+			if e != nil {
+				log.Printf("[WARN] 读取设备能力失败，只按内置映射校验：%v", e)
+			} else {
+				log.Printf("[DEV] 设备能力：%s", caps)
+				checkCaps = func(c *Config) {
+					if err := caps.validateConfig(c); err != nil {
+						log.Printf("[WARN] 配置与设备自报能力不符（仍按配置下发）：%v", err)
+					}
+				}
+			}
+		}
+		flen := int(ctrl.FeatureLen)
+		validate = func(c *Config) error {
+			if err := checkExtraReports(c, flen, c.Device.bufferReportID()); err != nil {
+				return err
+			}
+			checkCaps(c)
+			return nil
+		}
+		if err := validate(cfg); err != nil {
+			log.Printf("[ERR] 配置与设备能力不符：%v", err)
+			log.Printf("程序不会退出（窗口保留）。请修复配置后重启：%s", cfgPath)
+			waitForever(ctx)
+			return
+		}
+	}
 
-	// 枚举 VAXEE 设备
-	enumerateDevices()
+	// 官方软件冲突：启动时检查一次，之后每轮复查
+	var conflict ConflictWatcher
+	if conflict.check(cfg, nil, time.Now()) && cfg.Conflict == conflictExit {
+		log.Printf("[ERR] conflict=exit：官方软件运行中，不开始自动切换。")
+		log.Printf("程序不会退出（窗口保留）。请退出官方软件后重启本程序。")
+		waitForever(ctx)
+		return
+	}
 
 	// 设置低优先级
 	setLowPriorityDefaults(true, true)
 	log.Printf("开始后台监控：每 %s 检查一次前台进程。", cfg.Interval)
 
-	// 启动定时器
-	// ticker := time.NewTicker(cfg.Interval)
-	// defer ticker.Stop()
+	// 固定节拍：间隔从每轮开始时刻起算（间隔随命中与否变化，不能用 time.Ticker）
+	var cadence Cadence
+	var idle IdleBackoff
 
 	var last Applied
-	var lastErr string
+	var errLog ErrorLog
+	var override ManualOverride
+	var state State
+	var learner Learner
+	var statusOut StatusWriter
+	lastBeat := time.Now()
+
+	// 手动覆盖热键
+	hotkeys := restartHotkeys(nil, cfg)
+	defer hotkeys.Stop()
+
+	// 电源状态变化通知（AC/电池切换时立即重新评估）
+	power, powerErr := StartPowerWatcher()
+	if powerErr != nil {
+		log.Printf("[POWER] 无法监听电源事件：%v", powerErr)
+	}
+	defer power.Stop()
+
+	// 白名单进程启动检测（可选）
+	var launches *ProcessStartWatcher
+	if cfg.WatchProcessStart {
+		var err error
+		if launches, err = StartProcessStartWatcher(); err != nil {
+			log.Printf("[ERR] 无法检测进程启动：%v", err)
+		}
+		defer launches.Stop()
+	}
 
-	// 主循环
-	for {
-		// 热加载配置
-		reloadConfigIfChanged(cfgPath, &cfg, &modTime)
+	// 状态窗口（可选）
+	if cfg.GUI {
+		win, err := StartStatusWindow(&state)
+		if err != nil {
+			log.Printf("[ERR] 无法创建状态窗口：%v", err)
+		}
+		defer win.Stop()
+	}
 
-		// 执行一次检查
-		switchMsg, errStr := tickOnce(cfg, &last)
-		if switchMsg != "" {
-			log.Print(switchMsg)
+	// 命名管道命令（可选）
+	var pipe *PipeServer
+	if cfg.PipeName != "" {
+		var err error
+		if pipe, err = StartPipeServer(cfg.PipeName); err != nil {
+			log.Printf("[ERR] 无法创建命名管道 %s：%v", cfg.PipeName, err)
+		}
+		defer pipe.Stop()
+	}
+	paused := false // pipe pause：暂停自动切换
+	// 重载后：重新注册热键；只有改了影响下发内容/目标集合的设备选项才清空缓存、重新下发
+	afterReload := func(old *Config) {
+		hotkeys = restartHotkeys(hotkeys, cfg)
+		// 热键进入的手动覆盖：新配置去掉了热键后再也无法用热键退出，直接恢复自动切换
+		if override.active && override.custom == nil && cfg.HotkeyCycle == nil {
+			log.Print(override.release(&state))
+		}
+		if keys := applyKeysChanged(old, cfg); len(keys) > 0 {
+			log.Printf("[CFG] 下发相关的设备选项已变更（%s），下一轮重新下发当前配置。", strings.Join(keys, ", "))
+			last = Applied{}
+		}
+	}
+	reloadNow := func() bool {
+		prev, old := modTime, cfg
+		modTime = time.Time{}
+		if reloadConfigIfChanged(cfgPath, &cfg, &modTime, validate) {
+			afterReload(old)
+			return true
 		}
+		if modTime.IsZero() {
+			modTime = prev
+		}
+		return false
+	}
 
-		// 处理错误信息
-		handleError(&lastErr, errStr)
+	// 主循环：第一轮紧接启动执行（apply_on_start=false 时跳过）
+	skipTick := !cfg.ApplyOnStart
+	for first := true; ; first = false {
+		cadence.begin()
 
-		// 等待下一次检查
-		// <-ticker.C
-		time.Sleep(cfg.Interval)
+		// 热加载配置
+		if old := cfg; reloadConfigIfChanged(cfgPath, &cfg, &modTime, validate) {
+			afterReload(old)
+		}
+		// 本轮的进程列表：冲突检测、while_running、require_running 共用一次枚举
+		procs := &ProcSnapshot{}
+		conflict.check(cfg, procs, time.Now())
+		quiet := quietGate.check(cfg, time.Now())
+
+		// 执行一次检查（学习模式只记录；手动覆盖、管道暂停、安静时段期间暂停自动切换）
+		if cfg.LearnMode {
+			learner.observe(cfg)
+		} else if !override.active && !paused && !quiet && !(first && skipTick) {
+			switchMsg, errStr := tickOnce(ctx, cfg, &last, &state, procs)
+			if switchMsg != "" {
+				logEvent(stateEvent("switch", switchMsg, state.Snapshot()))
+			}
+
+			// 处理错误信息
+			errLog.handle(errStr)
+			state.update(func(s *StatusSnapshot) { s.LastError = errStr })
+
+			// 只有真正检查过前台才计入“没变”的轮数（暂停、安静时段期间不拉长间隔）
+			idle.observe(state.Snapshot().Proc)
+		}
 
-	}
+		statusOut.write(cfg, state.Snapshot())
+
+		// 心跳：按检查间隔的粒度判断是否到点
+		if cfg.Heartbeat > 0 && time.Since(lastBeat) >= cfg.Heartbeat {
+			snap := state.Snapshot()
+			logEvent(stateEvent("heartbeat", snap.heartbeatLine(), snap))
+			lastBeat = time.Now()
+		}
 
+		// 等待下一次检查，或热键事件
+		snap := state.Snapshot()
+		select {
+		case <-ctx.Done():
+			restoreOnExit(cfg)
+			return
+		case <-time.After(cadence.wait(idle.stretch(cfg, nextWait(cfg, snap.Rule, !override.active && snap.Hit)))):
+		case ev := <-hotkeys.Events():
+			if cfg.LearnMode {
+				break
+			}
+			msg, errStr := override.handleHotkey(ctx, cfg, &last, &state, ev)
+			if msg != "" {
+				log.Print(msg)
+			}
+			errLog.handle(errStr)
+			state.update(func(s *StatusSnapshot) { s.LastError = errStr })
+		case name := <-launches.Events():
+			if cfg.LearnMode || override.active || paused || quietGate.active {
+				break
+			}
+			if _, hit := matchWhitelist(cfg, name, "", nil); !hit {
+				break
+			}
+			// 直接进入下一轮：tickOnce 按刚启动的进程决策
+			log.Printf("[LAUNCH] 白名单进程已启动：%s，不等它切到前台就提前切换。", name)
+			launchHold.start(name)
+		case req := <-pipe.Requests():
+			req.Reply <- handlePipeCommand(ctx, cfg, &last, &state, &override, &paused, reloadNow, req.Line)
+		case ev := <-power.Events():
+			switch ev {
+			case powerStatusChanged:
+				// 直接进入下一轮，按新的电源状态重新评估
+				if cfg.hasBatteryVariants() {
+					log.Printf("[POWER] 电源状态变化，立即重新评估。")
+				}
+			case powerResumed:
+				if !cfg.ResumeReapply {
+					break
+				}
+				// 唤醒后设备状态未知：清空缓存，下一轮必定重新下发
+				last = Applied{}
+				deviceReadback.reset()
+				if cfg.LearnMode {
+					break
+				}
+				log.Printf("[POWER] 系统已唤醒，重新下发当前配置。")
+				if override.active {
+					msg, errStr := override.apply(ctx, cfg, &last, &state)
+					if msg != "" {
+						log.Print(msg)
+					}
+					errLog.handle(errStr)
+					state.update(func(s *StatusSnapshot) { s.LastError = errStr })
+				}
+			}
+		}
+	}
 }
 
 // ==================== 辅助函数 ====================
 
 // enumerateDevices 枚举并显示设备信息
-func enumerateDevices() {
-	infos, enumErr := EnumerateVaxeeDevices()
+// 返回找到的控制通道（找不到时 ok=false）
+func enumerateDevices(ctx context.Context, cfg *Config) (ctrl VaxeeDeviceInfo, ok bool) {
+	infos, enumErr := EnumerateVaxeeDevices(ctx)
 	if enumErr != nil {
 		log.Printf("[DEV] 枚举 HID 设备失败：%v", enumErr)
-		return
+		return VaxeeDeviceInfo{}, false
 	}
 
 	if len(infos) == 0 {
 		log.Printf("[DEV] 未发现 VAXEE 设备（Manufacturer/Product 不包含 vaxee）。")
 		log.Printf("[DEV] 程序将继续运行，每次尝试切换时会重新查找设备。")
-		enumerateAllHidDevices()
-	} else {
-		log.Printf("[DEV] 发现 %d 个 VAXEE HID 设备：", len(infos))
-		for i, d := range infos {
-			log.Printf("  #%d Manufacturer=%q Product=%q VID=0x%04x PID=0x%04x Path=%s",
-				i+1, d.Manufacturer, d.Product, d.VID, d.PID, d.Path)
+		enumerateAllHidDevices(ctx, cfg)
+		return VaxeeDeviceInfo{}, false
+	}
+
+	// 找出控制通道；probe_device_info 时再读取型号/固件（固件不支持时留空）
+	ctrl, ctrlErr := FindOneVaxeeDevice(ctx, cfg.Device)
+	if ctrlErr == nil && ctrl.Model == "" && cfg.ProbeDeviceInfo {
+		ctrl.Model, ctrl.Firmware, _ = ReadDeviceInfo(ctx, ctrl.Path, cfg.Device, int(ctrl.FeatureLen))
+	}
+
+	if ctrlErr != nil && cfg.Device.Index > 0 {
+		log.Printf("[ERR] %v", ctrlErr)
+	}
+	// 一只鼠标会暴露多个 HID 集合：按物理设备分组显示，只通过其中的控制通道下发
+	groups, nDev := physicalGroups(infos)
+	log.Printf("[DEV] 发现 %d 个 VAXEE HID 集合，属于 %d 个物理设备：", len(infos), nDev)
+	ctrlGroup := 0
+	for i, d := range infos {
+		note := ""
+		if cfg.Device.SafeMode && isKeyboardOrConsumer(d) {
+			note = " (safe_mode 排除)"
+		}
+		if _, ok := ignoredPath(d.Path, cfg.Device); ok {
+			note = " (ignore_path 跳过)"
+		}
+		if ctrlErr == nil && d.Path == ctrl.Path {
+			ctrlGroup = groups[i]
+			note = fmt.Sprintf(" [控制通道 Model=%q Firmware=%q]", ctrl.Model, ctrl.Firmware)
+			if cfg.Device.Index == i+1 {
+				note += " (-device 指定)"
+			}
+		}
+		if !serialMatches(d, cfg.Device) {
+			note = " (serial 不匹配)"
+		}
+		if !containerMatches(d, cfg.Device) {
+			note = " (container_id 不匹配)"
 		}
+		if d.ContainerID != "" {
+			note = " Container=" + d.ContainerID + note
+		}
+		log.Printf("  #%d 设备%d Manufacturer=%q Product=%q Serial=%q VID=0x%04x PID=0x%04x Path=%s%s",
+			i+1, groups[i], d.Manufacturer, d.Product, d.Serial, d.VID, d.PID, d.Path, note)
+	}
+	if ctrlGroup != 0 {
+		siblings := 0
+		for _, g := range groups {
+			if g == ctrlGroup {
+				siblings++
+			}
+		}
+		log.Printf("[DEV] 通过设备%d 的控制通道下发（该设备共 %d 个集合，其余集合不单独下发）。", ctrlGroup, siblings)
 	}
+
+	// 当前板载配置槽位：只在配置了槽位映射时读取（推测协议；固件不支持时不显示）
+	if ctrlErr == nil && (len(cfg.RuleSlots) > 0 || cfg.DefaultSlot != 0) {
+		if slot, err := ReadActiveProfileSlot(ctx, ctrl.Path, cfg.Device, int(ctrl.FeatureLen)); err == nil {
+			log.Printf("[DEV] 当前板载配置槽位：%d", slot)
+		}
+	}
+	return ctrl, ctrlErr == nil
 }
 
-// enumerateAllHidDevices 枚举所有 HID 设备
-func enumerateAllHidDevices() {
-	all, errAll := EnumerateAllHidDevices()
-	if errAll != nil {
-		log.Printf("[DEV] 枚举全部 HID 设备失败：%v", errAll)
+// enumerateAllHidDevices 枚举所有 HID 设备（边枚举边打印）
+func enumerateAllHidDevices(ctx context.Context, cfg *Config) {
+	if !cfg.DumpAllHID {
+		log.Printf("[DEV] dump_all_hid=false：不列出全部 HID 设备。")
 		return
 	}
-
-	log.Printf("[DEV] 系统 HID 设备总数（可读取字符串/属性的接口）：%d", len(all))
-	for i, d := range all {
+	n, shown := 0, 0
+	errAll := EnumerateAllHidDevicesFunc(ctx, func(d VaxeeDeviceInfo) bool {
+		n++
 		// 过滤掉完全空字符串的设备，减少噪音
 		if d.Manufacturer == "" && d.Product == "" {
-			continue
+			return true
 		}
+		if cfg.DumpAllHIDMax > 0 && shown >= cfg.DumpAllHIDMax {
+			return true // 继续计数，只是不再打印
+		}
+		shown++
 		log.Printf("  [HID #%d] Manufacturer=%q Product=%q VID=0x%04x PID=0x%04x Path=%s",
-			i+1, d.Manufacturer, d.Product, d.VID, d.PID, d.Path)
+			n, d.Manufacturer, d.Product, d.VID, d.PID, d.Path)
+		return true
+	})
+	if errAll != nil {
+		log.Printf("[DEV] 枚举全部 HID 设备失败：%v", errAll)
+		return
+	}
+
+	log.Printf("[DEV] 系统 HID 设备总数（可读取字符串/属性的接口）：%d", n)
+	if cfg.DumpAllHIDMax > 0 && shown >= cfg.DumpAllHIDMax {
+		log.Printf("[DEV] 只列出了前 %d 个（dump_all_hid_max）。", shown)
 	}
 	log.Printf("[DEV] 提示：如果你在列表里看到了目标鼠标但字符串不含 VAXEE，后续可以改成按 VID/PID 固定匹配。")
 }
 
-// reloadConfigIfChanged 检查并重新加载配置
-func reloadConfigIfChanged(cfgPath string, cfg **Config, modTime *time.Time) {
-	if fi, e := os.Stat(cfgPath); e == nil && fi.ModTime().After(*modTime) {
-		if nc, mt, e2 := loadConfig(cfgPath); e2 == nil {
+// reloadConfigIfChanged 检查并重新加载配置，返回是否已重载；
+// validate 不通过（如额外报告超出报告长度）时保留旧配置
+//
+// 配置文件被删除时：打一次警告，继续使用内存中的配置（不自动重建，免得与用户意图冲突）；
+// 把 modTime 清零，文件重新出现（哪怕是 mtime 更旧的备份）时一定会重新加载。
+func reloadConfigIfChanged(cfgPath string, cfg **Config, modTime *time.Time, validate func(*Config) error) bool {
+	fi, e := os.Stat(cfgPath)
+	if os.IsNotExist(e) {
+		if !modTime.IsZero() {
+			log.Printf("[CFG] 配置文件已被删除：%s", cfgPath)
+			log.Printf("[CFG] 继续使用内存中的配置运行；文件恢复后自动重新加载。注意：不恢复的话，下次启动会生成默认配置。")
+			*modTime = time.Time{}
+		}
+		return false
+	}
+	if e == nil && fi.ModTime().After(*modTime) {
+		nc, mt, e2 := loadConfig(cfgPath)
+		if e2 == nil {
+			applySessionFlags(nc)
+			e2 = validate(nc)
+		}
+		if e2 == nil {
 			*cfg = nc
 			*modTime = mt
+			applyLogOptions(nc.Log)
+			deviceThread.setEnabled(nc.ApplyThread)
 			log.Printf("[CFG] 检测到配置文件变更，已重新加载。")
 			printConfig(*cfg)
+			configErrorNotifier.reset()
+			return true
 		} else {
 			log.Printf("[ERR] 配置文件变更但重载失败：%v", e2)
+			if (*cfg).NotifyConfigError {
+				configErrorNotifier.notify(cfgPath, e2)
+			}
 		}
 	}
-}
-
-// handleError 处理错误信息
-func handleError(lastErr *string, errStr string) {
-	if errStr != "" && errStr != *lastErr {
-		*lastErr = errStr
-		log.Printf("[ERR] %s", errStr)
-	} else if errStr == "" {
-		*lastErr = ""
-	}
+	return false
 }
 
 // package main
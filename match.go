@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// 普通白名单条目与前台进程名的比较方式（match_mode）
+const (
+	matchExact     = "exact"     // 完全相等（默认）
+	matchSubstring = "substring" // 进程名包含条目，如 cs2 命中 cs2.exe
+	matchPrefix    = "prefix"    // 进程名以条目开头
+)
+
+func parseMatchMode(s string) (string, error) {
+	switch v := strings.ToLower(strings.TrimSpace(s)); v {
+	case matchExact, matchSubstring, matchPrefix:
+		return v, nil
+	}
+	return "", fmt.Errorf("invalid match_mode: %s (exact|substring|prefix)", s)
+}
+
+// matchEntry 按 match_mode 比较一个白名单条目
+func matchEntry(mode, proc, entry string) bool {
+	switch mode {
+	case matchSubstring:
+		return strings.Contains(proc, entry)
+	case matchPrefix:
+		return strings.HasPrefix(proc, entry)
+	}
+	return proc == entry
+}
+
+// 规则的具体程度（数值越小越具体），同优先级时用来决胜
+const (
+	specExact = iota
+	specPrefix
+	specSubstring
+	specCmdline
+	specTitle
+)
+
+// ruleMatch 一条命中的规则
+type ruleMatch struct {
+	rule     string
+	priority int
+	spec     int
+	order    int // 同类规则中的书写顺序
+}
+
+// better 规则决胜：优先级高者胜；同优先级按具体程度 exact > prefix > substring > cmdline > title；
+// 仍相同则按配置文件中的书写顺序，先写的胜
+func (m ruleMatch) better(o ruleMatch) bool {
+	if m.priority != o.priority {
+		return m.priority > o.priority
+	}
+	if m.spec != o.spec {
+		return m.spec < o.spec
+	}
+	return m.order < o.order
+}
+
+// matchWhitelist 判断前台进程是否命中白名单，返回命中的规则描述。
+// proc 为已归一化（basename + 小写）的进程名，title 为前台窗口标题（取不到时为空）；
+// cmdline 仅在可能改变结果时才调用（读取命令行有额外开销，且可能失败）。
+func matchWhitelist(cfg *Config, proc, title string, cmdline func() (string, error)) (rule string, hit bool) {
+	var best ruleMatch
+	found := false
+	consider := func(m ruleMatch) {
+		if !found || m.better(best) {
+			best, found = m, true
+		}
+	}
+
+	for i, w := range cfg.Whitelist {
+		spec := -1
+		switch {
+		case w == proc:
+			spec = specExact
+		case cfg.MatchMode == matchPrefix && matchEntry(matchPrefix, proc, w):
+			spec = specPrefix
+		case cfg.MatchMode == matchSubstring && matchEntry(matchSubstring, proc, w):
+			spec = specSubstring
+		}
+		if spec >= 0 {
+			consider(ruleMatch{rule: w, priority: cfg.RulePriority[w], spec: spec, order: i})
+		}
+	}
+
+	if t := strings.ToLower(title); t != "" {
+		for i, r := range cfg.TitleRules {
+			if strings.Contains(t, r) {
+				name := titlePrefix + r
+				consider(ruleMatch{rule: name, priority: cfg.RulePriority[name], spec: specTitle, order: i})
+			}
+		}
+	}
+
+	if len(cfg.CmdlineRules) > 0 && cmdline != nil && (!found || cfg.maxCmdlinePriority() > best.priority) {
+		// 尽力而为：读不到就当 cmdline 规则没命中
+		if cl, err := cmdline(); err == nil && cl != "" {
+			cl = strings.ToLower(cl)
+			for i, r := range cfg.CmdlineRules {
+				if strings.Contains(cl, r) {
+					name := cmdlinePrefix + r
+					consider(ruleMatch{rule: name, priority: cfg.RulePriority[name], spec: specCmdline, order: i})
+				}
+			}
+		}
+	}
+
+	if !found {
+		return "", false
+	}
+	return best.rule, true
+}
+
+// MatchMemo 记住上一个前台进程名的匹配结果。焦点在两轮检查之间很少变化，
+// 同一进程连续命中时不必再扫一遍几百条白名单。
+// 配置重载后 cfg 指针变化即失效；有 cmdline/title 规则时不缓存（同名进程的命令行、标题可能不同）。
+type MatchMemo struct {
+	cfg  *Config
+	proc string
+	rule string
+	hit  bool
+}
+
+// match 带缓存的 matchWhitelist；m 为 nil 时直接匹配
+func (m *MatchMemo) match(cfg *Config, proc, title string, cmdline func() (string, error)) (string, bool) {
+	if m == nil || len(cfg.CmdlineRules) > 0 || len(cfg.TitleRules) > 0 {
+		return matchWhitelist(cfg, proc, title, cmdline)
+	}
+	if m.cfg != cfg || m.proc != proc {
+		m.rule, m.hit = matchWhitelist(cfg, proc, "", cmdline)
+		m.cfg, m.proc = cfg, proc
+	}
+	return m.rule, m.hit
+}
+
+// maxCmdlinePriority cmdline 规则中的最高优先级（没有 cmdline 规则时无意义）
+func (c *Config) maxCmdlinePriority() int {
+	max := 0
+	for i, r := range c.CmdlineRules {
+		if p := c.RulePriority[cmdlinePrefix+r]; i == 0 || p > max {
+			max = p
+		}
+	}
+	return max
+}
+
+// cutPriority 拆出规则末尾的优先级标记（" @10"，可为负），没有时优先级为 0；
+// " @" 后不是整数时报错（名字本身含 " @" 的要加引号）
+func cutPriority(s string) (string, int, error) {
+	i := strings.LastIndex(s, " @")
+	if i < 0 {
+		return s, 0, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s[i+2:]))
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid priority %q", strings.TrimSpace(s[i+1:]))
+	}
+	return strings.TrimSpace(s[:i]), n, nil
+}
+
+// ruleSummaryMax 启动日志里最多列出的规则数，其余只计数
+const ruleSummaryMax = 8
+
+// orderedRules 按决胜顺序（见 ruleMatch.better）列出所有规则，带优先级的附上 " @N"
+func orderedRules(cfg *Config) []string {
+	spec := specExact
+	switch cfg.MatchMode {
+	case matchPrefix:
+		spec = specPrefix
+	case matchSubstring:
+		spec = specSubstring
+	}
+	ms := make([]ruleMatch, 0, len(cfg.Whitelist)+len(cfg.CmdlineRules)+len(cfg.TitleRules))
+	for i, w := range cfg.Whitelist {
+		ms = append(ms, ruleMatch{rule: w, priority: cfg.RulePriority[w], spec: spec, order: i})
+	}
+	for i, r := range cfg.CmdlineRules {
+		name := cmdlinePrefix + r
+		ms = append(ms, ruleMatch{rule: name, priority: cfg.RulePriority[name], spec: specCmdline, order: i})
+	}
+	for i, r := range cfg.TitleRules {
+		name := titlePrefix + r
+		ms = append(ms, ruleMatch{rule: name, priority: cfg.RulePriority[name], spec: specTitle, order: i})
+	}
+	slices.SortStableFunc(ms, func(a, b ruleMatch) int {
+		switch {
+		case a.better(b):
+			return -1
+		case b.better(a):
+			return 1
+		}
+		return 0
+	})
+
+	out := make([]string, len(ms))
+	for i, m := range ms {
+		out[i] = m.rule
+		if m.priority != 0 {
+			out[i] += fmt.Sprintf(" @%d", m.priority)
+		}
+	}
+	return out
+}
+
+// ruleSummary 启动日志用的规则概要：总数 + 前 ruleSummaryMax 条
+func ruleSummary(cfg *Config) string {
+	rules := orderedRules(cfg)
+	if len(rules) == 0 {
+		return "（无，始终使用 default 配置）"
+	}
+	if len(rules) <= ruleSummaryMax {
+		return strings.Join(rules, ", ")
+	}
+	return fmt.Sprintf("%s …另 %d 条（完整列表见 -dump-config）",
+		strings.Join(rules[:ruleSummaryMax], ", "), len(rules)-ruleSummaryMax)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalizeProcName(t *testing.T) {
+	cases := []struct {
+		in, want string
+	}{
+		{"cs2.exe", "cs2.exe"},
+		{`C:\Games\cs2.exe`, "cs2.exe"},
+		{"C:/Games/cs2.exe", "cs2.exe"},
+		{"CS2.EXE", "cs2.exe"},
+		{"  cs2.exe\t", "cs2.exe"},
+		{` C:\Program Files\Steam\CS2.Exe `, "cs2.exe"},
+	}
+	for _, c := range cases {
+		if got := normalizeProcName(c.in); got != c.want {
+			t.Errorf("normalizeProcName(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+// 白名单按全路径、大小写混写、带空白书写时，都应命中前台的 cs2.exe
+func TestWhitelistNormalizedMatch(t *testing.T) {
+	for _, entry := range []string{`C:\Games\cs2.exe`, "CS2.EXE", "   cs2.exe   "} {
+		path := filepath.Join(t.TempDir(), "vaxee.conf")
+		if err := os.WriteFile(path, []byte(entry+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, _, err := loadConfig(path)
+		if err != nil {
+			t.Fatalf("%q: %v", entry, err)
+		}
+		if _, hit := matchWhitelist(cfg, normalizeProcName("CS2.exe"), "", nil); !hit {
+			t.Errorf("whitelist entry %q does not match foreground cs2.exe (whitelist %q)", entry, cfg.Whitelist)
+		}
+	}
+}
+
+// 几百条白名单（prefix 模式需逐条比较）下的匹配开销；带 Memo 的为主循环同一前台连续多轮的情形
+func BenchmarkMatchWhitelist(b *testing.B) {
+	cfg := &Config{MatchMode: matchPrefix, WhitelistSet: map[string]struct{}{}, RulePriority: map[string]int{}}
+	for i := 0; i < 500; i++ {
+		w := fmt.Sprintf("game%03d", i)
+		cfg.Whitelist = append(cfg.Whitelist, w)
+		cfg.WhitelistSet[w] = struct{}{}
+	}
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			matchWhitelist(cfg, "game499.exe", "", nil)
+		}
+	})
+	b.Run("memo", func(b *testing.B) {
+		var m MatchMemo
+		for i := 0; i < b.N; i++ {
+			m.match(cfg, "game499.exe", "", nil)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"log"
+	"sync"
+)
+
+// ==================== 配置错误提示 ====================
+// 重载失败时弹一次提示（同一个错误只提示一次，重载成功后重置），
+// 用户确认后用默认编辑器打开配置文件。弹窗在独立 goroutine 中，不阻塞主循环。
+
+type ConfigErrorNotifier struct {
+	mu      sync.Mutex
+	last    string // 已提示过的错误
+	showing bool   // 当前有弹窗未关闭
+}
+
+var configErrorNotifier ConfigErrorNotifier
+
+func (n *ConfigErrorNotifier) notify(cfgPath string, err error) {
+	msg := err.Error()
+	n.mu.Lock()
+	if n.showing || msg == n.last {
+		n.mu.Unlock()
+		return
+	}
+	n.last = msg
+	n.showing = true
+	n.mu.Unlock()
+
+	go func() {
+		defer func() {
+			n.mu.Lock()
+			n.showing = false
+			n.mu.Unlock()
+		}()
+		open, e := askOpenConfig(cfgPath, msg)
+		if e != nil {
+			log.Printf("[CFG] 无法显示配置错误提示：%v", e)
+			return
+		}
+		if !open {
+			return
+		}
+		if e := openInEditor(cfgPath); e != nil {
+			log.Printf("[ERR] 打开配置文件失败：%v", e)
+		}
+	}()
+}
+
+// reset 配置已成功加载：之后再出同样的错误也重新提示
+func (n *ConfigErrorNotifier) reset() {
+	n.mu.Lock()
+	n.last = ""
+	n.mu.Unlock()
+}
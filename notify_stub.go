@@ -0,0 +1,13 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+func askOpenConfig(cfgPath, msg string) (bool, error) {
+	return false, errors.New("config error notification is only supported on Windows")
+}
+
+func openInEditor(path string) error {
+	return errors.New("opening the config file is only supported on Windows")
+}
@@ -0,0 +1,73 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	user32NT = syscall.NewLazyDLL("user32.dll")
+	shell32  = syscall.NewLazyDLL("shell32.dll")
+
+	procMessageBoxW   = user32NT.NewProc("MessageBoxW")
+	procShellExecuteW = shell32.NewProc("ShellExecuteW")
+)
+
+const (
+	MB_YESNO       = 0x00000004
+	MB_ICONWARNING = 0x00000030
+	IDYES          = 6
+	SW_SHOWNORMAL  = 1
+)
+
+// askOpenConfig 弹出提示框，返回用户是否选择打开配置文件。
+// 不用 MB_SETFOREGROUND/MB_TOPMOST：游戏在前台时不抢焦点（否则本程序成了前台进程，会误切到未命中配置）
+func askOpenConfig(cfgPath, msg string) (bool, error) {
+	text := fmt.Sprintf("配置文件已修改，但加载失败（继续使用旧配置）：\n\n%s\n\n%s\n\n是否用默认编辑器打开配置文件？", msg, cfgPath)
+	pText, err := syscall.UTF16PtrFromString(text)
+	if err != nil {
+		return false, err
+	}
+	pTitle, _ := syscall.UTF16PtrFromString("vaxee-autoswitch 配置错误")
+	r, _, e := procMessageBoxW.Call(0,
+		uintptr(unsafe.Pointer(pText)),
+		uintptr(unsafe.Pointer(pTitle)),
+		MB_YESNO|MB_ICONWARNING)
+	if r == 0 {
+		return false, e
+	}
+	return r == IDYES, nil
+}
+
+// openInEditor 按文件关联打开（.conf 没有关联时退回记事本）
+func openInEditor(path string) error {
+	if err := shellOpen("open", path, ""); err == nil {
+		return nil
+	}
+	return shellOpen("open", "notepad.exe", syscall.EscapeArg(path))
+}
+
+func shellOpen(verb, file, params string) error {
+	pVerb, _ := syscall.UTF16PtrFromString(verb)
+	pFile, err := syscall.UTF16PtrFromString(file)
+	if err != nil {
+		return err
+	}
+	var pParams *uint16
+	if params != "" {
+		pParams, _ = syscall.UTF16PtrFromString(params)
+	}
+	r, _, _ := procShellExecuteW.Call(0,
+		uintptr(unsafe.Pointer(pVerb)),
+		uintptr(unsafe.Pointer(pFile)),
+		uintptr(unsafe.Pointer(pParams)),
+		0, SW_SHOWNORMAL)
+	// 返回值 <= 32 表示失败
+	if r <= 32 {
+		return fmt.Errorf("ShellExecute %s failed: %d", file, r)
+	}
+	return nil
+}
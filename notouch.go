@@ -0,0 +1,35 @@
+package main
+
+import (
+	"log"
+	"slices"
+)
+
+// ==================== 反作弊安全（no_touch_while_focused） ====================
+// 列表中的进程切到前台时照常下发一次；之后只要它还在前台就不再读写鼠标
+// （自动重发、唤醒后重发、trust_device_readback 读回都跳过），离开前台后恢复。
+// 手动覆盖（热键/管道命令）是用户主动操作，不受限制。
+
+type NoTouchGate struct {
+	proc string // 已完成首次下发、正在保持不动的进程
+}
+
+var noTouchGate NoTouchGate
+
+// hold 当前前台已完成首次下发，本轮不访问设备
+func (g *NoTouchGate) hold(cfg *Config, proc string) bool {
+	if g.proc != "" && g.proc != proc {
+		log.Printf("[SAFE] %s 已离开前台，恢复正常切换。", g.proc)
+		g.proc = ""
+	}
+	return g.proc != "" && slices.Contains(cfg.NoTouchProcs, proc)
+}
+
+// touched 前台的目标配置已生效；是列表中的进程就开始保持不动
+func (g *NoTouchGate) touched(cfg *Config, proc string) {
+	if g.proc == proc || !slices.Contains(cfg.NoTouchProcs, proc) {
+		return
+	}
+	g.proc = proc
+	log.Printf("[SAFE] %s 在前台：已下发，之后不再访问鼠标直到它离开前台。", proc)
+}
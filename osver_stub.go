@@ -0,0 +1,7 @@
+//go:build !windows
+
+package main
+
+func osVersion() string {
+	return ""
+}
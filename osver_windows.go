@@ -0,0 +1,35 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	ntdllOV = syscall.NewLazyDLL("ntdll.dll")
+
+	procRtlGetVersion = ntdllOV.NewProc("RtlGetVersion")
+)
+
+// RTL_OSVERSIONINFOW（GetVersionEx 受兼容性清单影响，RtlGetVersion 返回真实版本）
+type RTL_OSVERSIONINFOW struct {
+	OSVersionInfoSize uint32
+	MajorVersion      uint32
+	MinorVersion      uint32
+	BuildNumber       uint32
+	PlatformId        uint32
+	CSDVersion        [128]uint16
+}
+
+// osVersion Windows 版本号，如 "Windows 10.0.22631"
+func osVersion() string {
+	var vi RTL_OSVERSIONINFOW
+	vi.OSVersionInfoSize = uint32(unsafe.Sizeof(vi))
+	if st, _, _ := procRtlGetVersion.Call(uintptr(unsafe.Pointer(&vi))); st != 0 {
+		return "Windows（版本未知）"
+	}
+	return fmt.Sprintf("Windows %d.%d.%d", vi.MajorVersion, vi.MinorVersion, vi.BuildNumber)
+}
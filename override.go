@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// 热键事件编号（与 hotkeyList 返回顺序一致）
+const (
+	hotkeyCycle = iota
+	hotkeyRelease
+)
+
+// ManualOverride 手动覆盖状态：active 时暂停自动切换
+type ManualOverride struct {
+	active bool
+	idx    int
+	custom *Profile // 外部命令（pipe apply）指定的配置；热键操作后清除
+}
+
+// hotkeyList 按事件编号顺序返回要注册的热键（release 可缺省）
+func hotkeyList(cfg *Config) []Hotkey {
+	if cfg.HotkeyCycle == nil {
+		return nil
+	}
+	keys := []Hotkey{*cfg.HotkeyCycle}
+	if cfg.HotkeyRelease != nil {
+		keys = append(keys, *cfg.HotkeyRelease)
+	}
+	return keys
+}
+
+// restartHotkeys 按当前配置（重新）注册热键
+func restartHotkeys(old *HotkeyListener, cfg *Config) *HotkeyListener {
+	old.Stop()
+	keys := hotkeyList(cfg)
+	if len(keys) == 0 {
+		return nil
+	}
+	l, err := StartHotkeys(keys)
+	if err != nil {
+		log.Printf("[HOTKEY] 注册热键失败：%v", err)
+		return nil
+	}
+	if cfg.HotkeyRelease != nil {
+		log.Printf("[HOTKEY] cycle=%s release=%s", cfg.HotkeyCycle.Spec, cfg.HotkeyRelease.Spec)
+	} else {
+		log.Printf("[HOTKEY] cycle=%s（未配置 release，无法用热键恢复自动）", cfg.HotkeyCycle.Spec)
+	}
+	return l
+}
+
+// handleHotkey 处理一次热键：循环到下一个手动配置，或释放手动覆盖
+func (o *ManualOverride) handleHotkey(ctx context.Context, cfg *Config, last *Applied, st *State, ev int) (msg string, errStr string) {
+	switch ev {
+	case hotkeyCycle:
+		if len(cfg.ManualProfiles) == 0 {
+			return "", ""
+		}
+		if o.active && o.custom == nil {
+			o.idx = (o.idx + 1) % len(cfg.ManualProfiles)
+		} else {
+			o.active = true
+			o.idx = 0
+		}
+		o.custom = nil
+		return o.apply(ctx, cfg, last, st)
+
+	case hotkeyRelease:
+		if !o.active {
+			return "", ""
+		}
+		return o.release(st), ""
+	}
+	return "", ""
+}
+
+// release 退出手动覆盖
+func (o *ManualOverride) release(st *State) string {
+	o.active, o.custom = false, nil
+	st.update(func(s *StatusSnapshot) { s.Manual = false })
+	return "[MANUAL] 已退出手动覆盖，恢复自动切换。"
+}
+
+// applyCustom 进入手动覆盖并下发外部指定的配置
+func (o *ManualOverride) applyCustom(ctx context.Context, cfg *Config, last *Applied, st *State, p Profile) (msg string, errStr string) {
+	o.active, o.custom = true, &p
+	return o.apply(ctx, cfg, last, st)
+}
+
+// apply 下发当前手动配置（配置重载后列表可能变短，先收敛下标）
+func (o *ManualOverride) apply(ctx context.Context, cfg *Config, last *Applied, st *State) (msg string, errStr string) {
+	var p Profile
+	if o.custom != nil {
+		p = *o.custom
+	} else {
+		if o.idx >= len(cfg.ManualProfiles) {
+			o.idx = 0
+		}
+		p = cfg.ManualProfiles[o.idx]
+	}
+	p = capForApply(cfg, p) // 缓存与状态里记下实际下发的值
+	st.update(func(s *StatusSnapshot) {
+		s.Manual, s.ManualProfile = true, p
+	})
+
+	dev, findErr := FindOneVaxeeDevice(ctx, cfg.Device)
+	if findErr != nil {
+		return "", "未找到可用 VAXEE 设备：" + findErr.Error()
+	}
+	applyErr := applyProfile(ctx, cfg, dev.Path, p)
+	recordSwitch(cfg, "(manual)", *last, p, applyErr)
+	last.update(p, applyErr)
+	if applyErr != nil {
+		return "", "应用设置失败：" + applyErr.Error()
+	}
+	st.recordApplied(p, dev.Path)
+
+	if o.custom != nil {
+		return fmt.Sprintf("[MANUAL] 手动覆盖（外部命令）-> %s（自动切换已暂停）", profileName(p)), ""
+	}
+	return fmt.Sprintf("[MANUAL] 手动覆盖 #%d/%d -> %s（自动切换已暂停）",
+		o.idx+1, len(cfg.ManualProfiles), profileName(p)), ""
+}
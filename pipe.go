@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ==================== 命名管道命令（pipe_name） ====================
+// 给 Stream Deck 插件等本地程序用的轻量 IPC：每行一条命令，每条回复一行（OK ... / ERR ...）。
+// 管道服务只负责收发，命令统一交给主循环执行，设备访问仍与自动切换串行，不会并发下发。
+//
+//   apply <mode> <poll>   进入手动覆盖并下发，如 apply competitive_ms_off 4000
+//   status                当前状态（JSON）
+//   reload                立即重新加载配置文件
+//   pause / resume        暂停 / 恢复自动切换（resume 同时退出手动覆盖）
+
+// PipeRequest 一条来自管道的命令；主循环处理后把一行结果写回 Reply
+type PipeRequest struct {
+	Line  string
+	Reply chan string
+}
+
+// pipeStatus status 命令的回复内容
+type pipeStatus struct {
+	statusJSON
+	Paused bool `json:"paused"`
+}
+
+// handlePipeCommand 在主循环中执行一条管道命令，返回回复行
+func handlePipeCommand(ctx context.Context, cfg *Config, last *Applied, st *State, o *ManualOverride,
+	paused *bool, reload func() bool, line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "ERR 空命令"
+	}
+	switch strings.ToLower(fields[0]) {
+	case "apply":
+		if len(fields) != 3 {
+			return "ERR 用法：apply <mode> <poll>"
+		}
+		p, err := parseProfile(fields[1] + ":" + fields[2])
+		if err != nil {
+			return "ERR " + err.Error()
+		}
+		msg, errStr := o.applyCustom(ctx, cfg, last, st, p)
+		if errStr != "" {
+			log.Printf("[PIPE] apply 失败：%s", errStr)
+			return "ERR " + errStr
+		}
+		log.Print(msg)
+		return "OK " + profileName(p)
+
+	case "status":
+		data, err := json.Marshal(pipeStatus{newStatusJSON(st.Snapshot()), *paused})
+		if err != nil {
+			return "ERR " + err.Error()
+		}
+		return "OK " + string(data)
+
+	case "reload":
+		if !reload() {
+			return "ERR 重新加载失败（详见日志）"
+		}
+		return "OK 已重新加载"
+
+	case "pause":
+		if !*paused {
+			*paused = true
+			log.Printf("[PIPE] 已暂停自动切换。")
+		}
+		return "OK 已暂停自动切换"
+
+	case "resume":
+		if o.active {
+			log.Print(o.release(st))
+		}
+		if *paused {
+			*paused = false
+			log.Printf("[PIPE] 已恢复自动切换。")
+		}
+		return "OK 已恢复自动切换"
+	}
+	return fmt.Sprintf("ERR 未知命令：%s（apply / status / reload / pause / resume）", fields[0])
+}
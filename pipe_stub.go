@@ -0,0 +1,17 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+type PipeServer struct{}
+
+func StartPipeServer(name string) (*PipeServer, error) {
+	return nil, errors.New("named pipes are only supported on Windows")
+}
+
+func (s *PipeServer) Requests() <-chan PipeRequest {
+	return nil
+}
+
+func (s *PipeServer) Stop() {}
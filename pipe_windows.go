@@ -0,0 +1,172 @@
+//go:build windows
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	k32NP = syscall.NewLazyDLL("kernel32.dll")
+
+	procCreateNamedPipeW  = k32NP.NewProc("CreateNamedPipeW")
+	procConnectNamedPipe  = k32NP.NewProc("ConnectNamedPipe")
+	procCloseHandlePipeNP = k32NP.NewProc("CloseHandle")
+)
+
+const (
+	PIPE_ACCESS_DUPLEX            = 0x00000003
+	FILE_FLAG_FIRST_PIPE_INSTANCE = 0x00080000
+	PIPE_TYPE_BYTE                = 0x00000000
+	PIPE_WAIT                     = 0x00000000
+	PIPE_REJECT_REMOTE_CLIENTS    = 0x00000008
+	PIPE_UNLIMITED_INSTANCES      = 255
+
+	ERROR_PIPE_CONNECTED syscall.Errno = 535
+
+	pipeBufSize = 4096
+)
+
+// PipeServer 命名管道服务：每个客户端一个 goroutine，命令经 Requests() 交给主循环
+type PipeServer struct {
+	name string
+	c    chan PipeRequest
+	quit chan struct{}
+	done chan struct{}
+}
+
+// StartPipeServer 创建管道并开始接受连接；名字不合法或已被其它进程占用时返回错误
+func StartPipeServer(name string) (*PipeServer, error) {
+	h, err := createPipeInstance(name, true)
+	if err != nil {
+		return nil, err
+	}
+	s := &PipeServer{name: name, c: make(chan PipeRequest), quit: make(chan struct{}), done: make(chan struct{})}
+	go s.serve(h)
+	return s, nil
+}
+
+func createPipeInstance(name string, first bool) (syscall.Handle, error) {
+	p, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return syscall.InvalidHandle, err
+	}
+	mode := uintptr(PIPE_ACCESS_DUPLEX)
+	if first {
+		mode |= FILE_FLAG_FIRST_PIPE_INSTANCE
+	}
+	r1, _, e := procCreateNamedPipeW.Call(
+		uintptr(unsafe.Pointer(p)),
+		mode,
+		PIPE_TYPE_BYTE|PIPE_WAIT|PIPE_REJECT_REMOTE_CLIENTS, // 只接受本机连接
+		PIPE_UNLIMITED_INSTANCES,
+		pipeBufSize, pipeBufSize, 0, 0,
+	)
+	if syscall.Handle(r1) == syscall.InvalidHandle {
+		return syscall.InvalidHandle, e
+	}
+	return syscall.Handle(r1), nil
+}
+
+// serve 循环等待客户端；每接入一个就再建一个新实例继续等
+func (s *PipeServer) serve(h syscall.Handle) {
+	defer close(s.done)
+	for {
+		r1, _, e := procConnectNamedPipe.Call(uintptr(h), 0)
+		connected := r1 != 0 || e == ERROR_PIPE_CONNECTED
+		select {
+		case <-s.quit:
+			procCloseHandlePipeNP.Call(uintptr(h))
+			return
+		default:
+		}
+		if connected {
+			go s.handleClient(h)
+		} else {
+			procCloseHandlePipeNP.Call(uintptr(h))
+		}
+
+		var err error
+		if h, err = createPipeInstance(s.name, false); err != nil {
+			log.Printf("[PIPE] 创建管道实例失败，停止接受新连接：%v", err)
+			return
+		}
+	}
+}
+
+// pipeConn 同步读写管道句柄
+type pipeConn syscall.Handle
+
+func (c pipeConn) Read(p []byte) (int, error) {
+	var n uint32
+	if err := syscall.ReadFile(syscall.Handle(c), p, &n, nil); err != nil {
+		return int(n), err
+	}
+	if n == 0 {
+		return 0, io.EOF
+	}
+	return int(n), nil
+}
+
+func (c pipeConn) Write(p []byte) (int, error) {
+	var n uint32
+	err := syscall.WriteFile(syscall.Handle(c), p, &n, nil)
+	return int(n), err
+}
+
+// handleClient 逐行读取命令，等主循环执行完再回复；客户端断开即结束
+func (s *PipeServer) handleClient(h syscall.Handle) {
+	defer procCloseHandlePipeNP.Call(uintptr(h))
+	conn := pipeConn(h)
+	sc := bufio.NewScanner(conn)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		req := PipeRequest{Line: line, Reply: make(chan string, 1)}
+		select {
+		case s.c <- req:
+		case <-s.quit:
+			return
+		}
+		var reply string
+		select {
+		case reply = <-req.Reply:
+		case <-s.quit:
+			return
+		}
+		if _, err := io.WriteString(conn, reply+"\n"); err != nil {
+			return
+		}
+	}
+}
+
+// Requests 待主循环处理的命令；s 为 nil 时返回 nil channel
+func (s *PipeServer) Requests() <-chan PipeRequest {
+	if s == nil {
+		return nil
+	}
+	return s.c
+}
+
+// Stop 停止接受连接（阻塞在 ConnectNamedPipe 上的等待由一次自连接唤醒）
+func (s *PipeServer) Stop() {
+	if s == nil {
+		return
+	}
+	close(s.quit)
+	p, err := syscall.UTF16PtrFromString(s.name)
+	if err == nil {
+		h, err := syscall.CreateFile(p, syscall.GENERIC_READ|syscall.GENERIC_WRITE, 0, nil, syscall.OPEN_EXISTING, 0, 0)
+		if err == nil {
+			syscall.CloseHandle(h)
+		}
+	}
+	<-s.done
+}
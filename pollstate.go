@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// ==================== 设备状态监视（-poll-state） ====================
+// 排查“是不是游戏/驱动在改我的鼠标”：按间隔读回设备当前的性能模式/回报率/消抖并打印，
+// 和上一次不同时高亮。只发读请求（与 trust_device_readback 相同），不下发任何设置。
+
+const minPollStateInterval = 100 * time.Millisecond
+
+// runPollState 一直运行到 Ctrl+C，返回退出码
+func runPollState(ctx context.Context, cfgPath string, interval time.Duration) int {
+	cfg, _, err := loadConfig(cfgPath)
+	if err != nil {
+		log.Printf("[ERR] 读取配置失败：%v", err)
+		return 1
+	}
+	applySessionFlags(cfg)
+	applyLogOptions(cfg.Log)
+	deviceThread.setEnabled(cfg.ApplyThread)
+	if interval < minPollStateInterval {
+		log.Printf("[ERR] -poll-state 间隔不能小于 %s", minPollStateInterval)
+		return 2
+	}
+	// rewrite/verify 选择控制通道时会写回设置；这里只读，固定用第一个可用集合
+	opts := cfg.Device
+	opts.ControlSelect = controlSelectFirst
+
+	log.Printf("[STATE] 每 %s 读回一次设备状态（只读，不下发），按 Ctrl+C 退出。", interval)
+
+	var (
+		dev     VaxeeDeviceInfo
+		prev    Profile
+		have    bool
+		lastErr string
+		samples int
+		changes int
+	)
+	report := func(msg string) {
+		if msg != lastErr {
+			lastErr = msg
+			log.Printf("[STATE] %s", msg)
+		}
+	}
+	for {
+		if dev.Path == "" {
+			d, err := FindOneVaxeeDevice(ctx, opts)
+			if err != nil {
+				if ctx.Err() == nil {
+					report(fmt.Sprintf("未找到可用 VAXEE 设备：%v", err))
+				}
+			} else {
+				dev = d
+				log.Printf("[STATE] 设备：%s", dev.Path)
+			}
+		}
+		if dev.Path != "" {
+			got, err := ReadCurrentSettings(ctx, dev.Path, opts, int(dev.FeatureLen))
+			switch {
+			case ctx.Err() != nil:
+			case err != nil:
+				// 可能被拔出或重新枚举，下一轮重新查找
+				report(fmt.Sprintf("读回失败：%v", err))
+				dev = VaxeeDeviceInfo{}
+			default:
+				lastErr = ""
+				samples++
+				switch {
+				case !have:
+					log.Printf("[STATE] %s", profileName(got))
+				case got != prev:
+					changes++
+					log.Printf("[STATE] %s  <<< 变化：%s", profileName(got), stateDiff(prev, got))
+				default:
+					log.Printf("[STATE] %s", profileName(got))
+				}
+				prev, have = got, true
+			}
+		}
+
+		if err := sleepCtx(ctx, interval); err != nil {
+			log.Printf("[STATE] 共读回 %d 次，其中变化 %d 次。", samples, changes)
+			return 0
+		}
+	}
+}
+
+// stateDiff 列出两次读回之间变化的项
+func stateDiff(a, b Profile) string {
+	var parts []string
+	if a.Perf != b.Perf {
+		parts = append(parts, fmt.Sprintf("性能模式 %s -> %s", perfName(a.Perf), perfName(b.Perf)))
+	}
+	if a.Poll != b.Poll {
+		parts = append(parts, fmt.Sprintf("回报率 %dHz -> %dHz", a.Poll, b.Poll))
+	}
+	if a.Debounce != b.Debounce {
+		parts = append(parts, fmt.Sprintf("消抖 %dms -> %dms", a.Debounce, b.Debounce))
+	}
+	return strings.Join(parts, "，")
+}
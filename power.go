@@ -0,0 +1,16 @@
+package main
+
+// 电源事件（PowerWatcher.Events）
+const (
+	powerStatusChanged = iota // AC/电池切换
+	powerResumed              // 从睡眠/休眠唤醒
+)
+
+// onBattery 配置了电池专用项时才查询电源状态；查询失败按交流电处理
+func onBattery(cfg *Config) bool {
+	if !cfg.hasBatteryVariants() {
+		return false
+	}
+	b, err := OnBatteryPower()
+	return err == nil && b
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+func OnBatteryPower() (bool, error) {
+	return false, errors.New("power status is only supported on Windows")
+}
+
+type PowerWatcher struct{}
+
+func StartPowerWatcher() (*PowerWatcher, error) {
+	return nil, errors.New("power notifications are only supported on Windows")
+}
+
+func (w *PowerWatcher) Events() <-chan int {
+	return nil
+}
+
+func (w *PowerWatcher) Stop() {}
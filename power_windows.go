@@ -0,0 +1,213 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	user32PW = syscall.NewLazyDLL("user32.dll")
+	k32PW    = syscall.NewLazyDLL("kernel32.dll")
+
+	procGetSystemPowerStatus = k32PW.NewProc("GetSystemPowerStatus")
+	procGetModuleHandleW     = k32PW.NewProc("GetModuleHandleW")
+
+	procRegisterClassExW = user32PW.NewProc("RegisterClassExW")
+	procCreateWindowExW  = user32PW.NewProc("CreateWindowExW")
+	procDestroyWindow    = user32PW.NewProc("DestroyWindow")
+	procDefWindowProcW   = user32PW.NewProc("DefWindowProcW")
+	procDispatchMessageW = user32PW.NewProc("DispatchMessageW")
+	procPostMessageW     = user32PW.NewProc("PostMessageW")
+	procPostQuitMessage  = user32PW.NewProc("PostQuitMessage")
+)
+
+const (
+	WM_DESTROY        = 0x0002
+	WM_CLOSE          = 0x0010
+	WM_POWERBROADCAST = 0x0218
+
+	PBT_APMPOWERSTATUSCHANGE = 0x000A
+	PBT_APMRESUMEAUTOMATIC   = 0x0012 // 任何唤醒都会发送（RESUMESUSPEND 只在有用户输入时才有）
+
+	AC_LINE_OFFLINE = 0
+	AC_LINE_ONLINE  = 1
+)
+
+type SYSTEM_POWER_STATUS struct {
+	ACLineStatus        byte
+	BatteryFlag         byte
+	BatteryLifePercent  byte
+	SystemStatusFlag    byte
+	BatteryLifeTime     uint32
+	BatteryFullLifeTime uint32
+}
+
+type WNDCLASSEXW struct {
+	CbSize        uint32
+	Style         uint32
+	LpfnWndProc   uintptr
+	CbClsExtra    int32
+	CbWndExtra    int32
+	HInstance     uintptr
+	HIcon         uintptr
+	HCursor       uintptr
+	HbrBackground uintptr
+	LpszMenuName  *uint16
+	LpszClassName *uint16
+	HIconSm       uintptr
+}
+
+// OnBatteryPower 当前是否使用电池供电（ACLineStatus=0）；状态未知时返回错误
+func OnBatteryPower() (bool, error) {
+	var st SYSTEM_POWER_STATUS
+	r1, _, e := procGetSystemPowerStatus.Call(uintptr(unsafe.Pointer(&st)))
+	if r1 == 0 {
+		return false, e
+	}
+	switch st.ACLineStatus {
+	case AC_LINE_OFFLINE:
+		return true, nil
+	case AC_LINE_ONLINE:
+		return false, nil
+	}
+	return false, fmt.Errorf("unknown AC line status: %d", st.ACLineStatus)
+}
+
+// PowerWatcher 用一个隐藏的顶层窗口接收 WM_POWERBROADCAST。
+// 注意：HWND_MESSAGE 的 message-only 窗口收不到广播消息，所以这里是不显示的普通窗口。
+type PowerWatcher struct {
+	hwnd uintptr
+	c    chan int
+	done chan struct{}
+}
+
+var (
+	powerWndProcOnce sync.Once
+	powerWndProcPtr  uintptr
+	powerClassName   = syscall.StringToUTF16Ptr("VaxeeAutoSwitchPowerWnd")
+
+	// 当前活动的 watcher（窗口过程是全局回调，只能靠它找回 channel）
+	activePowerMu sync.Mutex
+	activePower   *PowerWatcher
+)
+
+func powerWndProc(hwnd, msg, wParam, lParam uintptr) uintptr {
+	switch msg {
+	case WM_POWERBROADCAST:
+		activePowerMu.Lock()
+		w := activePower
+		activePowerMu.Unlock()
+		if w != nil {
+			switch wParam {
+			case PBT_APMPOWERSTATUSCHANGE:
+				w.post(powerStatusChanged)
+			case PBT_APMRESUMEAUTOMATIC:
+				w.post(powerResumed)
+			}
+		}
+		return 1 // TRUE
+	case WM_CLOSE:
+		procDestroyWindow.Call(hwnd)
+		return 0
+	case WM_DESTROY:
+		procPostQuitMessage.Call(0)
+		return 0
+	}
+	r, _, _ := procDefWindowProcW.Call(hwnd, msg, wParam, lParam)
+	return r
+}
+
+func (w *PowerWatcher) post(ev int) {
+	select {
+	case w.c <- ev:
+	default:
+	}
+}
+
+// StartPowerWatcher 创建隐藏窗口并在独立 OS 线程上跑消息循环
+func StartPowerWatcher() (*PowerWatcher, error) {
+	powerWndProcOnce.Do(func() {
+		powerWndProcPtr = syscall.NewCallback(powerWndProc)
+	})
+
+	w := &PowerWatcher{c: make(chan int, 4), done: make(chan struct{})}
+	ready := make(chan error, 1)
+
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		defer close(w.done)
+
+		hInst, _, _ := procGetModuleHandleW.Call(0)
+		wc := WNDCLASSEXW{
+			LpfnWndProc:   powerWndProcPtr,
+			HInstance:     hInst,
+			LpszClassName: powerClassName,
+		}
+		wc.CbSize = uint32(unsafe.Sizeof(wc))
+		procRegisterClassExW.Call(uintptr(unsafe.Pointer(&wc))) // 重复注册失败无所谓
+
+		hwnd, _, e := procCreateWindowExW.Call(
+			0,
+			uintptr(unsafe.Pointer(powerClassName)),
+			uintptr(unsafe.Pointer(powerClassName)),
+			0, // 不带 WS_VISIBLE，也不 ShowWindow
+			0, 0, 0, 0,
+			0, 0, hInst, 0,
+		)
+		if hwnd == 0 {
+			ready <- fmt.Errorf("CreateWindowExW failed: %v", e)
+			return
+		}
+		w.hwnd = hwnd
+
+		activePowerMu.Lock()
+		activePower = w
+		activePowerMu.Unlock()
+		defer func() {
+			activePowerMu.Lock()
+			if activePower == w {
+				activePower = nil
+			}
+			activePowerMu.Unlock()
+		}()
+		ready <- nil
+
+		var msg MSG
+		for {
+			r1, _, _ := procGetMessageW.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+			if int32(r1) <= 0 {
+				return
+			}
+			procDispatchMessageW.Call(uintptr(unsafe.Pointer(&msg)))
+		}
+	}()
+
+	if err := <-ready; err != nil {
+		<-w.done
+		return nil, err
+	}
+	return w, nil
+}
+
+// Events 电源事件；w 为 nil 时返回 nil channel
+func (w *PowerWatcher) Events() <-chan int {
+	if w == nil {
+		return nil
+	}
+	return w.c
+}
+
+// Stop 关闭隐藏窗口并结束消息循环
+func (w *PowerWatcher) Stop() {
+	if w == nil {
+		return
+	}
+	procPostMessageW.Call(w.hwnd, WM_CLOSE, 0, 0)
+	<-w.done
+}
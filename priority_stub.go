@@ -0,0 +1,9 @@
+//go:build !windows
+
+package main
+
+// setLowPriorityDefaults 非 Windows 平台不调整优先级
+func setLowPriorityDefaults(enableBackgroundMode bool, enableEcoQoS bool) {}
+
+// setDeviceThreadPriority 非 Windows 平台不调整优先级
+func setDeviceThreadPriority() {}
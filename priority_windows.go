@@ -0,0 +1,172 @@
+//go:build windows
+
+package main
+
+import (
+	"log"
+	"syscall"
+	"unsafe"
+)
+
+// Windows API 相关常量和变量
+var (
+	kernel32DLL = syscall.NewLazyDLL("kernel32.dll")
+
+	// Windows API 函数
+	procGetCurrentProcess     = kernel32DLL.NewProc("GetCurrentProcess")
+	procGetCurrentThread      = kernel32DLL.NewProc("GetCurrentThread")
+	procSetPriorityClass      = kernel32DLL.NewProc("SetPriorityClass")
+	procSetThreadPriority     = kernel32DLL.NewProc("SetThreadPriority")
+	procSetProcessInformation = kernel32DLL.NewProc("SetProcessInformation")
+	procSetThreadInformation  = kernel32DLL.NewProc("SetThreadInformation")
+)
+
+// Windows 优先级常量
+const (
+	// SetPriorityClass dwPriorityClass
+	IDLE_PRIORITY_CLASS           = 0x00000040
+	BELOW_NORMAL_PRIORITY_CLASS   = 0x00004000
+	PROCESS_MODE_BACKGROUND_BEGIN = 0x00100000
+
+	// SetThreadPriority nPriority
+	THREAD_PRIORITY_LOWEST       = -2
+	THREAD_PRIORITY_IDLE         = -15
+	THREAD_MODE_BACKGROUND_BEGIN = 0x00010000
+
+	// SetProcessInformation ProcessInformationClass
+	ProcessPowerThrottling = 4
+
+	// SetThreadInformation ThreadInformationClass
+	ThreadPowerThrottling = 5
+
+	// PROCESS/THREAD_POWER_THROTTLING_STATE
+	PROCESS_POWER_THROTTLING_CURRENT_VERSION = 1
+	PROCESS_POWER_THROTTLING_EXECUTION_SPEED = 0x1
+
+	THREAD_POWER_THROTTLING_CURRENT_VERSION = 1
+	THREAD_POWER_THROTTLING_EXECUTION_SPEED = 0x1
+)
+
+// Windows 结构体定义
+type PROCESS_POWER_THROTTLING_STATE struct {
+	Version     uint32
+	ControlMask uint32
+	StateMask   uint32
+}
+
+type THREAD_POWER_THROTTLING_STATE struct {
+	Version     uint32
+	ControlMask uint32
+	StateMask   uint32
+}
+
+// u32ptrFromI32 将 int32 转换为 uintptr
+func u32ptrFromI32(v int32) uintptr {
+	return uintptr(uint32(v))
+}
+
+// ==================== Windows 优先级设置 ====================
+
+// setLowPriorityDefaults 设置低优先级默认值
+func setLowPriorityDefaults(enableBackgroundMode bool, enableEcoQoS bool) {
+	// 获取当前进程和线程句柄
+	hProc, _, _ := procGetCurrentProcess.Call()
+	hThread, _, _ := procGetCurrentThread.Call()
+
+	// 1. 设置进程优先级为 BELOW_NORMAL
+	if r, _, e := procSetPriorityClass.Call(hProc, uintptr(BELOW_NORMAL_PRIORITY_CLASS)); r == 0 {
+		log.Printf("[PRIO] SetPriorityClass(BELOW_NORMAL) failed: %v", e)
+	} else {
+		log.Printf("[PRIO] Process priority set to BELOW_NORMAL.")
+	}
+
+	// 2. 设置线程优先级为 LOWEST
+	if r, _, e := procSetThreadPriority.Call(hThread, uintptr(u32ptrFromI32(THREAD_PRIORITY_LOWEST))); r == 0 {
+		log.Printf("[PRIO] SetThreadPriority(LOWEST) failed: %v", e)
+	} else {
+		log.Printf("[PRIO] Thread priority set to LOWEST.")
+	}
+
+	// 3. 可选：启用后台处理模式
+	if enableBackgroundMode {
+		if r, _, e := procSetPriorityClass.Call(hProc, uintptr(PROCESS_MODE_BACKGROUND_BEGIN)); r == 0 {
+			log.Printf("[PRIO] PROCESS_MODE_BACKGROUND_BEGIN failed: %v", e)
+		} else {
+			log.Printf("[PRIO] Process background mode enabled.")
+		}
+
+		if r, _, e := procSetThreadPriority.Call(hThread, uintptr(THREAD_MODE_BACKGROUND_BEGIN)); r == 0 {
+			log.Printf("[PRIO] THREAD_MODE_BACKGROUND_BEGIN failed: %v", e)
+		} else {
+			log.Printf("[PRIO] Thread background mode enabled.")
+		}
+	}
+
+	// 4. 可选：启用 EcoQoS/执行速度节流（Windows 8 之前没有这两个 API）
+	if enableEcoQoS {
+		if procSetProcessInformation.Find() != nil {
+			log.Printf("[PRIO] 当前系统不支持 SetProcessInformation，跳过进程 EcoQoS。")
+		} else {
+			setProcessPowerThrottling(hProc)
+		}
+		if procSetThreadInformation.Find() != nil {
+			log.Printf("[PRIO] 当前系统不支持 SetThreadInformation，跳过线程 EcoQoS。")
+		} else {
+			setThreadPowerThrottling(hThread)
+		}
+	}
+}
+
+// setProcessPowerThrottling 设置进程电源节流
+func setProcessPowerThrottling(hProc uintptr) {
+	state := PROCESS_POWER_THROTTLING_STATE{
+		Version:     PROCESS_POWER_THROTTLING_CURRENT_VERSION,
+		ControlMask: PROCESS_POWER_THROTTLING_EXECUTION_SPEED,
+		StateMask:   PROCESS_POWER_THROTTLING_EXECUTION_SPEED,
+	}
+
+	r, _, e := procSetProcessInformation.Call(
+		hProc,
+		uintptr(ProcessPowerThrottling),
+		uintptr(unsafe.Pointer(&state)),
+		unsafe.Sizeof(state),
+	)
+
+	if r == 0 {
+		log.Printf("[PRIO] Process EcoQoS/PowerThrottling failed: %v", e)
+	} else {
+		log.Printf("[PRIO] Process EcoQoS/PowerThrottling enabled.")
+	}
+}
+
+// setThreadPowerThrottling 设置线程电源节流
+func setThreadPowerThrottling(hThread uintptr) {
+	state := THREAD_POWER_THROTTLING_STATE{
+		Version:     THREAD_POWER_THROTTLING_CURRENT_VERSION,
+		ControlMask: THREAD_POWER_THROTTLING_EXECUTION_SPEED,
+		StateMask:   THREAD_POWER_THROTTLING_EXECUTION_SPEED,
+	}
+
+	_, _, _ = procSetThreadInformation.Call(
+		hThread,
+		uintptr(ThreadPowerThrottling),
+		uintptr(unsafe.Pointer(&state)),
+		unsafe.Sizeof(state),
+	)
+	// 线程侧失败也无所谓，不影响主流程
+}
+
+// setDeviceThreadPriority 设备线程（见 DeviceThread）：与主线程一样设为 LOWEST 并开启线程级节流。
+// 不进入 THREAD_MODE_BACKGROUND：后台模式会压低 I/O 优先级，下发可能被明显推迟
+func setDeviceThreadPriority() {
+	hThread, _, _ := procGetCurrentThread.Call()
+	if r, _, e := procSetThreadPriority.Call(hThread, uintptr(u32ptrFromI32(THREAD_PRIORITY_LOWEST))); r == 0 {
+		log.Printf("[PRIO] 设备线程 SetThreadPriority(LOWEST) failed: %v", e)
+	}
+	if procSetThreadInformation.Find() != nil {
+		debugf("设备线程已就绪（LOWEST）")
+		return
+	}
+	setThreadPowerThrottling(hThread)
+	debugf("设备线程已就绪（LOWEST + EcoQoS）")
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+func RunningProcessNames() (map[string]struct{}, error) {
+	return nil, errors.New("process enumeration is only supported on Windows")
+}
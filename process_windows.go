@@ -0,0 +1,56 @@
+//go:build windows
+
+package main
+
+import (
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	k32PS = syscall.NewLazyDLL("kernel32.dll")
+
+	procCreateToolhelp32Snapshot = k32PS.NewProc("CreateToolhelp32Snapshot")
+	procProcess32FirstW          = k32PS.NewProc("Process32FirstW")
+	procProcess32NextW           = k32PS.NewProc("Process32NextW")
+	procCloseHandlePS            = k32PS.NewProc("CloseHandle")
+)
+
+const TH32CS_SNAPPROCESS = 0x00000002
+
+// PROCESSENTRY32W（tlhelp32.h）
+type PROCESSENTRY32W struct {
+	Size            uint32
+	Usage           uint32
+	ProcessID       uint32
+	DefaultHeapID   uintptr
+	ModuleID        uint32
+	Threads         uint32
+	ParentProcessID uint32
+	PriClassBase    int32
+	Flags           uint32
+	ExeFile         [260]uint16
+}
+
+// RunningProcessNames 当前所有进程的 exe 名（小写）
+func RunningProcessNames() (map[string]struct{}, error) {
+	snap, _, err := procCreateToolhelp32Snapshot.Call(TH32CS_SNAPPROCESS, 0)
+	if snap == uintptr(syscall.InvalidHandle) {
+		return nil, err
+	}
+	defer procCloseHandlePS.Call(snap)
+
+	names := map[string]struct{}{}
+	var pe PROCESSENTRY32W
+	pe.Size = uint32(unsafe.Sizeof(pe))
+	r, _, err := procProcess32FirstW.Call(snap, uintptr(unsafe.Pointer(&pe)))
+	if r == 0 {
+		return nil, err
+	}
+	for r != 0 {
+		names[strings.ToLower(syscall.UTF16ToString(pe.ExeFile[:]))] = struct{}{}
+		r, _, _ = procProcess32NextW.Call(snap, uintptr(unsafe.Pointer(&pe)))
+	}
+	return names, nil
+}
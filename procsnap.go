@@ -0,0 +1,23 @@
+package main
+
+// ==================== 每轮共享的进程列表 ====================
+// while_running、require_running、官方软件冲突检测都要看进程列表；
+// 同一轮里只枚举一次（第一次用到时才枚举，都没配置时不枚举）。
+
+type ProcSnapshot struct {
+	done  bool
+	names map[string]struct{}
+	err   error
+}
+
+// get 本轮的进程列表（exe 名小写）；s 为 nil 时直接枚举
+func (s *ProcSnapshot) get() (map[string]struct{}, error) {
+	if s == nil {
+		return RunningProcessNames()
+	}
+	if !s.done {
+		s.names, s.err = RunningProcessNames()
+		s.done = true
+	}
+	return s.names, s.err
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// ==================== VAXEE Feature Report 协议 ====================
+//
+// 抓包得到的写报告格式：
+//   [0] ReportID（0x0e） [1] 0xa5 [2] cmd [3] 0x02（写） [4] 0x01（数据长度） [5] val
+// 读报告为推测格式：把 [3] 换成 0x01（读）用 SetFeature 发出请求，
+// 再 GetFeature 取回 [ReportID, 0xa5, cmd, op, len, data...]；
+// 回包头不匹配时视为固件不支持该读命令。
+
+const (
+	protoMagic = 0xa5
+	opRead     = 0x01
+	opWrite    = 0x02
+
+	cmdPerf       = 0x08 // 性能模式
+	cmdPoll       = 0x07 // 回报率
+	cmdDebounce   = 0x0c // 按键消抖（推测，依据官方软件抓包）
+	cmdCombined   = 0x09 // 性能模式 + 回报率组合报告（推测，见 combined_report）
+	cmdDeviceInfo = 0x01 // 型号/固件信息（推测，不支持时字段留空）
+	cmdSave       = 0x0d // 把当前设置写入板载存储（推测，见 persist）
+	cmdSlot       = 0x0b // 板载配置槽位：读为当前槽位，写为切换槽位（推测，值从 1 开始）
+	cmdLED        = 0x10 // 指示灯颜色：数据长度 3，依次 R G B（未经验证，见 led.go）
+)
+
+// maxProfileSlot 板载配置槽位上限（推测）
+const maxProfileSlot = 5
+
+// CommandBytes 各设置项实际使用的命令字节。默认即上面的抓包值；
+// 固件更新改了命令号时可用 cmd_perf / cmd_poll 等配置项覆盖，不必重新编译。
+type CommandBytes struct {
+	Perf       byte
+	Poll       byte
+	MotionSync byte
+	Debounce   byte
+	Combined   byte
+	Save       byte
+	Slot       byte
+	LED        byte
+}
+
+var defaultCommandBytes = CommandBytes{
+	Perf:       cmdPerf,
+	Poll:       cmdPoll,
+	MotionSync: cmdMotionSync,
+	Debounce:   cmdDebounce,
+	Combined:   cmdCombined,
+	Save:       cmdSave,
+	Slot:       cmdSlot,
+	LED:        cmdLED,
+}
+
+// bufferReportID 实际写进报告缓冲区首字节的值：report_id_in_buffer=false 时为 0（无编号报告），
+// 读写两个方向都用它，回包也按它校验
+func (o DeviceOptions) bufferReportID() byte {
+	if !o.ReportIDInBuffer {
+		return 0
+	}
+	return o.ReportID
+}
+
+// 生成指定长度的 feature report（保证 buffer 长度符合 caps.FeatureReportByteLength）[1](https://learn.microsoft.com/en-us/windows-hardware/drivers/ddi/hidsdi/nf-hidsdi-hidd_setfeature)[2](https://learn.microsoft.com/zh-tw/windows-hardware/drivers/ddi/hidpi/ns-hidpi-_hidp_caps)
+func buildReportSized(total int, reportID byte, cmd byte, val byte) []byte {
+	if total < 6 {
+		total = 6
+	}
+	buf := make([]byte, total)
+	buf[0] = reportID // ReportID 14（你的抓包就是 0x0e）[9](https://blog.csdn.net/frederick_master/article/details/78845161)
+	buf[1] = protoMagic
+	buf[2] = cmd
+	buf[3] = opWrite
+	buf[4] = 0x01
+	buf[5] = val
+	return buf
+}
+
+// buildCombinedReport 组合报告（推测格式）：数据长度 2，依次为性能模式字节、回报率字节
+func buildCombinedReport(total int, reportID byte, cmd byte, perf byte, poll byte) []byte {
+	if total < 7 {
+		total = 7
+	}
+	buf := make([]byte, total)
+	buf[0] = reportID
+	buf[1] = protoMagic
+	buf[2] = cmd
+	buf[3] = opWrite
+	buf[4] = 0x02
+	buf[5] = perf
+	buf[6] = poll
+	return buf
+}
+
+// CombinedSupport 拒绝过组合报告的控制通道（按路径）。被拒绝一次后直接分条发送，
+// 否则每次下发都要先发一条注定失败的报告
+type CombinedSupport struct {
+	mu      sync.Mutex
+	refused map[string]bool
+}
+
+var combinedSupport CombinedSupport
+
+func (s *CombinedSupport) rejected(path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refused[path]
+}
+
+func (s *CombinedSupport) reject(path string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.refused == nil {
+		s.refused = map[string]bool{}
+	}
+	s.refused[path] = true
+	log.Printf("[APPLY] 组合报告被拒绝（%v），之后对该通道直接分条发送：%s", err, path)
+}
+
+// buildRawReport 额外报告：原样复制配置里的字节，补 0 到 total；超长时报错（不截断）
+func buildRawReport(total int, raw []byte) ([]byte, error) {
+	if len(raw) > total {
+		return nil, fmt.Errorf("%d bytes exceeds feature report length %d", len(raw), total)
+	}
+	buf := make([]byte, total)
+	copy(buf, raw)
+	return buf, nil
+}
+
+// buildLEDReport 指示灯颜色报告（推测格式）：数据长度 3，依次 R G B；不补长度，交给 buildRawReport
+func buildLEDReport(reportID byte, cmd byte, c LEDColor) []byte {
+	r, g, b := c.RGB()
+	return []byte{reportID, protoMagic, cmd, opWrite, 0x03, r, g, b}
+}
+
+// buildReadRequest 生成读请求（推测格式）
+func buildReadRequest(total int, reportID byte, cmd byte) []byte {
+	if total < 6 {
+		total = 6
+	}
+	buf := make([]byte, total)
+	buf[0] = reportID
+	buf[1] = protoMagic
+	buf[2] = cmd
+	buf[3] = opRead
+	return buf
+}
+
+// parseReadResponse 校验 GetFeature 回包头并取出数据段
+func parseReadResponse(resp []byte, reportID byte, cmd byte) ([]byte, error) {
+	if len(resp) < 5 || resp[0] != reportID || resp[1] != protoMagic || resp[2] != cmd {
+		return nil, fmt.Errorf("unexpected response for cmd 0x%02x: % x", cmd, head(resp, 8))
+	}
+	n := int(resp[4])
+	if 5+n > len(resp) {
+		n = len(resp) - 5
+	}
+	return resp[5 : 5+n], nil
+}
+
+// parseDeviceInfo 设备信息数据段：以 NUL 结尾的 ASCII 型号，后接两字节固件版本（主.次）
+func parseDeviceInfo(data []byte) (model, firmware string) {
+	i := 0
+	for i < len(data) && data[i] != 0 {
+		i++
+	}
+	model = strings.TrimSpace(string(data[:i]))
+	if i+2 < len(data) {
+		firmware = fmt.Sprintf("%d.%d", data[i+1], data[i+2])
+	}
+	return model, firmware
+}
+
+func head(b []byte, n int) []byte {
+	if len(b) < n {
+		return b
+	}
+	return b[:n]
+}
+
+// settingField 一条设置报告属于哪一项（用于判断半途失败时哪些项已完整生效）
+type settingField int
+
+const (
+	fieldPerf settingField = iota // 性能模式（含 Motion Sync 独立报告）
+	fieldPoll
+	fieldOther // 不参与 Applied 记录的附加项（消抖等）
+	fieldLED   // 指示灯：被拒绝时不算下发失败（见 LEDSupport）
+)
+
+// settingReport 一条待发送的写报告
+type settingReport struct {
+	name  string
+	field settingField
+	cmd   byte
+	val   byte
+	raw   []byte // 非空时为额外报告：原样发送，不用 cmd/val
+}
+
+// reportMotionSync Motion Sync 独立报告的名字；它与性能模式同属 fieldPerf，但不能并进组合报告
+const reportMotionSync = "motion sync"
+
+// isPerfByte 性能模式字节本身的报告（不含 Motion Sync）
+func (r settingReport) isPerfByte() bool {
+	return r.field == fieldPerf && r.name != reportMotionSync
+}
+
+// settingReports 按固定顺序列出一组设置要发送的报告，0 值的项跳过：
+// 性能模式 -> Motion Sync（motion_sync_report）-> 回报率 -> 消抖 -> 指示灯 -> 额外报告 -> 保存（persist）。
+// 新增参数只需在这里按位置追加一项。映射失败时一条都不发。
+func settingReports(opts DeviceOptions, p Profile) ([]settingReport, error) {
+	var out []settingReport
+	switch {
+	case p.PerfByte.Set():
+		// 原始字节原样发送，不拆 Motion Sync
+		out = append(out, settingReport{"perf(raw)", fieldPerf, opts.Cmd.Perf, p.PerfByte.Byte(), nil})
+	case p.Perf != 0:
+		// Motion Sync 独立命令的固件只发基础模式
+		perfByte := byte(p.Perf)
+		if opts.MotionSyncReport {
+			perfByte = byte(perfBase(p.Perf))
+		}
+		out = append(out, settingReport{"perf", fieldPerf, opts.Cmd.Perf, perfByte, nil})
+		if opts.MotionSyncReport {
+			ms := byte(motionSyncOffVal)
+			if perfMotionSync(p.Perf) {
+				ms = motionSyncOnVal
+			}
+			out = append(out, settingReport{reportMotionSync, fieldPerf, opts.Cmd.MotionSync, ms, nil})
+		}
+	}
+	switch {
+	case p.PollByte.Set():
+		out = append(out, settingReport{"poll(raw)", fieldPoll, opts.Cmd.Poll, p.PollByte.Byte(), nil})
+	case p.Poll != 0:
+		yy, err := pollingToYY(p.Poll)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, settingReport{"poll", fieldPoll, opts.Cmd.Poll, yy, nil})
+	}
+	if p.Debounce != 0 {
+		b, err := debounceToByte(p.Debounce)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, settingReport{"debounce", fieldOther, opts.Cmd.Debounce, b, nil})
+	}
+	if p.LED.Set() {
+		out = append(out, settingReport{"led " + p.LED.String(), fieldLED, 0, 0, buildLEDReport(opts.bufferReportID(), opts.Cmd.LED, p.LED)})
+	}
+	for _, r := range p.Extra.Reports() {
+		out = append(out, settingReport{"extra " + formatExtraReport(r), fieldOther, 0, 0, r})
+	}
+	// 保存放在最后：前面的设置都发完才写入闪存；没有改动时不写
+	if opts.Persist && len(out) > 0 {
+		out = append(out, settingReport{"save", fieldOther, opts.Cmd.Save, 0x01, nil})
+	}
+	return out, nil
+}
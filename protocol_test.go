@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+// cmd_motion_sync 覆盖成与性能模式相同的命令字节时，Motion Sync 也不能被当成性能模式字节并进组合报告
+func TestIsPerfByteIgnoresMotionSync(t *testing.T) {
+	opts := DeviceOptions{Cmd: defaultCommandBytes, MotionSyncReport: true}
+	opts.Cmd.MotionSync = opts.Cmd.Perf
+	reports, err := settingReports(opts, Profile{Perf: PerfCompetitiveMSOn, Poll: Poll2000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	perf, ms := 0, 0
+	for _, r := range reports {
+		switch {
+		case r.isPerfByte():
+			perf++
+		case r.name == reportMotionSync:
+			ms++
+		}
+	}
+	if perf != 1 || ms != 1 {
+		t.Errorf("perf reports = %d, motion sync reports = %d, want 1 and 1 (%+v)", perf, ms, reports)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// ==================== 安静时段（quiet_hours） ====================
+// 时段内完全不自动切换、不访问鼠标，日志只保留 [ERR] 与 [WARN]；
+// 时段结束后的第一轮照常检查，立即按当时的前台下发。
+// 手动覆盖（热键/管道命令）是用户主动操作，不受限制。
+
+// QuietHours 每天的安静时段（从 0 点起的分钟数）。Start > End 表示跨过午夜
+type QuietHours struct {
+	Start, End int
+	set        bool
+}
+
+// parseQuietHours 解析 "23:00-07:00"；空串 / off 表示不启用
+func parseQuietHours(s string) (QuietHours, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "off") {
+		return QuietHours{}, nil
+	}
+	a, b, ok := strings.Cut(s, "-")
+	if !ok {
+		return QuietHours{}, fmt.Errorf("invalid quiet_hours: %s (格式 HH:MM-HH:MM)", s)
+	}
+	start, err := parseClock(a)
+	if err != nil {
+		return QuietHours{}, fmt.Errorf("invalid quiet_hours: %s (%v)", s, err)
+	}
+	end, err := parseClock(b)
+	if err != nil {
+		return QuietHours{}, fmt.Errorf("invalid quiet_hours: %s (%v)", s, err)
+	}
+	if start == end {
+		return QuietHours{}, fmt.Errorf("invalid quiet_hours: %s (开始与结束相同)", s)
+	}
+	return QuietHours{Start: start, End: end, set: true}, nil
+}
+
+// parseClock 解析 "HH:MM"，返回从 0 点起的分钟数
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("bad time %q", strings.TrimSpace(s))
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Set 是否配置了安静时段
+func (q QuietHours) Set() bool { return q.set }
+
+// contains now（本地时间）是否在时段内；含开始、不含结束
+func (q QuietHours) contains(now time.Time) bool {
+	if !q.set {
+		return false
+	}
+	m := now.Hour()*60 + now.Minute()
+	if q.Start < q.End {
+		return m >= q.Start && m < q.End
+	}
+	return m >= q.Start || m < q.End
+}
+
+func (q QuietHours) String() string {
+	if !q.set {
+		return "off"
+	}
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", q.Start/60, q.Start%60, q.End/60, q.End%60)
+}
+
+type QuietGate struct {
+	active bool
+}
+
+var quietGate QuietGate
+
+// check 当前是否在安静时段；进入/离开时打日志并切换日志过滤
+func (g *QuietGate) check(cfg *Config, now time.Time) bool {
+	quiet := cfg.QuietHours.contains(now)
+	if quiet == g.active {
+		return quiet
+	}
+	g.active = quiet
+	if quiet {
+		log.Printf("[QUIET] 进入安静时段 %s：暂停自动切换，日志只保留错误。", cfg.QuietHours)
+		quietLog.Store(true)
+	} else {
+		quietLog.Store(false)
+		log.Printf("[QUIET] 安静时段结束，恢复自动切换。")
+	}
+	return quiet
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ==================== 带引号的配置值 ====================
+// 值里需要保留首尾空格，或含有 , : = " @ 这类会被当成分隔符的字符时，可以整体加双引号：
+//   learn_file="D:\My Logs\learn.txt"
+//   "My Game: Edition.exe" @5
+//   cmdline:"-mode=ranked, -novid"
+// 引号内只有 \" 和 \\ 是转义，其它反斜杠原样保留（方便直接写 Windows 路径）。
+// 不加引号时行为不变：去掉首尾空白后原样使用。
+
+// cutQuoted s 以双引号开头时返回引号内的内容（已处理转义）与闭合引号之后的部分；
+// 不以引号开头时 quoted=false，s 原样返回在 rest 中
+func cutQuoted(s string) (val, rest string, quoted bool, err error) {
+	if !strings.HasPrefix(s, `"`) {
+		return "", s, false, nil
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			return b.String(), s[i+1:], true, nil
+		case c == '\\' && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\'):
+			i++
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", true, fmt.Errorf("unterminated quote: %s", s)
+}
+
+// unquoteValue key=value 的值：整个值是一个带引号的字符串时去掉引号；
+// 引号之后还有内容（如列表 "a.exe", "b.exe"）时原样返回，由各项再分别处理
+func unquoteValue(s string) (string, error) {
+	val, rest, quoted, err := cutQuoted(s)
+	if err != nil {
+		return "", err
+	}
+	if !quoted || strings.TrimSpace(rest) != "" {
+		return s, nil
+	}
+	return val, nil
+}
+
+// cutRuleName 规则里的名字与末尾的优先级（" @N"）；名字带引号时引号之后只允许优先级
+func cutRuleName(s string) (string, int, error) {
+	s = strings.TrimSpace(s)
+	val, rest, quoted, err := cutQuoted(s)
+	if err != nil {
+		return "", 0, err
+	}
+	if !quoted {
+		return cutPriority(s)
+	}
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		return val, 0, nil
+	}
+	// 引号之后整段都是优先级标记才算数（@0 也是合法的优先级）
+	if name, prio, err := cutPriority(" " + rest); err == nil && name == "" {
+		return val, prio, nil
+	}
+	return "", 0, fmt.Errorf("unexpected %q after quoted name", rest)
+}
+
+// quoteValue dump 时给需要引号才能原样读回的值加引号
+func quoteValue(s string) string {
+	if s == strings.TrimSpace(s) && !strings.ContainsAny(s, `",=`) && !strings.Contains(s, " @") && !strings.HasPrefix(s, "#") {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCutQuoted(t *testing.T) {
+	cases := []struct {
+		in, val, rest string
+		quoted, err   bool
+	}{
+		{`cs2.exe`, "", "cs2.exe", false, false},
+		{`"My Game: Edition"`, "My Game: Edition", "", true, false},
+		{`"a \"b\" c" @5`, `a "b" c`, " @5", true, false},
+		{`"C:\Games\x\\y"`, `C:\Games\x\y`, "", true, false},
+		{`"unclosed`, "", "", true, true},
+		{`"ends with escape\"`, "", "", true, true},
+	}
+	for _, c := range cases {
+		val, rest, quoted, err := cutQuoted(c.in)
+		if val != c.val || rest != c.rest || quoted != c.quoted || (err != nil) != c.err {
+			t.Errorf("cutQuoted(%q) = %q, %q, %v, %v; want %q, %q, %v, err=%v",
+				c.in, val, rest, quoted, err, c.val, c.rest, c.quoted, c.err)
+		}
+	}
+}
+
+func TestUnquoteValue(t *testing.T) {
+	cases := []struct {
+		in, want string
+		err      bool
+	}{
+		{`D:\logs\learn.txt`, `D:\logs\learn.txt`, false},
+		{`"D:\My Logs\learn.txt"`, `D:\My Logs\learn.txt`, false},
+		{`"  padded  "`, "  padded  ", false},
+		{`"a.exe", "b.exe"`, `"a.exe", "b.exe"`, false}, // 列表由各项分别去引号
+		{`"open`, "", true},
+	}
+	for _, c := range cases {
+		got, err := unquoteValue(c.in)
+		if got != c.want || (err != nil) != c.err {
+			t.Errorf("unquoteValue(%q) = %q, %v; want %q, err=%v", c.in, got, err, c.want, c.err)
+		}
+	}
+}
+
+func TestCutRuleName(t *testing.T) {
+	cases := []struct {
+		in   string
+		name string
+		prio int
+		err  bool
+	}{
+		{"cs2.exe", "cs2.exe", 0, false},
+		{"  cs2.exe @10 ", "cs2.exe", 10, false},
+		{"launcher.exe @-1", "launcher.exe", -1, false},
+		{"cs2.exe @high", "", 0, true},
+		{`"a, b.exe" @5`, "a, b.exe", 5, false},
+		{`"My Game: Edition" @0`, "My Game: Edition", 0, false},
+		{`"a.exe" @x`, "", 0, true},
+		{`"a.exe" b @1`, "", 0, true},
+		{`"My Game: Edition.exe"`, "My Game: Edition.exe", 0, false},
+		{`"a.exe" junk`, "", 0, true},
+		{`"a.exe @5`, "", 0, true},
+	}
+	for _, c := range cases {
+		name, prio, err := cutRuleName(c.in)
+		if name != c.name || prio != c.prio || (err != nil) != c.err {
+			t.Errorf("cutRuleName(%q) = %q, %d, %v; want %q, %d, err=%v", c.in, name, prio, err, c.name, c.prio, c.err)
+		}
+	}
+}
+
+func TestSplitWhitelistLine(t *testing.T) {
+	cases := []struct {
+		in   string
+		want []string
+	}{
+		{"cs2.exe, valorant.exe", []string{"cs2.exe", "valorant.exe"}},
+		{"  cs2.exe  ,, ", []string{"cs2.exe"}},
+		{`"a, b.exe" @5, c.exe`, []string{`"a, b.exe" @5`, "c.exe"}},
+		{`"x \", y.exe", z.exe`, []string{`"x \", y.exe"`, "z.exe"}},
+	}
+	for _, c := range cases {
+		if got := splitWhitelistLine(c.in); !slices.Equal(got, c.want) {
+			t.Errorf("splitWhitelistLine(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+// 带引号的 title: 规则保留空格与 ':'，并按窗口标题命中
+func TestTitleRuleQuoted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vaxee.conf")
+	text := "title:\"My Game: Edition\" @3\ntitle:  Lobby  \n"
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"my game: edition", "lobby"}; !slices.Equal(cfg.TitleRules, want) {
+		t.Fatalf("TitleRules = %q, want %q", cfg.TitleRules, want)
+	}
+	if p := cfg.RulePriority[titlePrefix+"my game: edition"]; p != 3 {
+		t.Errorf("priority = %d, want 3", p)
+	}
+	if rule, hit := matchWhitelist(cfg, "game.exe", "MY GAME: EDITION - Match", nil); !hit || rule != titlePrefix+"my game: edition" {
+		t.Errorf("matchWhitelist = %q, %v", rule, hit)
+	}
+	if _, hit := matchWhitelist(cfg, "game.exe", "My Game", nil); hit {
+		t.Errorf("partial title should not match")
+	}
+}
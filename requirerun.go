@@ -0,0 +1,43 @@
+package main
+
+import (
+	"log"
+	"strings"
+)
+
+// ==================== 游戏会话门控（require_running） ====================
+// 列表中的进程（如游戏启动器）一个都没在运行时，不按前台切换，只保持 default 配置；
+// 任一出现后恢复正常切换。每轮检查时看一次进程列表（与本轮其它检查共用，见 ProcSnapshot）。
+
+type RunningGate struct {
+	blocked bool // 上一轮是否因未运行而停用（只在状态变化时打日志）
+}
+
+var requireRunning RunningGate
+
+// allow require_running 未配置或其中任一进程在运行时返回 true；枚举失败时不拦截
+func (g *RunningGate) allow(cfg *Config, procs *ProcSnapshot) bool {
+	if len(cfg.RequireRunning) == 0 {
+		g.blocked = false
+		return true
+	}
+	running, err := procs.get()
+	if err != nil {
+		return true
+	}
+	found := ""
+	for _, name := range cfg.RequireRunning {
+		if _, ok := running[name]; ok {
+			found = name
+			break
+		}
+	}
+	switch {
+	case found == "" && !g.blocked:
+		log.Printf("[REQUIRE] %s 都没有运行：停用按前台切换，保持 default 配置。", strings.Join(cfg.RequireRunning, ", "))
+	case found != "" && g.blocked:
+		log.Printf("[REQUIRE] %s 已运行，恢复按前台切换。", found)
+	}
+	g.blocked = found == ""
+	return !g.blocked
+}
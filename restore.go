@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ==================== 退出时恢复基线（restore_on_exit） ====================
+// Ctrl+C / 关机时把鼠标恢复到 baseline_mode/baseline_poll，并读回确认真正生效：
+// 关机过程中 USB 栈可能正在卸载，下发“成功”不代表设置已经落到鼠标上。
+// 整个过程（含一次重试）限定在 restoreTimeout 内，不拖慢退出。
+
+const restoreTimeout = 3 * time.Second
+
+// errRestoreUnverified 已下发，但读不回设备状态（多为固件不支持读回），无法确认
+var errRestoreUnverified = errors.New("restore not verified")
+
+// restoreOnExit 主循环因退出信号结束时调用；未开启 restore_on_exit 时什么都不做
+func restoreOnExit(cfg *Config) {
+	if !cfg.RestoreOnExit {
+		return
+	}
+	p := cfg.baselineProfile()
+	// 退出信号已取消主 ctx，用独立的 ctx
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTimeout)
+	defer cancel()
+
+	attempts := 1
+	if cfg.RestoreRetry {
+		attempts = 2
+	}
+	var err error
+	for i := 1; i <= attempts; i++ {
+		err = restoreBaseline(ctx, cfg, p)
+		switch {
+		case err == nil:
+			log.Printf("[RESTORE] 已恢复基线并读回确认：%s", profileName(p))
+			return
+		case errors.Is(err, errRestoreUnverified):
+			log.Printf("[RESTORE] 已恢复基线 %s，但读不回设备状态，未确认：%v", profileName(p), err)
+			return
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if i < attempts {
+			log.Printf("[RESTORE] 恢复基线未确认（%v），重试一次。", err)
+		}
+	}
+	log.Printf("[ERR] 退出时恢复基线 %s 失败：%v", profileName(p), err)
+}
+
+// restoreBaseline 下发基线后读回比对
+func restoreBaseline(ctx context.Context, cfg *Config, p Profile) error {
+	dev, err := FindOneVaxeeDevice(ctx, cfg.Device)
+	if err != nil {
+		return err
+	}
+	if err := applyProfile(ctx, cfg, dev.Path, p); err != nil {
+		return err
+	}
+	got, err := ReadCurrentSettings(ctx, dev.Path, cfg.Device, int(dev.FeatureLen))
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: %v", errRestoreUnverified, err)
+	}
+	if !readBackMatches(got, p) {
+		return fmt.Errorf("readback mismatch: want %s, got %s", profileName(p), profileName(got))
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ==================== 按程序的配置（继承 hit_* 模板） ====================
+// "cs2.exe: poll=4000" —— 该程序命中时以 hit_* 配置为模板，只改写列出的字段：
+//   mode=<性能模式> / poll=<回报率> / debounce=<毫秒>，多个字段用逗号分隔；
+//   interval=<秒> 该程序在前台时的检查间隔（覆盖 hit_interval_seconds）。
+// 继承在 loadConfig 读完全部配置后解析（与书写顺序无关），Decide 拿到的是完整配置。
+
+// RuleOverride 按程序配置里写出的字段（0 = 继承模板）
+type RuleOverride struct {
+	Perf     PerfMode
+	Poll     PollingRate
+	Debounce int
+	Interval time.Duration // 该程序在前台时的检查间隔（0 = 按 hit_interval_seconds）
+}
+
+func (o RuleOverride) String() string {
+	var parts []string
+	if o.Perf != 0 {
+		parts = append(parts, "mode="+perfName(o.Perf))
+	}
+	if o.Poll != 0 {
+		parts = append(parts, fmt.Sprintf("poll=%d", o.Poll))
+	}
+	if o.Debounce != 0 {
+		parts = append(parts, fmt.Sprintf("debounce=%d", o.Debounce))
+	}
+	if o.Interval != 0 {
+		parts = append(parts, fmt.Sprintf("interval=%d", int(o.Interval.Seconds())))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// cutRuleProfileLine 识别 "进程名: 字段=值, ..." 行：第一个 '=' 之前的最后一个 ':' 分开进程名与字段
+// （key=value 行的 ':' 都在 '=' 之后，C:\ 开头的路径也会取到 exe 名之后的那个冒号）。
+// 进程名带引号时取闭合引号之后的第一个 ':'，返回的 proc 仍带引号（由 cutRuleName 去掉）。
+func cutRuleProfileLine(line string) (proc, fields string, ok bool) {
+	if _, rest, quoted, err := cutQuoted(line); quoted {
+		i := strings.IndexByte(rest, ':')
+		if err != nil || i < 0 {
+			return "", "", false
+		}
+		i += len(line) - len(rest)
+		return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]), true
+	}
+	eq := strings.IndexByte(line, '=')
+	if eq < 0 {
+		return "", "", false
+	}
+	i := strings.LastIndexByte(line[:eq], ':')
+	if i <= 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]), true
+}
+
+// parseRuleOverride 解析 "mode=competitive_ms_on, poll=4000"
+func parseRuleOverride(s string) (RuleOverride, error) {
+	var o RuleOverride
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			return o, fmt.Errorf("invalid field (want key=value): %s", part)
+		}
+		k, v = strings.ToLower(strings.TrimSpace(k)), strings.TrimSpace(v)
+		switch k {
+		case "mode":
+			m, err := parsePerf(v)
+			if err != nil {
+				return o, err
+			}
+			o.Perf = m
+		case "poll":
+			n, err := parseInt(v)
+			if err != nil {
+				return o, fmt.Errorf("invalid poll: %s", v)
+			}
+			if _, err := pollingToYY(PollingRate(n)); err != nil {
+				return o, err
+			}
+			o.Poll = PollingRate(n)
+		case "debounce":
+			n, err := parseInt(v)
+			if err != nil {
+				return o, fmt.Errorf("invalid debounce: %s", v)
+			}
+			if _, err := debounceToByte(n); err != nil {
+				return o, err
+			}
+			o.Debounce = n
+		case "interval":
+			n, err := parseInt(v)
+			if err != nil || n <= 0 {
+				return o, fmt.Errorf("invalid interval: %s (must be > 0)", v)
+			}
+			o.Interval = time.Duration(n) * time.Second
+		default:
+			return o, fmt.Errorf("unknown field: %s (mode|poll|debounce|interval)", k)
+		}
+	}
+	if o == (RuleOverride{}) {
+		return o, fmt.Errorf("no fields")
+	}
+	return o, nil
+}
+
+// resolveRuleProfiles 以 hit_* 为模板展开各程序的配置；写了 mode/poll 的项不再沿用对应的原始字节
+func (c *Config) resolveRuleProfiles() {
+	if len(c.RuleOverrides) == 0 {
+		return
+	}
+	base := c.effectiveProfile(true, false)
+	c.RuleProfiles = make(map[string]Profile, len(c.RuleOverrides))
+	for rule, o := range c.RuleOverrides {
+		p := base
+		if o.Perf != 0 {
+			p.Perf, p.PerfByte = o.Perf, 0
+		}
+		if o.Poll != 0 {
+			p.Poll, p.PollByte = o.Poll, 0
+		}
+		if o.Debounce != 0 {
+			p.Debounce = o.Debounce
+		}
+		c.RuleProfiles[rule] = p
+	}
+}
+
+// profileFor 命中规则 rule 时的目标配置：有按程序配置时用它（电池供电时 hit_*_battery 仍覆盖，
+// 与 effectiveProfile 一致），否则同 effectiveProfile
+func (c *Config) profileFor(rule string, hit, battery bool) Profile {
+	p, ok := c.RuleProfiles[rule]
+	if !hit || !ok {
+		return c.effectiveProfile(hit, battery)
+	}
+	if battery {
+		if c.HitModeBattery != 0 {
+			p.Perf = c.HitModeBattery
+		}
+		if c.HitPollBattery != 0 {
+			p.Poll = c.HitPollBattery
+		}
+	}
+	return p
+}
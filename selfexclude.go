@@ -0,0 +1,39 @@
+package main
+
+import (
+	"log"
+	"os"
+)
+
+// ==================== 排除自身窗口（self_exclude） ====================
+// 本程序的控制台/状态窗口被点到前台时，不把自己当成前台进程去匹配规则，
+// 本轮直接跳过、保持当前配置（否则点一下状态窗口就会切回默认配置）。
+
+// selfProcName 本程序的进程名（已 normalizeProcName），取不到时为空
+var selfProcName = func() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	return normalizeProcName(exe)
+}()
+
+type SelfExclude struct {
+	active bool // 上一轮是否因自身在前台而跳过（只在进入/离开时打日志）
+}
+
+var selfExclude SelfExclude
+
+// skip 前台是本程序自身时返回 true
+func (s *SelfExclude) skip(cfg *Config, proc string) bool {
+	self := cfg.SelfExclude && selfProcName != "" && proc == selfProcName
+	if self != s.active {
+		if self {
+			log.Printf("[SAFE] 前台是本程序自身（%s），不参与匹配，保持当前配置。", proc)
+		} else {
+			log.Printf("[SAFE] 本程序窗口已离开前台，恢复正常切换。")
+		}
+		s.active = self
+	}
+	return self
+}
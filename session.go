@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// ==================== 会话过滤（远程桌面） ====================
+// 远程桌面连接时 GetForegroundWindow 反映的是远程会话的前台，
+// 而鼠标和游戏在本地控制台会话。session=console / session=N 时，
+// 前台进程不属于目标会话就跳过本轮，不切换。
+//
+// 限制：程序运行在 session 0（服务/SYSTEM 计划任务）时取不到任何用户会话的前台窗口，
+// 自动切换无法工作，须在用户登录的会话中运行。
+
+const (
+	sessionAny     = "any"     // 不限制（默认）
+	sessionConsole = "console" // 只在物理控制台会话的前台上动作
+)
+
+// parseSession any | console | 非负整数会话 ID
+func parseSession(val string) (string, error) {
+	v := strings.ToLower(strings.TrimSpace(val))
+	switch v {
+	case sessionAny, sessionConsole:
+		return v, nil
+	}
+	if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+		return strconv.Itoa(n), nil
+	}
+	return "", fmt.Errorf("invalid session: %s (any|console|会话 ID)", val)
+}
+
+// targetSession 要求前台所在的会话 ID；ok=false 表示不限制（或控制台暂时无会话）
+func (cfg *Config) targetSession() (id uint32, ok bool) {
+	switch cfg.Session {
+	case "", sessionAny:
+		return 0, false
+	case sessionConsole:
+		return ConsoleSessionID()
+	}
+	n, err := strconv.ParseUint(cfg.Session, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint32(n), true
+}
+
+// SessionGate 判断前台是否在目标会话；跳过状态变化时各打一条日志
+type SessionGate struct {
+	skipping bool
+}
+
+var sessionGate SessionGate
+
+// allow 前台属于目标会话（或不限制、查询失败）时返回 true
+func (g *SessionGate) allow(cfg *Config) bool {
+	target, ok := cfg.targetSession()
+	if !ok {
+		g.skipping = false
+		return true
+	}
+	fg, err := ForegroundSessionID()
+	if err != nil || fg == target {
+		if g.skipping {
+			log.Printf("[SESSION] 前台已回到会话 %d，恢复自动切换。", target)
+		}
+		g.skipping = false
+		return true
+	}
+	if !g.skipping {
+		log.Printf("[SESSION] 前台属于会话 %d（目标会话 %d，session=%s），暂停切换。", fg, target, cfg.Session)
+	}
+	g.skipping = true
+	return false
+}
+
+// warnSessionZero 运行在 session 0 时提示无法工作
+func warnSessionZero() {
+	if id, err := CurrentSessionID(); err == nil && id == 0 {
+		log.Printf("[SESSION] 当前运行在 session 0（服务/SYSTEM 任务），无法获取用户的前台窗口，自动切换不会生效。")
+		log.Printf("[SESSION] 请在用户登录的会话中运行（例如计划任务选“只在用户登录时运行”）。")
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+func ConsoleSessionID() (uint32, bool) {
+	return 0, false
+}
+
+func ForegroundSessionID() (uint32, error) {
+	return 0, errors.New("sessions are only supported on Windows")
+}
+
+func CurrentSessionID() (uint32, error) {
+	return 0, errors.New("sessions are only supported on Windows")
+}
@@ -0,0 +1,50 @@
+//go:build windows
+
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var (
+	k32SS = syscall.NewLazyDLL("kernel32.dll")
+
+	procWTSGetActiveConsoleSessionId = k32SS.NewProc("WTSGetActiveConsoleSessionId")
+	procProcessIdToSessionId         = k32SS.NewProc("ProcessIdToSessionId")
+)
+
+// 切换用户/连接过渡期间没有控制台会话
+const noConsoleSession = 0xFFFFFFFF
+
+// ConsoleSessionID 物理控制台所在会话；过渡期间 ok=false
+func ConsoleSessionID() (uint32, bool) {
+	r1, _, _ := procWTSGetActiveConsoleSessionId.Call()
+	if uint32(r1) == noConsoleSession {
+		return 0, false
+	}
+	return uint32(r1), true
+}
+
+func processSessionID(pid uint32) (uint32, error) {
+	var sid uint32
+	r1, _, err := procProcessIdToSessionId.Call(uintptr(pid), uintptr(unsafe.Pointer(&sid)))
+	if r1 == 0 {
+		return 0, err
+	}
+	return sid, nil
+}
+
+// ForegroundSessionID 前台进程所在会话
+func ForegroundSessionID() (uint32, error) {
+	pid, err := foregroundPID()
+	if err != nil {
+		return 0, err
+	}
+	return processSessionID(pid)
+}
+
+// CurrentSessionID 本进程所在会话
+func CurrentSessionID() (uint32, error) {
+	return processSessionID(uint32(syscall.Getpid()))
+}
@@ -0,0 +1,21 @@
+package main
+
+import "errors"
+
+// ==================== 模拟前台（-simulate-foreground） ====================
+// 本次运行把前台进程固定为指定的进程名，不读取真实前台窗口：
+// 在没有装游戏的机器上也能验证“命中规则 -> 下发到真实鼠标”的整条链路（配合 -once 使用）。
+// 命令行规则（cmdline:）不会命中；标题规则（title:）按固定标题 "(simulated)" 匹配。
+
+// simulatedForeground 已 normalizeProcName 的模拟进程名；空 = 读真实前台
+var simulatedForeground string
+
+var errSimulatedForeground = errors.New("foreground is simulated (-simulate-foreground)")
+
+// simulatedForegroundInfo 模拟前台时返回固定的前台信息
+func simulatedForegroundInfo() (ForegroundInfo, bool) {
+	if simulatedForeground == "" {
+		return ForegroundInfo{}, false
+	}
+	return ForegroundInfo{Name: simulatedForeground, Title: "(simulated)"}, true
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+)
+
+// ==================== 压力测试（-soak N） ====================
+// 开发/QA 用：交替下发两组配置 N 次，每次尽量读回校验，统计成功率与耗时。
+// 走与自动切换完全相同的 applyProfile 路径；结束时恢复测试前的状态。
+
+const defaultSoakDelay = 500 * time.Millisecond
+
+// soakProfiles 选出两组不同的配置：优先命中/未命中配置，相同时用基线补位
+func soakProfiles(cfg *Config) (Profile, Profile, bool) {
+	a := cfg.effectiveProfile(true, false)
+	b := cfg.effectiveProfile(false, false)
+	if a == b {
+		b = cfg.baselineProfile()
+	}
+	return a, b, a != b
+}
+
+// readBackMatches 读回值与目标一致；目标没设消抖或设备读不出消抖时不比较消抖，
+// 原始字节无法对应映射值，也不比较
+func readBackMatches(got, want Profile) bool {
+	if (!want.PerfByte.Set() && got.Perf != want.Perf) || (!want.PollByte.Set() && got.Poll != want.Poll) {
+		return false
+	}
+	return want.Debounce == 0 || got.Debounce == 0 || got.Debounce == want.Debounce
+}
+
+// runSoak 返回退出码：全部下发成功且读回一致（或无法读回）为 0
+func runSoak(ctx context.Context, cfgPath string, n int, delay time.Duration) int {
+	cfg, _, err := loadConfig(cfgPath)
+	if err != nil {
+		log.Printf("[ERR] 读取配置失败：%v", err)
+		return 1
+	}
+	applySessionFlags(cfg)
+	applyLogOptions(cfg.Log)
+	deviceThread.setEnabled(cfg.ApplyThread)
+	if cfg.Device.Persist {
+		// 反复写闪存没有意义还会损耗寿命
+		log.Printf("[SOAK] 已忽略 persist=true：压力测试期间不写入鼠标闪存。")
+		cfg.Device.Persist = false
+	}
+	if n <= 0 {
+		log.Printf("[ERR] -soak 次数必须大于 0")
+		return 2
+	}
+
+	a, b, ok := soakProfiles(cfg)
+	if !ok {
+		log.Printf("[ERR] 命中/未命中/基线配置完全相同（%s），没有可交替的配置。", profileName(a))
+		return 2
+	}
+
+	dev, err := FindOneVaxeeDevice(ctx, cfg.Device)
+	if err != nil {
+		log.Printf("[ERR] 未找到可用 VAXEE 设备：%v", err)
+		return 1
+	}
+	flen := int(dev.FeatureLen)
+
+	// 记下原始状态；读不回来时结束后恢复到基线
+	orig, readErr := ReadCurrentSettings(ctx, dev.Path, cfg.Device, flen)
+	canVerify := readErr == nil
+	if canVerify {
+		log.Printf("[SOAK] 当前设备状态：%s", profileName(orig))
+	} else {
+		orig = cfg.baselineProfile()
+		log.Printf("[SOAK] 固件不支持读回（%v），跳过校验；结束后恢复到基线 %s", readErr, profileName(orig))
+	}
+	log.Printf("[SOAK] 开始：%d 次，间隔 %s，交替 %s <-> %s（设备：%s）",
+		n, delay, profileName(a), profileName(b), dev.Path)
+
+	var (
+		okCount, failCount, mismatch int
+		durs                         []time.Duration
+	)
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		p := a
+		if i%2 == 1 {
+			p = b
+		}
+
+		start := time.Now()
+		applyErr := applyProfile(ctx, cfg, dev.Path, p)
+		d := time.Since(start)
+		if applyErr != nil {
+			failCount++
+			log.Printf("[SOAK] #%d %s 失败（%s）：%v", i+1, profileName(p), d, applyErr)
+		} else {
+			okCount++
+			durs = append(durs, d)
+			if canVerify {
+				got, err := ReadCurrentSettings(ctx, dev.Path, cfg.Device, flen)
+				switch {
+				case err != nil:
+					mismatch++
+					log.Printf("[SOAK] #%d 读回失败：%v", i+1, err)
+				case !readBackMatches(got, p):
+					mismatch++
+					log.Printf("[SOAK] #%d 读回不一致：期望 %s，实际 %s", i+1, profileName(p), profileName(got))
+				}
+			}
+		}
+
+		if i+1 < n {
+			if err := sleepCtx(ctx, delay); err != nil {
+				break
+			}
+		}
+	}
+	done := okCount + failCount
+
+	// 恢复原始状态（ctx 可能已被 Ctrl+C 取消，用独立的 ctx）
+	rctx, cancel := context.WithTimeout(context.Background(), cfg.ApplyTimeout)
+	restoreErr := applyProfile(rctx, cfg, dev.Path, orig)
+	cancel()
+	if restoreErr != nil {
+		log.Printf("[ERR] 恢复原始状态 %s 失败：%v", profileName(orig), restoreErr)
+	} else {
+		log.Printf("[SOAK] 已恢复原始状态：%s", profileName(orig))
+	}
+
+	// 统计
+	if done == 0 {
+		log.Printf("[SOAK] 未执行任何下发。")
+		return 1
+	}
+	log.Printf("[SOAK] 完成 %d/%d 次：成功 %d（%.1f%%），失败 %d", done, n, okCount,
+		float64(okCount)*100/float64(done), failCount)
+	if canVerify {
+		log.Printf("[SOAK] 读回校验：不一致/读回失败 %d 次", mismatch)
+	}
+	if len(durs) > 0 {
+		slices.Sort(durs)
+		var sum time.Duration
+		for _, d := range durs {
+			sum += d
+		}
+		p95 := durs[(len(durs)*95+99)/100-1]
+		log.Printf("[SOAK] 下发耗时：min=%s avg=%s p95=%s max=%s",
+			durs[0], sum/time.Duration(len(durs)), p95, durs[len(durs)-1])
+	}
+
+	if failCount > 0 || mismatch > 0 || restoreErr != nil || done < n {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// StatusSnapshot 某一时刻的运行状态（托盘/HTTP/汇总等都从这里读）
+type StatusSnapshot struct {
+	Proc    string // 最近一次看到的前台进程
+	Rule    string // 命中的规则（未命中为空）
+	Hit     bool
+	Battery bool
+
+	Desired   Profile // 按规则算出的目标配置
+	Applied   Profile // 最近一次成功下发的配置
+	AppliedOK bool
+	Device    string // 最近一次下发所用的设备路径
+
+	Manual        bool // 手动覆盖中
+	ManualProfile Profile
+
+	LastError  string
+	LastSwitch time.Time
+	Switches   int
+}
+
+// State 线程安全的运行状态，由主循环在每次 tick/下发时更新
+type State struct {
+	mu sync.Mutex
+	s  StatusSnapshot
+}
+
+// Snapshot 返回当前状态的副本
+func (st *State) Snapshot() StatusSnapshot {
+	if st == nil {
+		return StatusSnapshot{}
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.s
+}
+
+// update 在锁内修改状态；st 为 nil（如 -once）时什么都不做
+func (st *State) update(fn func(s *StatusSnapshot)) {
+	if st == nil {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	fn(&st.s)
+}
+
+// recordApplied 记录一次成功下发
+func (st *State) recordApplied(p Profile, device string) {
+	st.update(func(s *StatusSnapshot) {
+		s.Applied = p
+		s.AppliedOK = true
+		s.Device = device
+		s.LastSwitch = time.Now()
+		s.Switches++
+	})
+}
+
+// heartbeatLine 心跳日志的一行概要
+func (s StatusSnapshot) heartbeatLine() string {
+	profile := "（尚未下发）"
+	if s.AppliedOK {
+		profile = profileName(s.Applied)
+	}
+	if s.Manual {
+		profile += "（手动覆盖）"
+	}
+	device := s.Device
+	if device == "" {
+		device = "（无）"
+	}
+	return fmt.Sprintf("[ALIVE] 运行中：当前 %s，设备 %s，已切换 %d 次", profile, device, s.Switches)
+}
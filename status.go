@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// ==================== 状态文件（status_file） ====================
+// 给 OBS 等外部程序读的轻量状态：每轮检查后写一次 JSON，内容不变时不落盘。
+
+// statusJSON 状态文件内容；in_game = 当前前台命中白名单
+type statusJSON struct {
+	InGame  bool   `json:"in_game"`
+	Process string `json:"process"`
+	Rule    string `json:"rule,omitempty"`
+	Profile string `json:"profile,omitempty"` // 最近一次成功下发的配置
+	Manual  bool   `json:"manual"`
+	Battery bool   `json:"battery"`
+}
+
+func newStatusJSON(s StatusSnapshot) statusJSON {
+	j := statusJSON{
+		InGame:  s.Hit,
+		Process: s.Proc,
+		Rule:    s.Rule,
+		Manual:  s.Manual,
+		Battery: s.Battery,
+	}
+	if s.AppliedOK {
+		j.Profile = profileName(s.Applied)
+	}
+	return j
+}
+
+// statusPath 相对路径按配置文件所在目录解析（与 history_file 一致）
+func statusPath(cfg *Config) string {
+	if cfg.StatusFile == "" || filepath.IsAbs(cfg.StatusFile) {
+		return cfg.StatusFile
+	}
+	return filepath.Join(filepath.Dir(cfg.ConfigPath), cfg.StatusFile)
+}
+
+// StatusWriter 记住上次写出的内容，避免每轮都写盘
+type StatusWriter struct {
+	last []byte
+	path string
+}
+
+// write 未配置 status_file 时什么都不做；写失败只打日志
+func (w *StatusWriter) write(cfg *Config, s StatusSnapshot) {
+	path := statusPath(cfg)
+	if path == "" {
+		return
+	}
+	data, err := json.Marshal(newStatusJSON(s))
+	if err != nil {
+		return
+	}
+	data = append(data, '\n')
+	if path == w.path && bytes.Equal(data, w.last) {
+		return
+	}
+
+	// 先写临时文件再改名，读取方不会读到半个 JSON
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		log.Printf("[STATUS] 写入状态文件失败：%v", err)
+		return
+	}
+	w.last, w.path = data, path
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// ==================== 前台监视（-watch） ====================
+// 排查规则为什么没命中：每次前台进程/窗口标题变化时打印一行，
+// 附带按当前配置 Decide 的结果。不访问鼠标；配置文件修改后自动重载（失败只打日志，不弹窗）。
+
+// ForegroundInfo 前台窗口的诊断信息
+type ForegroundInfo struct {
+	Name  string // 小写 exe 名（与匹配规则用的一致）
+	Path  string // 完整路径
+	Title string // 窗口标题
+}
+
+// watchPollInterval -watch 读取前台与检查配置文件的固定间隔（与 interval_seconds 无关，变化要尽快打出来）
+const watchPollInterval = 300 * time.Millisecond
+
+// watchReloadDebounce 配置文件 mtime 变化后需保持不变这么久才重载（编辑器保存时可能连写几次）
+const watchReloadDebounce = 500 * time.Millisecond
+
+// runWatch 一直运行到 Ctrl+C，返回退出码
+func runWatch(ctx context.Context, cfgPath string) int {
+	cfg, modTime, err := loadConfig(cfgPath)
+	if err != nil {
+		log.Printf("[ERR] 读取配置失败：%v", err)
+		return 1
+	}
+	applyLogOptions(cfg.Log)
+	log.Printf("[WATCH] 监视前台窗口变化（不访问鼠标），按 Ctrl+C 退出。配置：%s", cfgPath)
+
+	var prev ForegroundInfo
+	var lastErr string
+	var changedAt time.Time // 最近一次看到 mtime 变化的时间；零值 = 没有待重载的变更
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+	for {
+		// 配置文件：mtime 变了先记下，稳定 watchReloadDebounce 后再重载；
+		// 诊断模式下重载失败只打日志，不弹提示框
+		if fi, e := os.Stat(cfgPath); e == nil && !fi.ModTime().Equal(modTime) {
+			if changedAt.IsZero() {
+				changedAt = time.Now()
+			}
+			modTime = fi.ModTime()
+		} else if !changedAt.IsZero() && time.Since(changedAt) >= watchReloadDebounce {
+			changedAt = time.Time{}
+			if nc, _, e := loadConfig(cfgPath); e != nil {
+				log.Printf("[ERR] 配置文件变更但重载失败：%v", e)
+			} else {
+				cfg = nc
+				applyLogOptions(cfg.Log)
+				log.Printf("[WATCH] 配置文件已重新加载。")
+				prev = ForegroundInfo{} // 按新配置把当前前台再打一遍
+			}
+		}
+
+		info, err := ForegroundWindowInfo()
+		switch {
+		case err != nil:
+			// 锁屏、UAC、提权进程等取不到前台信息；只在变化时提示一次
+			if msg := err.Error(); msg != lastErr {
+				lastErr = msg
+				log.Printf("[WATCH] 无法读取前台窗口：%v", err)
+			}
+		case info != prev:
+			prev, lastErr = info, ""
+			log.Print(watchLine(cfg, info))
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchLine 一次前台变化的说明：进程、路径、标题，以及会切到的配置
+func watchLine(cfg *Config, info ForegroundInfo) string {
+	battery := onBattery(cfg)
+	d := Decide(cfg, info.Name, info.Title, Context{Battery: battery, Cmdline: ForegroundProcessCmdline,
+		Running: ambientRunning(cfg, nil)})
+
+	verdict := "未命中"
+	switch {
+	case d.Ambient != "":
+		verdict = d.Ambient + " 运行中"
+	case d.Hit:
+		verdict = "命中 " + d.Rule
+	}
+	suffix := ""
+	if battery {
+		suffix = "（电池）"
+	}
+	if d.Clamped {
+		suffix += fmt.Sprintf("（回报率上限 %dHz，规则要求 %dHz）", d.Poll, d.RawPoll)
+	}
+	return fmt.Sprintf("[WATCH] %s  %q  路径=%s  -> %s：%s%s",
+		info.Name, info.Title, info.Path, verdict, profileName(d.Profile()), suffix)
+}